@@ -0,0 +1,131 @@
+// Command admin runs maintenance tasks on the server's database, like
+// taking and restoring backups.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/backup"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const usage = `Usage: admin [flags] <command>
+
+Commands:
+  backup        back up the database, even while the server runs, and
+                delete the oldest backups beyond --keep
+  backups       list the backups
+  restore FILE  replace the database with a backup; stop the server first.
+                FILE is a path or the name of a backup in --dir
+
+Flags:
+`
+
+func main() {
+	log.SetFlags(0)
+	dsn := flag.String("dsn", envOr("DB_PATH", "./data.db"), "SQLite database ($DB_PATH)")
+	dir := flag.String("dir", envOr("BACKUP_DIR", "./backups"), "directory of the backups ($BACKUP_DIR)")
+	keep := flag.Int("keep", envInt("BACKUP_KEEP", 7), "number of backups to keep ($BACKUP_KEEP)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	switch command {
+	case "backup":
+		if *keep < 1 {
+			log.Fatal("--keep must be at least 1")
+		}
+		db, err := sql.Open("sqlite3", *dsn)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer db.Close()
+		b, err := backup.Create(context.Background(), db, *dir)
+		if err != nil {
+			log.Fatal("Failed to back up the database: ", err)
+		}
+		log.Printf("Backed up schema version %d to %s (%d bytes)", b.SchemaVersion, filepath.Join(*dir, b.Name), b.Size)
+		deleted, err := backup.Prune(*dir, *keep)
+		for _, name := range deleted {
+			log.Printf("Deleted %s", name)
+		}
+		if err != nil {
+			log.Fatal("Failed to delete old backups: ", err)
+		}
+	case "backups":
+		backups, err := backup.List(*dir)
+		if err != nil {
+			log.Fatal(err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tCREATED\tSCHEMA\tSIZE")
+		for _, b := range backups {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\n", b.Name, b.CreatedAt.Format(time.RFC3339), b.SchemaVersion, b.Size)
+		}
+		w.Flush()
+	case "restore":
+		if len(args) != 1 {
+			log.Fatal("restore needs the backup to restore")
+		}
+		path := args[0]
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			b, err := backup.Get(*dir, args[0])
+			if err != nil {
+				log.Fatalf("%s: %v", args[0], err)
+			}
+			path = filepath.Join(*dir, b.Name)
+		}
+		latest, err := database.LatestMigration(migrations.FS)
+		if err != nil {
+			log.Fatal(err)
+		}
+		version, previous, err := backup.Restore(path, *dsn, latest)
+		if err != nil {
+			log.Fatal("Failed to restore: ", err)
+		}
+		if previous != "" {
+			log.Printf("Moved the previous database to %s", previous)
+		}
+		log.Printf("Restored %s at schema version %d", *dsn, version)
+		if version < latest {
+			log.Printf("Migrations up to version %d are pending; run the migrate command or start the API with AUTO_MIGRATE", latest)
+		}
+	default:
+		log.Fatalf("Unknown command %q, run with -h for help", command)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return fallback
+}
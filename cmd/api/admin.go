@@ -0,0 +1,344 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+type adminUsersResponse struct {
+	Users []*database.User `json:"users"`
+	pagination
+}
+
+type adminActionsResponse struct {
+	Actions []*database.AdminAction `json:"actions"`
+	pagination
+}
+
+type transferEventRequest struct {
+	OwnerId int `json:"ownerId" binding:"required,min=1"`
+}
+
+// GetAdminUsers returns users
+//
+//	@Summary		Returns users
+//	@Description	Returns users by id, optionally only those whose name or email contains q, or those with a status (active or disabled). Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			q			query		string	false	"Part of the name or email"
+//	@Param			status		query		string	false	"Account status"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			pageSize	query		int		false	"Results per page (max 100)"
+//	@Success		200			{object}	adminUsersResponse
+//	@Router			/api/v1/admin/users [get]
+//	@Security		BearerAuth
+func (app *application) getAdminUsers(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", "active", "disabled":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	page, ok := readPagination(c)
+	if !ok {
+		return
+	}
+
+	users, total, err := app.modelsFor(c).Users.Search(c.Query("q"), status, page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve users"})
+		return
+	}
+	page.Total = total
+	c.JSON(http.StatusOK, adminUsersResponse{Users: users, pagination: page})
+}
+
+// DisableUser disables a user account
+//
+//	@Summary		Disables a user account
+//	@Description	Disables an account, which can no longer log in or use the tokens it has. Administrators can't disable their own account. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	database.User
+//	@Router			/api/v1/admin/users/{id}/disable [post]
+//	@Security		BearerAuth
+func (app *application) disableUser(c *gin.Context) {
+	app.setUserDisabled(c, true)
+}
+
+// EnableUser enables a disabled user account
+//
+//	@Summary		Enables a disabled user account
+//	@Description	Lets a disabled account log in again. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	database.User
+//	@Router			/api/v1/admin/users/{id}/enable [post]
+//	@Security		BearerAuth
+func (app *application) enableUser(c *gin.Context) {
+	app.setUserDisabled(c, false)
+}
+
+func (app *application) setUserDisabled(c *gin.Context, disabled bool) {
+	user, ok := app.userFromParam(c)
+	if !ok {
+		return
+	}
+	if disabled && user.Id == app.GetUserFromContext(c).Id {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You can't disable your own account"})
+		return
+	}
+
+	action := "user.enable"
+	if disabled {
+		action = "user.disable"
+	}
+	audit := app.adminAction(c, action, database.TargetUser, &user.Id, nil)
+	if err := app.modelsFor(c).Users.SetDisabled(user.Id, disabled, audit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	user, err := app.modelsFor(c).Users.Get(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+// ResetUserPassword forces a user to change their password
+//
+//	@Summary		Forces a user to change their password
+//	@Description	Locks the account out of everything but changing its password with PUT /api/v1/user/password, which needs the current one. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	database.User
+//	@Router			/api/v1/admin/users/{id}/reset-password [post]
+//	@Security		BearerAuth
+func (app *application) resetUserPassword(c *gin.Context) {
+	user, ok := app.userFromParam(c)
+	if !ok {
+		return
+	}
+
+	audit := app.adminAction(c, "user.reset_password", database.TargetUser, &user.Id, nil)
+	if err := app.modelsFor(c).Users.RequirePasswordReset(user.Id, audit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	user.PasswordResetRequired = true
+	c.JSON(http.StatusOK, user)
+}
+
+// TransferEvent hands an event over to another user
+//
+//	@Summary		Hands an event over to another user
+//	@Description	Makes another user the owner of an event. Webhooks the previous owner registered for this event alone are deleted. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int						true	"Event ID"
+//	@Param			transfer	body		transferEventRequest	true	"New owner"
+//	@Success		200			{object}	database.Event
+//	@Router			/api/v1/admin/events/{id}/transfer [post]
+//	@Security		BearerAuth
+func (app *application) transferEvent(c *gin.Context) {
+	event, ok := app.adminEventFromParam(c)
+	if !ok {
+		return
+	}
+	var request transferEventRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	owner, err := app.modelsFor(c).Users.Get(request.OwnerId)
+	if errors.Is(err, database.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return
+	}
+
+	audit := app.adminAction(c, "event.transfer", database.TargetEvent, &event.Id, map[string]any{"from": event.OwnerId, "to": owner.Id})
+	if err := app.modelsFor(c).Events.SetOwner(event.Id, owner.Id, audit); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer event"})
+		return
+	}
+
+	event.OwnerId = owner.Id
+	app.emitWebhook(webhooks.EventUpdated, event, event)
+	app.hub.Publish(event.Id, streamEventUpdated, event)
+	c.JSON(http.StatusOK, event)
+}
+
+// AdminDeleteEvent deletes any event
+//
+//	@Summary		Deletes any event
+//	@Description	Deletes an event of any owner, refunding its paid tickets like when the owner deletes it. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"Event ID"
+//	@Success		204
+//	@Router			/api/v1/admin/events/{id} [delete]
+//	@Security		BearerAuth
+func (app *application) adminDeleteEvent(c *gin.Context) {
+	event, ok := app.adminEventFromParam(c)
+	if !ok {
+		return
+	}
+	audit := app.adminAction(c, "event.delete", database.TargetEvent, &event.Id, map[string]any{"name": event.Name, "ownerId": event.OwnerId})
+	if !app.removeEvent(c, event, audit) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetSystemStats returns statistics of the whole system
+//
+//	@Summary		Returns statistics of the whole system
+//	@Description	Returns the number of users, events, attendees, orders and background jobs by status. Administrators only.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	database.SystemStats
+//	@Router			/api/v1/admin/stats [get]
+//	@Security		BearerAuth
+func (app *application) getSystemStats(c *gin.Context) {
+	stats, err := app.modelsFor(c).Stats.System()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve statistics"})
+		return
+	}
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetAdminActions returns the audit log
+//
+//	@Summary		Returns the audit log
+//	@Description	Returns the actions administrators took, newest first, optionally only those on a target type (user, event, job or backup) or a single target. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			targetType	query		string	false	"Target type"
+//	@Param			targetId	query		int		false	"Target ID"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			pageSize	query		int		false	"Results per page (max 100)"
+//	@Success		200			{object}	adminActionsResponse
+//	@Router			/api/v1/admin/actions [get]
+//	@Security		BearerAuth
+func (app *application) getAdminActions(c *gin.Context) {
+	targetType := c.Query("targetType")
+	switch targetType {
+	case "", database.TargetUser, database.TargetEvent, database.TargetJob, database.TargetBackup:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target type"})
+		return
+	}
+	targetId := 0
+	if value := c.Query("targetId"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid target ID"})
+			return
+		}
+		targetId = id
+	}
+	page, ok := readPagination(c)
+	if !ok {
+		return
+	}
+
+	actions, total, err := app.modelsFor(c).Admin.List(targetType, targetId, page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve actions"})
+		return
+	}
+	page.Total = total
+	c.JSON(http.StatusOK, adminActionsResponse{Actions: actions, pagination: page})
+}
+
+// adminAction describes an action of the logged in administrator for the
+// audit log. Models record it in the transaction of the change it describes,
+// so no change goes unrecorded.
+func (app *application) adminAction(c *gin.Context, action, targetType string, targetId *int, details map[string]any) *database.AdminAction {
+	adminId := app.GetUserFromContext(c).Id
+	return &database.AdminAction{
+		AdminId:    &adminId,
+		Action:     action,
+		TargetType: targetType,
+		TargetId:   targetId,
+		Details:    details,
+	}
+}
+
+// recordAdminAction adds an action that doesn't change the database, like
+// taking a backup, to the audit log. Handlers fail when it can't be recorded.
+func (app *application) recordAdminAction(c *gin.Context, action, targetType string, targetId *int, details map[string]any) error {
+	audit := app.adminAction(c, action, targetType, targetId, details)
+	if err := app.modelsFor(c).Admin.Insert(audit); err != nil {
+		log.Printf("failed to record admin action %s on %s: %v", action, targetType, err)
+		return err
+	}
+	return nil
+}
+
+// userFromParam loads the user named by the id parameter, writing the error
+// response if they don't exist.
+func (app *application) userFromParam(c *gin.Context) (*database.User, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return nil, false
+	}
+
+	user, err := app.modelsFor(c).Users.Get(id)
+	if errors.Is(err, database.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return nil, false
+	}
+	return user, true
+}
+
+// adminEventFromParam loads the event named by the id parameter whoever owns
+// it, writing the error response if it doesn't exist.
+func (app *application) adminEventFromParam(c *gin.Context) (*database.Event, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil, false
+	}
+
+	event, err := app.modelsFor(c).Events.Get(id)
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return nil, false
+	}
+	return event, true
+}
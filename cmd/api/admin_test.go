@@ -0,0 +1,90 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+func TestAdminActionsAreAudited(t *testing.T) {
+	app := newTestApp(t)
+	admin, adminToken := app.createAdmin(t, "admin")
+	user, userToken := app.createUser(t, "alice")
+	event := app.createEvent(t, userToken)
+
+	tests := []struct {
+		name       string
+		path       string
+		body       any
+		action     string
+		targetType string
+		targetId   int
+	}{
+		{name: "disable", path: "/api/v1/admin/users/" + strconv.Itoa(user.Id) + "/disable", action: "user.disable", targetType: database.TargetUser, targetId: user.Id},
+		{name: "enable", path: "/api/v1/admin/users/" + strconv.Itoa(user.Id) + "/enable", action: "user.enable", targetType: database.TargetUser, targetId: user.Id},
+		{name: "reset password", path: "/api/v1/admin/users/" + strconv.Itoa(user.Id) + "/reset-password", action: "user.reset_password", targetType: database.TargetUser, targetId: user.Id},
+		{name: "transfer", path: "/api/v1/admin/events/" + strconv.Itoa(event.Id) + "/transfer", body: map[string]any{"ownerId": admin.Id}, action: "event.transfer", targetType: database.TargetEvent, targetId: event.Id},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := app.do(t, http.MethodPost, tt.path, tt.body, bearer(adminToken)...)
+			if recorder.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+			}
+			actions, _, err := app.models.Admin.List(tt.targetType, tt.targetId, 1, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(actions) == 0 || actions[0].Action != tt.action || *actions[0].AdminId != admin.Id {
+				t.Errorf("latest action = %+v, want %s by %d", actions, tt.action, admin.Id)
+			}
+		})
+	}
+}
+
+func TestAdminActionFailsWithoutAudit(t *testing.T) {
+	app := newTestApp(t)
+	_, adminToken := app.createAdmin(t, "admin")
+	user, _ := app.createUser(t, "alice")
+	if _, err := app.db.Exec("drop table admin_actions"); err != nil {
+		t.Fatal(err)
+	}
+
+	recorder := app.do(t, http.MethodPost, "/api/v1/admin/users/"+strconv.Itoa(user.Id)+"/disable", nil, bearer(adminToken)...)
+	if recorder.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusInternalServerError, recorder.Body)
+	}
+	stored, err := app.models.Users.Get(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.DisabledAt != nil {
+		t.Error("the user was disabled without the action being audited")
+	}
+}
+
+func TestTransferEvent(t *testing.T) {
+	app := newTestApp(t)
+	_, adminToken := app.createAdmin(t, "admin")
+	_, aliceToken := app.createUser(t, "alice")
+	bob, _ := app.createUser(t, "bob")
+	event := app.createEvent(t, aliceToken)
+	path := "/api/v1/admin/events/" + strconv.Itoa(event.Id) + "/transfer"
+
+	missing := app.do(t, http.MethodPost, path, map[string]any{"ownerId": 99}, bearer(adminToken)...)
+	if missing.Code != http.StatusNotFound {
+		t.Errorf("unknown owner: status = %d, want %d: %s", missing.Code, http.StatusNotFound, missing.Body)
+	}
+
+	transferred := app.do(t, http.MethodPost, path, map[string]any{"ownerId": bob.Id}, bearer(adminToken)...)
+	if transferred.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", transferred.Code, transferred.Body)
+	}
+	var got database.Event
+	decode(t, transferred, &got)
+	if got.OwnerId != bob.Id {
+		t.Errorf("owner = %d, want %d", got.OwnerId, bob.Id)
+	}
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// apiKeyHeader is the header API keys are sent in, instead of the
+	// Authorization header of logged in users.
+	apiKeyHeader = "X-API-Key"
+	// apiKeyPrefix starts every key, so leaked keys are easy to search for.
+	apiKeyPrefix = "evk_"
+	// apiKeyShownLength is how much of a key is stored in the clear to tell
+	// it apart from the user's other keys.
+	apiKeyShownLength = len(apiKeyPrefix) + 8
+)
+
+// apiKeyScopes maps the routes that accept API keys, by method and route
+// template, to the scope they require. Keys are refused everywhere else.
+var apiKeyScopes = map[string]string{
+	"GET /api/v1/events/:id":                      database.ScopeEventsRead,
+	"GET /api/v1/events/:id/tickets":              database.ScopeEventsRead,
+	"GET /api/v1/events/:id/stream":               database.ScopeEventsRead,
+	"POST /api/v1/events":                         database.ScopeEventsWrite,
+	"PUT /api/v1/events/:id":                      database.ScopeEventsWrite,
+	"DELETE /api/v1/events/:id":                   database.ScopeEventsWrite,
+	"POST /api/v1/events/:id/tickets":             database.ScopeEventsWrite,
+	"PUT /api/v1/events/:id/tickets/:ticketId":    database.ScopeEventsWrite,
+	"DELETE /api/v1/events/:id/tickets/:ticketId": database.ScopeEventsWrite,
+
+	"GET /api/v1/attendees/:id/events":                  database.ScopeAttendeesRead,
+	"GET /api/v1/events/:id/attendees":                  database.ScopeAttendeesRead,
+	"GET /api/v1/events/:id/attendees/export":           database.ScopeAttendeesRead,
+	"POST /api/v1/events/:id/attendees/:userid":         database.ScopeAttendeesWrite,
+	"DELETE /api/v1/events/:id/attendees/:userid":       database.ScopeAttendeesWrite,
+	"POST /api/v1/events/:id/attendees/:userid/checkin": database.ScopeAttendeesWrite,
+	"POST /api/v1/events/:id/attendees/import":          database.ScopeAttendeesWrite,
+	"POST /api/v1/invites/:token/rsvp":                  database.ScopeAttendeesWrite,
+
+	"GET /api/v1/orders":                              database.ScopeOrdersRead,
+	"POST /api/v1/events/:id/tickets/:ticketId/claim": database.ScopeOrdersWrite,
+
+	"GET /api/v1/invites/:token":                  database.ScopeInvitesRead,
+	"GET /api/v1/events/:id/invites":              database.ScopeInvitesRead,
+	"POST /api/v1/events/:id/invites":             database.ScopeInvitesWrite,
+	"DELETE /api/v1/events/:id/invites/:inviteId": database.ScopeInvitesWrite,
+
+	"GET /api/v1/webhooks":                database.ScopeWebhooksRead,
+	"GET /api/v1/webhooks/:id/deliveries": database.ScopeWebhooksRead,
+	"POST /api/v1/webhooks":               database.ScopeWebhooksWrite,
+	"DELETE /api/v1/webhooks/:id":         database.ScopeWebhooksWrite,
+
+	"GET /api/v1/events/:id/stats": database.ScopeStatsRead,
+	"GET /api/v1/me/stats":         database.ScopeStatsRead,
+}
+
+type apiKeyRequest struct {
+	Name      string     `json:"name" binding:"required,max=100"`
+	Scopes    []string   `json:"scopes" binding:"required,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// apiKeyResponse includes the key, which is only shown when it is created.
+type apiKeyResponse struct {
+	*database.APIKey
+	Key string `json:"key"`
+}
+
+// CreateAPIKey creates an API key
+//
+//	@Summary		Creates an API key
+//	@Description	Creates a key that programs send in the X-API-Key header to act as the user, on the routes its scopes cover: events:read, events:write, attendees:read, attendees:write, orders:read, orders:write, invites:read, invites:write, webhooks:read, webhooks:write and stats:read. The key is only returned once. Keys can't manage keys or the account, so this needs a login.
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			key	body		apiKeyRequest	true	"API key"
+//	@Success		201	{object}	apiKeyResponse
+//	@Router			/api/v1/api-keys [post]
+//	@Security		BearerAuth
+func (app *application) createAPIKey(c *gin.Context) {
+	var request apiKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for _, scope := range request.Scopes {
+		if !slices.Contains(database.Scopes, scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown scope " + scope})
+			return
+		}
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+		return
+	}
+
+	secret, err := newAPIKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	slices.Sort(request.Scopes)
+	key := database.APIKey{
+		UserId:    app.GetUserFromContext(c).Id,
+		Name:      request.Name,
+		Prefix:    secret[:apiKeyShownLength],
+		Scopes:    slices.Compact(request.Scopes),
+		ExpiresAt: request.ExpiresAt,
+	}
+	if err := app.modelsFor(c).APIKeys.Insert(&key, secret); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create API key"})
+		return
+	}
+	c.JSON(http.StatusCreated, apiKeyResponse{APIKey: &key, Key: secret})
+}
+
+// GetAPIKeys returns the user's API keys
+//
+//	@Summary		Returns the user's API keys
+//	@Description	Returns the user's API keys, revoked ones included, newest first, with when they were last used. The keys themselves aren't included.
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]database.APIKey
+//	@Router			/api/v1/api-keys [get]
+//	@Security		BearerAuth
+func (app *application) getAPIKeys(c *gin.Context) {
+	keys, err := app.modelsFor(c).APIKeys.GetByUser(app.GetUserFromContext(c).Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API keys"})
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// RevokeAPIKey revokes an API key
+//
+//	@Summary		Revokes an API key
+//	@Description	Revokes an API key, which stops working at once
+//	@Tags			api-keys
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"API key ID"
+//	@Success		204
+//	@Router			/api/v1/api-keys/{id} [delete]
+//	@Security		BearerAuth
+func (app *application) revokeAPIKey(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	key, err := app.modelsFor(c).APIKeys.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API key"})
+		return
+	}
+	if key == nil || key.UserId != app.GetUserFromContext(c).Id {
+		c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		return
+	}
+	if err := app.modelsFor(c).APIKeys.Revoke(key.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke API key"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// useAPIKey authenticates a request by its API key, writing the error
+// response if the key isn't valid or its scopes don't cover the route.
+func (app *application) useAPIKey(c *gin.Context, secret string) bool {
+	key, err := app.modelsFor(c).APIKeys.GetBySecret(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve API key"})
+		c.Abort()
+		return false
+	}
+	if key == nil || !key.Usable(time.Now()) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		c.Abort()
+		return false
+	}
+
+	scope, ok := apiKeyScopes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys can't be used for this route"})
+		c.Abort()
+		return false
+	}
+	if !key.HasScope(scope) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The API key lacks the " + scope + " scope"})
+		c.Abort()
+		return false
+	}
+
+	user, err := app.modelsFor(c).Users.Get(key.UserId)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+		c.Abort()
+		return false
+	}
+	if !app.accountUsable(c, user) {
+		return false
+	}
+	if err := app.modelsFor(c).APIKeys.Touch(key, time.Now()); err != nil {
+		log.Printf("failed to record use of API key %d: %v", key.Id, err)
+	}
+	c.Set("user", user)
+	return true
+}
+
+func newAPIKey() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
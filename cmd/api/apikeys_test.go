@@ -0,0 +1,100 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+func TestAPIKeyScopesCoverRegisteredRoutes(t *testing.T) {
+	app := newTestApp(t)
+	registered := map[string]bool{}
+	for _, route := range app.handler.(*gin.Engine).Routes() {
+		registered[route.Method+" "+route.Path] = true
+	}
+	for route, scope := range apiKeyScopes {
+		if !registered[route] {
+			t.Errorf("%s takes API keys but isn't a route", route)
+		}
+		if !slices.Contains(database.Scopes, scope) {
+			t.Errorf("%s needs the unknown scope %s", route, scope)
+		}
+	}
+}
+
+func TestUseAPIKey(t *testing.T) {
+	app := newTestApp(t)
+	user, token := app.createAdmin(t, "alice")
+	event := app.createEvent(t, token)
+
+	created := app.do(t, http.MethodPost, "/api/v1/api-keys", map[string]any{
+		"name": "dashboard", "scopes": []string{database.ScopeEventsRead, database.ScopeStatsRead},
+	}, bearer(token)...)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", created.Code, created.Body)
+	}
+	var key apiKeyResponse
+	decode(t, created, &key)
+
+	revoked, err := newAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	revokedKey := &database.APIKey{UserId: user.Id, Name: "revoked", Prefix: revoked[:apiKeyShownLength], Scopes: []string{database.ScopeStatsRead}}
+	if err := app.models.APIKeys.Insert(revokedKey, revoked); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.APIKeys.Revoke(revokedKey.Id); err != nil {
+		t.Fatal(err)
+	}
+	expired, err := newAPIKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	yesterday := time.Now().Add(-24 * time.Hour)
+	expiredKey := &database.APIKey{UserId: user.Id, Name: "expired", Prefix: expired[:apiKeyShownLength], Scopes: []string{database.ScopeStatsRead}, ExpiresAt: &yesterday}
+	if err := app.models.APIKeys.Insert(expiredKey, expired); err != nil {
+		t.Fatal(err)
+	}
+
+	eventPath := "/api/v1/events/" + strconv.Itoa(event.Id)
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+	}{
+		{name: "scope granted", method: http.MethodGet, path: "/api/v1/me/stats", key: key.Key, status: http.StatusOK},
+		{name: "scope missing", method: http.MethodDelete, path: eventPath, key: key.Key, status: http.StatusForbidden},
+		{name: "key management", method: http.MethodGet, path: "/api/v1/api-keys", key: key.Key, status: http.StatusForbidden},
+		{name: "admin route", method: http.MethodGet, path: "/api/v1/admin/stats", key: key.Key, status: http.StatusForbidden},
+		{name: "unknown key", method: http.MethodGet, path: "/api/v1/me/stats", key: apiKeyPrefix + "unknown", status: http.StatusUnauthorized},
+		{name: "revoked key", method: http.MethodGet, path: "/api/v1/me/stats", key: revoked, status: http.StatusUnauthorized},
+		{name: "expired key", method: http.MethodGet, path: "/api/v1/me/stats", key: expired, status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := app.do(t, tt.method, tt.path, nil, apiKeyHeader, tt.key)
+			if recorder.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body)
+			}
+		})
+	}
+
+	if stored, err := app.models.Events.Get(event.Id); err != nil || stored == nil {
+		t.Errorf("the event is gone after a delete without the events:write scope: %v", err)
+	}
+	used, err := app.models.APIKeys.Get(key.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if used.LastUsedAt == nil {
+		t.Error("the use of the key wasn't recorded")
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"time"
@@ -24,6 +25,9 @@ type loginRequest struct{
 
 type loginResponse struct{
 	Token string `json:"token"`
+	// PasswordResetRequired means the token only works to change the
+	// password, as an administrator asked for a reset.
+	PasswordResetRequired bool `json:"passwordResetRequired,omitempty"`
 }
 
 // Login logs in a user
@@ -43,7 +47,7 @@ func (app *application)login(c *gin.Context){
 		return
 	}
 	//Email checking
-	existingUser,err:=app.models.Users.GetByEmail(auth.Email)
+	existingUser,err:=app.modelsFor(c).Users.GetByEmail(auth.Email)
 	if existingUser==nil{
 		c.JSON(http.StatusUnauthorized,gin.H{"error":"Invalid email or password"})
 		return
@@ -54,22 +58,32 @@ func (app *application)login(c *gin.Context){
 	}
 
 	//Password checking
+	_, span := tracer.Start(c.Request.Context(), "bcrypt.CompareHashAndPassword")
 	err=bcrypt.CompareHashAndPassword([]byte(existingUser.Password),[]byte(auth.Password))
-	if err==nil{
+	span.End()
+	if err!=nil{
 		c.JSON(http.StatusUnauthorized,gin.H{"error":"Invalid email or password"})
 		return
 	}
-	token:=jwt.NewWithClaims(jwt.SigningMethodHS256,jwt.MapClaims{
-		"userId":existingUser.Id,
-		"exp": time.Now().Add(time.Hour * 72).Unix(),
-	})
-
-	tokenString,err:=token.SignedString([]byte(app.jwtSecret))
+	if existingUser.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+	tokenString,err:=app.newToken(existingUser.Id)
 	if err!=nil{
 		c.JSON(http.StatusInternalServerError,gin.H{"error":"error generating token"})
 		return
 	}
-	c.JSON(http.StatusOK,loginResponse{Token: tokenString})
+	c.JSON(http.StatusOK,loginResponse{Token: tokenString, PasswordResetRequired: existingUser.PasswordResetRequired})
+}
+
+// newToken issues the login token of a user.
+func (app *application) newToken(userId int) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"userId": userId,
+		"exp":    time.Now().Add(time.Hour * 72).Unix(),
+	})
+	return token.SignedString([]byte(app.jwtSecret))
 }
 
 // RegisterUser registers a new user
@@ -88,7 +102,9 @@ func (app *application) registerUser(c *gin.Context){
 		return
 	}
 
+	_, span := tracer.Start(c.Request.Context(), "bcrypt.GenerateFromPassword")
 	hashedPassword,err:=bcrypt.GenerateFromPassword([]byte(register.Password),bcrypt.DefaultCost)
+	span.End()
 	if err!=nil{
 		c.JSON(http.StatusInternalServerError,gin.H{"error":"Something went wrong"})
 		return
@@ -98,28 +114,178 @@ func (app *application) registerUser(c *gin.Context){
 		Email: register.Email,
 		Password: register.Password,
 		Name: register.Name,
+		EmailReminders: true,
 	}
 	
-	err=app.models.Users.Insert(&user)
+	err=app.modelsFor(c).Users.Insert(&user)
 	if err!=nil{
 		c.JSON(http.StatusInternalServerError,gin.H{"error":"Could not registered successfully"})
 		return
 	}
+	app.metrics.usersRegistered.Inc()
 	c.JSON(http.StatusCreated,user)
 
 }
 
-func (app * application)getUser(c *gin.Context){
-	id,err:=strconv.Atoi(c.Param("id"))
-	if err!=nil{
-		c.JSON(http.StatusBadRequest,gin.H{"error":"Invalid User ID"})
+// publicUser is what anyone may see of another user.
+type publicUser struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// GetUser returns a user
+//
+//	@Summary		Returns a user
+//	@Description	Returns a user's id and name. Users asking for themselves, and administrators, get the whole account, including its email and settings.
+//	@Tags			auth
+//	@Produce		json
+//	@Param			id	path		int	true	"User ID"
+//	@Success		200	{object}	publicUser
+//	@Failure		404	{object}	map[string]string
+//	@Router			/api/v1/user/{id} [post]
+func (app *application) getUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid User ID"})
 		return
 	}
 
-	user,err:=app.models.Users.Get(id)
-	if err!=nil{
-		c.JSON(http.StatusInternalServerError,gin.H{"error":"Failed to retrieve user details"})
+	user, err := app.modelsFor(c).Users.Get(id)
+	if errors.Is(err, database.ErrUserNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user details"})
+		return
+	}
+	viewer := app.GetUserFromContext(c)
+	if viewer.Id != user.Id && !viewer.IsAdmin {
+		c.JSON(http.StatusOK, publicUser{Id: user.Id, Name: user.Name})
+		return
+	}
+	c.JSON(http.StatusOK, user)
+}
+
+type privacyRequest struct {
+	HideFromAttendeeLists *bool `json:"hideFromAttendeeLists" binding:"required"`
+}
+
+// UpdatePrivacy updates the current user's privacy settings
+//
+//	@Summary		Updates the current user's privacy settings
+//	@Description	Sets whether the current user is left out of the attendee lists shown to other attendees and the public. Organizers still see every attendee of their events.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			settings	body		privacyRequest	true	"Privacy settings"
+//	@Success		200			{object}	database.User
+//	@Router			/api/v1/user/privacy [put]
+//	@Security		BearerAuth
+func (app *application) updatePrivacy(c *gin.Context) {
+	var request privacyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	if err := app.modelsFor(c).Users.SetHideFromAttendeeLists(user.Id, *request.HideFromAttendeeLists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update privacy settings"})
 		return
 	}
-	c.JSON(http.StatusOK,user)
-}
\ No newline at end of file
+	user.HideFromAttendeeLists = *request.HideFromAttendeeLists
+	c.JSON(http.StatusOK, user)
+}
+
+type notificationsRequest struct {
+	EmailReminders *bool `json:"emailReminders" binding:"required"`
+}
+
+// UpdateNotifications updates the current user's notification settings
+//
+//	@Summary		Updates the current user's notification settings
+//	@Description	Sets whether the current user is emailed reminders before the events they attend
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			settings	body		notificationsRequest	true	"Notification settings"
+//	@Success		200			{object}	database.User
+//	@Router			/api/v1/user/notifications [put]
+//	@Security		BearerAuth
+func (app *application) updateNotifications(c *gin.Context) {
+	var request notificationsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	if err := app.modelsFor(c).Users.SetEmailReminders(user.Id, *request.EmailReminders); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification settings"})
+		return
+	}
+	user.EmailReminders = *request.EmailReminders
+	c.JSON(http.StatusOK, user)
+}
+
+// changePasswordPath is the route of changePassword, which users who must
+// reset their password can still use.
+const changePasswordPath = "/api/v1/user/password"
+
+type changePasswordRequest struct {
+	// CurrentPassword may be left out by users who signed up with an
+	// identity provider and never set a password.
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword" binding:"required,min=8"`
+}
+
+// ChangePassword changes the current user's password
+//
+//	@Summary		Changes the current user's password
+//	@Description	Changes the current user's password, which completes a password reset required by an administrator. Users who signed up with OIDC and have no password yet (hasPassword false) set one without a current password.
+//	@Tags			auth
+//	@Accept			json
+//	@Produce		json
+//	@Param			passwords	body		changePasswordRequest	true	"Current and new password"
+//	@Success		200			{object}	database.User
+//	@Failure		401			{object}	map[string]string
+//	@Router			/api/v1/user/password [put]
+//	@Security		BearerAuth
+func (app *application) changePassword(c *gin.Context) {
+	var request changePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	if user.HasPassword {
+		_, span := tracer.Start(c.Request.Context(), "bcrypt.CompareHashAndPassword")
+		err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(request.CurrentPassword))
+		span.End()
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is wrong"})
+			return
+		}
+		if request.NewPassword == request.CurrentPassword {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current one"})
+			return
+		}
+	}
+
+	_, span := tracer.Start(c.Request.Context(), "bcrypt.GenerateFromPassword")
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(request.NewPassword), bcrypt.DefaultCost)
+	span.End()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if err := app.modelsFor(c).Users.SetPassword(user.Id, string(hashedPassword)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	user.PasswordResetRequired = false
+	user.HasPassword = true
+	c.JSON(http.StatusOK, user)
+}
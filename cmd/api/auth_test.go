@@ -0,0 +1,137 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+func TestLogin(t *testing.T) {
+	app := newTestApp(t)
+	registered := app.do(t, http.MethodPost, "/api/v1/auth/register", map[string]any{
+		"email": "alice@example.com", "password": "correct horse", "name": "Alice",
+	})
+	if registered.Code != http.StatusCreated {
+		t.Fatalf("register: %d %s", registered.Code, registered.Body)
+	}
+
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		status   int
+	}{
+		{name: "right password", email: "alice@example.com", password: "correct horse", status: http.StatusOK},
+		{name: "wrong password", email: "alice@example.com", password: "battery staple", status: http.StatusUnauthorized},
+		{name: "unknown email", email: "bob@example.com", password: "correct horse", status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := app.do(t, http.MethodPost, "/api/v1/auth/login", map[string]any{"email": tt.email, "password": tt.password})
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, tt.status, recorder.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var response loginResponse
+			decode(t, recorder, &response)
+			if response.Token == "" {
+				t.Error("no token in the response")
+			}
+		})
+	}
+}
+
+func TestGetUser(t *testing.T) {
+	app := newTestApp(t)
+	alice, own := app.createUser(t, "alice")
+	_, other := app.createUser(t, "bob")
+	_, admin := app.createAdmin(t, "root")
+
+	tests := []struct {
+		name   string
+		token  string
+		id     int
+		status int
+		// full is whether the whole account is shown.
+		full bool
+	}{
+		{"anonymous", "", alice.Id, http.StatusOK, false},
+		{"another user", other, alice.Id, http.StatusOK, false},
+		{"the user", own, alice.Id, http.StatusOK, true},
+		{"administrator", admin, alice.Id, http.StatusOK, true},
+		{"unknown user", own, 9999, http.StatusNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.token != "" {
+				headers = bearer(tt.token)
+			}
+			response := app.do(t, http.MethodPost, "/api/v1/user/"+strconv.Itoa(tt.id), nil, headers...)
+			if response.Code != tt.status {
+				t.Fatalf("user: %d %s", response.Code, response.Body)
+			}
+			if tt.status != http.StatusOK {
+				return
+			}
+			var user map[string]any
+			decode(t, response, &user)
+			if user["name"] != "alice" {
+				t.Errorf("name = %v, want alice", user["name"])
+			}
+			if _, ok := user["email"]; ok != tt.full {
+				t.Errorf("email shown = %v, want %v: %v", ok, tt.full, user)
+			}
+			if _, ok := user["isAdmin"]; ok != tt.full {
+				t.Errorf("settings shown = %v, want %v: %v", ok, tt.full, user)
+			}
+		})
+	}
+}
+
+func TestChangePasswordWithoutPassword(t *testing.T) {
+	app := newTestApp(t)
+	//a user who signed up with OIDC and was then asked to reset their password
+	user := &database.User{Name: "alice", Email: "alice@example.com", Password: "stand-in"}
+	identity := &database.Identity{Provider: "https://id.example.com", Subject: "alice", Email: user.Email}
+	if err := app.models.Identities.InsertWithUser(user, identity); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.db.Exec("update users set password_reset_required = 1 where id = ?", user.Id); err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.newToken(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		body   map[string]any
+		status int
+	}{
+		{"first password", map[string]any{"newPassword": "correct horse"}, http.StatusOK},
+		{"current password required once set", map[string]any{"newPassword": "battery staple"}, http.StatusUnauthorized},
+		{"with current password", map[string]any{"currentPassword": "correct horse", "newPassword": "battery staple"}, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := app.do(t, http.MethodPut, "/api/v1/user/password", tt.body, bearer(token)...)
+			if response.Code != tt.status {
+				t.Fatalf("change password: %d %s", response.Code, response.Body)
+			}
+		})
+	}
+
+	stored, err := app.models.Users.Get(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stored.HasPassword || stored.PasswordResetRequired {
+		t.Errorf("user = %+v, want a password and no reset required", stored)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"github.com/anshbadoni30/event-management-app/internal/backup"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// CreateBackup backs up the database
+//
+//	@Summary		Backs up the database
+//	@Description	Takes a consistent, compressed and checksummed copy of the database while the server keeps running, then deletes the oldest backups beyond the configured number to keep. Administrators only.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		201	{object}	backup.Backup
+//	@Router			/api/v1/admin/backups [post]
+//	@Security		BearerAuth
+func (app *application) createBackup(c *gin.Context) {
+	b, err := backup.Create(c.Request.Context(), app.db, app.backupDir)
+	if err != nil {
+		log.Printf("failed to back up the database: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to back up the database"})
+		return
+	}
+	if _, err := backup.Prune(app.backupDir, app.backupKeep); err != nil {
+		log.Printf("failed to delete old backups: %v", err)
+	}
+	if err := app.recordAdminAction(c, "backup.create", database.TargetBackup, nil, map[string]any{"name": b.Name}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record the backup"})
+		return
+	}
+	c.JSON(http.StatusCreated, b)
+}
+
+// GetBackups returns the database backups
+//
+//	@Summary		Returns the database backups
+//	@Description	Returns the database backups, newest first. Administrators only.
+//	@Tags			admin
+//	@Produce		json
+//	@Success		200	{object}	[]backup.Backup
+//	@Router			/api/v1/admin/backups [get]
+//	@Security		BearerAuth
+func (app *application) getBackups(c *gin.Context) {
+	backups, err := backup.List(app.backupDir)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve backups"})
+		return
+	}
+	c.JSON(http.StatusOK, backups)
+}
+
+// DownloadBackup downloads a database backup
+//
+//	@Summary		Downloads a database backup
+//	@Description	Downloads a gzipped backup of the database. Its SHA-256 checksum is in the X-Checksum-Sha256 header. Administrators only.
+//	@Tags			admin
+//	@Produce		application/gzip
+//	@Param			name	path		string	true	"Backup name"
+//	@Success		200		{file}		file
+//	@Failure		404		{object}	map[string]string
+//	@Router			/api/v1/admin/backups/{name} [get]
+//	@Security		BearerAuth
+func (app *application) downloadBackup(c *gin.Context) {
+	b, err := backup.Get(app.backupDir, c.Param("name"))
+	if errors.Is(err, backup.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve backup"})
+		return
+	}
+	//downloads are recorded before they start, so none goes unrecorded
+	if err := app.recordAdminAction(c, "backup.download", database.TargetBackup, nil, map[string]any{"name": b.Name}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record the download"})
+		return
+	}
+	c.Header("X-Checksum-Sha256", b.SHA256)
+	c.FileAttachment(filepath.Join(app.backupDir, b.Name), b.Name)
+}
@@ -1,10 +1,16 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
 	"github.com/gin-gonic/gin"
 )
 
@@ -27,25 +33,44 @@ func (app *application) createEvent(c *gin.Context) {
 	}
 	user:=app.GetUserFromContext(c)
 	event.OwnerId=user.Id
-	err := app.models.Events.Insert(&event)
+	err := app.modelsFor(c).Events.Insert(&event)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create event"})
 		return
 	}
+	if err := app.rescheduleReminders(event.Id); err != nil {
+		log.Printf("failed to schedule reminders of event %d: %v", event.Id, err)
+	}
+	app.metrics.eventsCreated.Inc()
+	app.emitWebhook(webhooks.EventCreated, &event, event)
 	c.JSON(http.StatusCreated, event)
 }
 
 // getEvents return all events
 //
 // @Summary Returns all events
-// @Description Resturns all events
+// @Description Resturns all public events, optionally only the ones tagged with any (or all, with match=all) of the comma separated tags
 // @Tags Events
 // @Accept json
 // @Produce json
+// @Param tags query string false "Comma separated tags"
+// @Param match query string false "any (default) or all"
 // @Success 200 {object} []database.Event
 // @Router /api/v1/events [get]
 func (app *application) getAllEvents(c *gin.Context) {
-	events, err := app.models.Events.GetAll()
+	var events []*database.Event
+	var err error
+
+	match := c.DefaultQuery("match", "any")
+	if match != "any" && match != "all" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "match must be any or all"})
+		return
+	}
+	if tags := c.Query("tags"); tags != "" {
+		events, err = app.modelsFor(c).Events.GetByTags(strings.Split(tags, ","), match == "all")
+	} else {
+		events, err = app.modelsFor(c).Events.GetAll()
+	}
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrive events"})
@@ -55,33 +80,117 @@ func (app *application) getAllEvents(c *gin.Context) {
 	c.JSON(http.StatusOK, events)
 }
 
-// GetEvent returns a single event
+type searchResponse struct {
+	Results []*database.EventSearchResult `json:"results"`
+	pagination
+}
+
+// SearchEvents searches events
 //
-//	@Summary		Returns a single event
-//	@Description	Returns a single event
+//	@Summary		Searches events
+//	@Description	Full-text search over event names, descriptions and locations. Every word must match, as a prefix. Matches are wrapped in <mark> tags in the highlight and snippet fields.
 //	@Tags			events
 //	@Accept			json
 //	@Produce		json
-//	@Param			id	path		int	true	"Event ID"
-//	@Success		200	{object}	database.Event
-//	@Router			/api/v1/events/{id} [get]
-func (app *application) getEvent(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id"))
+//	@Param			q			query		string	true	"Search text"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			pageSize	query		int		false	"Results per page (max 100)"
+//	@Success		200			{object}	searchResponse
+//	@Router			/api/v1/events/search [get]
+func (app *application) searchEvents(c *gin.Context) {
+	q := c.Query("q")
+	if database.SearchQuery(q) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search text is required"})
+		return
+	}
+	page, ok := readPagination(c)
+	if !ok {
+		return
+	}
 
+	results, total, err := app.modelsFor(c).Events.Search(q, page.PageSize, page.offset())
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search events"})
 		return
 	}
+	page.Total = total
+	c.JSON(http.StatusOK, searchResponse{Results: results, pagination: page})
+}
 
-	event, err := app.models.Events.Get(id)
+const (
+	defaultNearbyRadiusKm = 10
+	maxNearbyRadiusKm     = 1000
+)
 
-	if event == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+type nearbyResponse struct {
+	Results []*database.EventDistance `json:"results"`
+	pagination
+}
+
+// NearbyEvents returns the events near a location
+//
+//	@Summary		Returns the events near a location
+//	@Description	Returns the events within radius kilometres of a coordinate, closest first
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			lat			query		number	true	"Latitude"
+//	@Param			lng			query		number	true	"Longitude"
+//	@Param			radius		query		number	false	"Radius in kilometres (default 10, max 1000)"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			pageSize	query		int		false	"Results per page (max 100)"
+//	@Success		200			{object}	nearbyResponse
+//	@Router			/api/v1/events/nearby [get]
+func (app *application) nearbyEvents(c *gin.Context) {
+	lat, err := strconv.ParseFloat(c.Query("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid latitude"})
+		return
+	}
+	lng, err := strconv.ParseFloat(c.Query("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid longitude"})
+		return
+	}
+	radius := float64(defaultNearbyRadiusKm)
+	if value := c.Query("radius"); value != "" {
+		radius, err = strconv.ParseFloat(value, 64)
+		if err != nil || radius <= 0 || radius > maxNearbyRadiusKm {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid radius"})
+			return
+		}
+	}
+	page, ok := readPagination(c)
+	if !ok {
 		return
 	}
 
+	events, err := app.modelsFor(c).Events.Nearby(lat, lng, radius)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve nearby events"})
+		return
+	}
+
+	page.Total = len(events)
+	start := min(page.offset(), len(events))
+	end := min(start+page.PageSize, len(events))
+	c.JSON(http.StatusOK, nearbyResponse{Results: events[start:end], pagination: page})
+}
+
+// GetEvent returns a single event
+//
+//	@Summary		Returns a single event
+//	@Description	Returns a single event. Private events are only returned to their owner and attendees, or with a valid invite token.
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int		true	"Event ID"
+//	@Param			invite	query		string	false	"Invite token of a private event"
+//	@Success		200		{object}	database.Event
+//	@Router			/api/v1/events/{id} [get]
+func (app *application) getEvent(c *gin.Context) {
+	event, ok := app.viewableEvent(c)
+	if !ok {
 		return
 	}
 	c.JSON(http.StatusCreated, event)
@@ -90,7 +199,7 @@ func (app *application) getEvent(c *gin.Context) {
 // UpdateEvent updates an existing event
 //
 //	@Summary		Updates an existing event
-//	@Description	Updates an existing event
+//	@Description	Updates an existing event. visibility, attendeeVisibility and capacity keep their current values when left out; a null capacity removes the limit. The capacity can't be lowered below the number of attendees.
 //	@Tags			events
 //	@Accept			json
 //	@Produce		json
@@ -108,14 +217,14 @@ func (app *application) updateEvent(c *gin.Context) {
 	}
 
 	user:=app.GetUserFromContext(c)
-	existingevent, err := app.models.Events.Get(id)
+	existingevent, err := app.modelsFor(c).Events.Get(id)
 
 	if existingevent == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
 	}
 
-	if user.Id!=existingevent.Id{
+	if user.Id!=existingevent.OwnerId{
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to update this event"})
 		return
 	}
@@ -133,11 +242,33 @@ func (app *application) updateEvent(c *gin.Context) {
 		return
 	}
 	updatedEvent.Id = id
-	errr := app.models.Events.Update(updatedEvent)
+	updatedEvent.OwnerId = existingevent.OwnerId
+	//settings left out keep their values, so a private event doesn't
+	//become public by accident
+	if updatedEvent.Visibility == "" {
+		updatedEvent.Visibility = existingevent.Visibility
+	}
+	if updatedEvent.AttendeeVisibility == "" {
+		updatedEvent.AttendeeVisibility = existingevent.AttendeeVisibility
+	}
+	//so does the capacity, which an explicit null removes
+	if updatedEvent.Capacity == nil && !hasJSONField(c, "capacity") {
+		updatedEvent.Capacity = existingevent.Capacity
+	}
+	errr := app.modelsFor(c).Events.Update(updatedEvent)
+	if errors.Is(errr, database.ErrCapacityBelowAttendees) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Capacity is lower than the number of attendees"})
+		return
+	}
 	if errr != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update event"})
 		return
 	}
+	if err := app.rescheduleReminders(id); err != nil {
+		log.Printf("failed to reschedule reminders of event %d: %v", id, err)
+	}
+	app.emitWebhook(webhooks.EventUpdated, updatedEvent, updatedEvent)
+	app.hub.Publish(id, streamEventUpdated, updatedEvent)
 	c.JSON(http.StatusCreated, updatedEvent)
 }
 
@@ -160,7 +291,7 @@ func (app *application) deleteEvent(c *gin.Context) {
 	}
 
 	user:=app.GetUserFromContext(c)
-	existingEvent,err:=app.models.Events.Get(id)
+	existingEvent,err:=app.modelsFor(c).Events.Get(id)
 	if existingEvent==nil{
 		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
 		return
@@ -174,14 +305,42 @@ func (app *application) deleteEvent(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete this event"})
 		return
 	}
-	err = app.models.Events.Delete(id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete a event"})
+
+	if !app.removeEvent(c, existingEvent, nil) {
 		return
 	}
 	c.JSON(http.StatusNoContent, gin.H{"success": "OK"})
 }
 
+// removeEvent deletes an event after refunding its paid tickets, writing
+// the error response if that fails. audit, if given, is recorded with the
+// deletion.
+func (app *application) removeEvent(c *gin.Context, event *database.Event, audit *database.AdminAction) bool {
+	//paid tickets are refunded before their orders are deleted with the event
+	paidOrders, err := app.modelsFor(c).Orders.GetPaidByEvent(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
+		return false
+	}
+	ctx, cancel := paymentContext(c)
+	defer cancel()
+	if err := app.refundOrders(ctx, paidOrders); err != nil {
+		log.Printf("failed to refund orders of event %d: %v", event.Id, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to refund tickets"})
+		return false
+	}
+
+	err = app.modelsFor(c).Events.Delete(event.Id, audit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete a event"})
+		return false
+	}
+	app.emitWebhook(webhooks.EventDeleted, event, event)
+	//the stream of a deleted event ends, and its history goes with it
+	app.hub.Remove(event.Id)
+	return true
+}
+
 
 // AddAttendeeToEvent adds an attendee to an event
 // @Summary		Adds an attendee to an event
@@ -208,7 +367,7 @@ func (app *application) addAttendeeToEvent(c *gin.Context) {
 	}
 
 	//Checking of getting event details from event id
-	event, err := app.models.Events.Get(eventId)
+	event, err := app.modelsFor(c).Events.Get(eventId)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
 		return
@@ -219,7 +378,7 @@ func (app *application) addAttendeeToEvent(c *gin.Context) {
 	}
 
 	//Checking of getting user details from userid
-	userToAdd, err := app.models.Users.Get(userid)
+	userToAdd, err := app.modelsFor(c).Users.Get(userid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user details"})
 		return
@@ -235,53 +394,106 @@ func (app *application) addAttendeeToEvent(c *gin.Context) {
 		return
 	}
 
-	//checking of user in attendee table
-	existingAttendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, userToAdd.Id)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check existing attendee"})
-		return
-	}
-	if existingAttendee != nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "Attendee already exist"})
-		return
-	}
-
 	//Insertion of user in attendees table
 	attendee := database.Attendee{
 		UserId:  userToAdd.Id,
 		EventId: event.Id,
 	}
-	attendeeResult, err := app.models.Attendees.Insert(&attendee)
+	attendeeResult, err := app.modelsFor(c).Attendees.Insert(&attendee)
+	if errors.Is(err, database.ErrAlreadyAttending) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Attendee already exist"})
+		return
+	}
+	if errors.Is(err, database.ErrEventFull) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Event is full"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add Attendee"})
 		return
 	}
+	app.metrics.registrations.Inc()
+	app.emitWebhook(webhooks.AttendeeAdded, event, gin.H{"attendee": attendeeResult, "user": userToAdd})
+	app.publishAttendee(event.Id, streamAttendeeJoined, userid, attendeeResult)
 	c.JSON(http.StatusCreated, attendeeResult)
 }
 
-// GetAttendeesForEvent returns all attendees for a given event
+// attendeeList is an event's attendee list as far as the caller may see it.
+// Callers who may not see names only get the count, with null attendees.
+type attendeeList struct {
+	Count     int              `json:"count"`
+	Attendees []listedAttendee `json:"attendees"`
+}
+
+// listedAttendee is an attendee on an attendee list. Emails are only shown
+// to the organizer.
+type listedAttendee struct {
+	Id    int    `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email,omitempty"`
+}
+
+// GetAttendeesForEvent returns the attendees of an event
 //
-//	@Summary		Returns all attendees for a given event
-//	@Description	Returns all attendees for a given event
+//	@Summary		Returns the attendees of an event
+//	@Description	Returns the number of attendees of an event and, depending on the event's attendeeVisibility, their names. With public, any signed in user sees the names, with attendees only other attendees, and with organizers only the organizer. Users who opted out of attendee lists are left out for everyone but the organizer, who also sees emails.
 //	@Tags			attendees
 //	@Accept			json
 //	@Produce		json
 //	@Param			id	path		int	true	"Event ID"
-//	@Success		200	{object}	[]database.User
+//	@Success		200	{object}	attendeeList
 //	@Router			/api/v1/events/{id}/attendees [get]
 func (app *application) getAttendeesForEvent(c *gin.Context) {
-	id, err := strconv.Atoi(c.Param("id")) //Taking eventid
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Ivalid Event Id"})
+	event, ok := app.viewableEvent(c)
+	if !ok {
 		return
 	}
 
-	users, err := app.models.Attendees.GetAttendeesByEvent(id)
+	users, err := app.modelsFor(c).Attendees.GetAttendeesByEvent(event.Id)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attendees for event"})
 		return
 	}
-	c.JSON(http.StatusOK, users)
+
+	list := attendeeList{Count: len(users)}
+	viewer := app.GetUserFromContext(c)
+	organizer := viewer.Id != 0 && viewer.Id == event.OwnerId
+	attends := false
+	for _, user := range users {
+		if user.Id == viewer.Id {
+			attends = true
+		}
+	}
+	if !organizer && !canListAttendees(event, viewer, attends) {
+		c.JSON(http.StatusOK, list)
+		return
+	}
+	list.Attendees = []listedAttendee{}
+	for _, user := range users {
+		attendee := listedAttendee{Id: user.Id, Name: user.Name}
+		if organizer {
+			attendee.Email = user.Email
+		} else if user.HideFromAttendeeLists && user.Id != viewer.Id {
+			continue
+		}
+		list.Attendees = append(list.Attendees, attendee)
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// canListAttendees reports whether a signed in user other than the organizer,
+// who attends the event or not, may see the names on its attendee list.
+func canListAttendees(event *database.Event, viewer *database.User, attends bool) bool {
+	if viewer.Id == 0 {
+		return false
+	}
+	switch event.AttendeeVisibility {
+	case database.AttendeesPublic:
+		return true
+	case database.AttendeesAttendees:
+		return attends
+	}
+	return false
 }
 
 // DeleteAttendeeFromEvent deletes an attendee from an event
@@ -307,7 +519,7 @@ func (app *application) deleteAtendeeFromEvent(c *gin.Context) {
 		return
 	}
 
-	event,err:=app.models.Events.Get(eventid)
+	event,err:=app.modelsFor(c).Events.Get(eventid)
 	if err!=nil{
 		c.JSON(http.StatusInternalServerError,gin.H{"error":"Something went wrong"})
 		return
@@ -321,18 +533,85 @@ func (app *application) deleteAtendeeFromEvent(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to delete an attendee"})
 		return
 	}
-	err = app.models.Attendees.Delete(userid, eventid)
+
+	paidOrders, err := app.modelsFor(c).Orders.GetPaidByAttendee(eventid, userid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
+		return
+	}
+	ctx, cancel := paymentContext(c)
+	defer cancel()
+	if err := app.refundOrders(ctx, paidOrders); err != nil {
+		log.Printf("failed to refund orders of user %d for event %d: %v", userid, eventid, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to refund tickets"})
+		return
+	}
+
+	err = app.modelsFor(c).Attendees.Delete(userid, eventid)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete Attendee"})
 		return
 	}
+	app.emitWebhook(webhooks.AttendeeRemoved, event, gin.H{"userId": userid, "eventId": eventid})
+	app.publishAttendee(eventid, streamAttendeeLeft, userid, gin.H{"userId": userid, "eventId": eventid})
 	c.JSON(http.StatusNoContent, gin.H{"success": "OK"})
 }
 
+// CheckInAttendee checks an attendee in at an event
+// @Summary		Checks an attendee in at an event
+// @Description	Records the arrival time of an attendee. Only the event owner can check attendees in.
+// @Tags			attendees
+// @Accept			json
+// @Produce		json
+// @Param			id	path		int	true	"Event ID"
+// @Param			userId	path		int	true	"User ID"
+// @Success		200		{object}	database.Attendee
+// @Router			/api/v1/events/{id}/attendees/{userId}/checkin [post]
+// @Security		BearerAuth
+func (app *application) checkInAttendee(c *gin.Context) {
+	eventid, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event id"})
+		return
+	}
+	userid, err := strconv.Atoi(c.Param("userid"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user id"})
+		return
+	}
+
+	event, err := app.modelsFor(c).Events.Get(eventid)
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	user := app.GetUserFromContext(c)
+	if user.Id != event.OwnerId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to check in attendees"})
+		return
+	}
+
+	attendee, err := app.modelsFor(c).Attendees.CheckIn(eventid, userid)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in attendee"})
+		return
+	}
+	if attendee == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Attendee not found"})
+		return
+	}
+	app.publishAttendee(eventid, streamCheckIn, userid, attendee)
+	c.JSON(http.StatusOK, attendee)
+}
+
 // GetEventsByAttendee returns all events for a given attendee
 //
 //	@Summary		Returns all events for a given attendee
-//	@Description	Returns all events for a given attendee
+//	@Description	Returns the events a user attends. Users see all of their own events and organizers see their events. Everyone else only sees public events whose attendee list would show them the user, and nothing for users hidden from attendee lists; anonymous callers get nothing.
 //	@Tags			attendees
 //	@Accept			json
 //	@Produce		json
@@ -346,7 +625,9 @@ func (app *application) getEventsByAttendee(c *gin.Context) {
 		return
 	}
 
-	events, err := app.models.Attendees.GetByAttendee(id)
+	//the events are left out whose attendee list wouldn't show the user
+	user := app.GetUserFromContext(c)
+	events, err := app.modelsFor(c).Attendees.GetByAttendee(id, user.Id)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attendees"})
@@ -355,3 +636,111 @@ func (app *application) getEventsByAttendee(c *gin.Context) {
 
 	c.JSON(http.StatusOK, events)
 }
+
+// ownedEvent loads the event named by the id parameter and checks that the
+// current user owns it, writing the error response if not.
+func (app *application) ownedEvent(c *gin.Context) (*database.Event, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil, false
+	}
+
+	event, err := app.modelsFor(c).Events.Get(id)
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return nil, false
+	}
+
+	user := app.GetUserFromContext(c)
+	if user.Id != event.OwnerId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to manage this event"})
+		return nil, false
+	}
+	return event, true
+}
+
+// hasJSONField reports whether the JSON object in the request body, bound
+// with ShouldBindBodyWithJSON, has a field, which may be null.
+func hasJSONField(c *gin.Context, name string) bool {
+	body, ok := c.Get(gin.BodyBytesKey)
+	if !ok {
+		return false
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body.([]byte), &fields); err != nil {
+		return false
+	}
+	_, ok = fields[name]
+	return ok
+}
+
+// viewableEvent loads the event named by the id parameter, writing the error
+// response if it doesn't exist or is private and the caller may not see it.
+func (app *application) viewableEvent(c *gin.Context) (*database.Event, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid event ID"})
+		return nil, false
+	}
+
+	event, err := app.modelsFor(c).Events.Get(id)
+	if event == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return nil, false
+	}
+
+	allowed, err := app.canViewEvent(c, event)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return nil, false
+	}
+	//private events look the same as missing ones to outsiders
+	if !allowed {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+		return nil, false
+	}
+	return event, true
+}
+
+// canViewEvent reports whether the caller may see an event. Private events
+// are visible to their owner and attendees, and to anyone passing a usable
+// invite token of the event in the invite query parameter.
+func (app *application) canViewEvent(c *gin.Context, event *database.Event) (bool, error) {
+	if event.Visibility != database.VisibilityPrivate {
+		return true, nil
+	}
+
+	user := app.GetUserFromContext(c)
+	if user.Id != 0 {
+		if user.Id == event.OwnerId {
+			return true, nil
+		}
+		attendee, err := app.modelsFor(c).Attendees.GetByEventAndAttendee(event.Id, user.Id)
+		if err != nil {
+			return false, err
+		}
+		if attendee != nil {
+			return true, nil
+		}
+	}
+
+	if token := c.Query("invite"); token != "" {
+		invite, err := app.modelsFor(c).Invites.GetByToken(token)
+		if err != nil {
+			return false, err
+		}
+		if invite != nil && invite.EventId == event.Id && invite.Usable(time.Now()) && invite.For(user) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+func TestUpdateEventKeepsOmittedSettings(t *testing.T) {
+	app := newTestApp(t)
+	_, token := app.createUser(t, "alice")
+
+	created := app.do(t, http.MethodPost, "/api/v1/events", map[string]any{
+		"name": "Board meeting", "description": "Quarterly board meeting", "date": "2030-01-01T10:00:00Z", "location": "Room 1",
+		"visibility": "private", "attendeeVisibility": "organizers",
+	}, bearer(token)...)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("create: %d %s", created.Code, created.Body)
+	}
+	var event database.Event
+	decode(t, created, &event)
+	path := "/api/v1/events/" + strconv.Itoa(event.Id)
+
+	tests := []struct {
+		name               string
+		settings           map[string]any
+		visibility         string
+		attendeeVisibility string
+	}{
+		{name: "both left out", visibility: "private", attendeeVisibility: "organizers"},
+		{name: "visibility changed", settings: map[string]any{"visibility": "unlisted"}, visibility: "unlisted", attendeeVisibility: "organizers"},
+		{name: "attendee visibility changed", settings: map[string]any{"attendeeVisibility": "attendees"}, visibility: "unlisted", attendeeVisibility: "attendees"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{"name": "Board meeting", "description": "Quarterly board meeting, moved", "date": "2030-01-02T10:00:00Z", "location": "Room 2"}
+			for key, value := range tt.settings {
+				body[key] = value
+			}
+			if response := app.do(t, http.MethodPut, path, body, bearer(token)...); response.Code != http.StatusCreated {
+				t.Fatalf("update: %d %s", response.Code, response.Body)
+			}
+			stored, err := app.models.Events.Get(event.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Visibility != tt.visibility || stored.AttendeeVisibility != tt.attendeeVisibility {
+				t.Fatalf("stored %s/%s, want %s/%s", stored.Visibility, stored.AttendeeVisibility, tt.visibility, tt.attendeeVisibility)
+			}
+		})
+	}
+
+	var listed []database.Event
+	decode(t, app.do(t, http.MethodGet, "/api/v1/events", nil), &listed)
+	for _, e := range listed {
+		if e.Id == event.Id {
+			t.Fatal("updated unlisted event shows up in the listing")
+		}
+	}
+}
+
+func TestUpdateEventByOthersIsForbidden(t *testing.T) {
+	app := newTestApp(t)
+	_, owner := app.createUser(t, "alice")
+	_, other := app.createUser(t, "bob")
+
+	body := map[string]any{"name": "Picnic", "description": "Picnic in the park", "date": "2030-01-01T10:00:00Z", "location": "Park"}
+	//the second event shares its id with the other user, not the owner
+	var event database.Event
+	for range 2 {
+		decode(t, app.do(t, http.MethodPost, "/api/v1/events", body, bearer(owner)...), &event)
+	}
+	path := "/api/v1/events/" + strconv.Itoa(event.Id)
+
+	if response := app.do(t, http.MethodPut, path, body, bearer(other)...); response.Code != http.StatusForbidden {
+		t.Fatalf("update by another user: %d %s", response.Code, response.Body)
+	}
+	if response := app.do(t, http.MethodPut, path, body, bearer(owner)...); response.Code != http.StatusCreated {
+		t.Fatalf("update by the owner: %d %s", response.Code, response.Body)
+	}
+}
+
+func TestUpdateEventCapacity(t *testing.T) {
+	app := newTestApp(t)
+	_, token := app.createUser(t, "alice")
+	var attendees []*database.User
+	for _, name := range []string{"bob", "carol"} {
+		user, _ := app.createUser(t, name)
+		attendees = append(attendees, user)
+	}
+
+	var event database.Event
+	decode(t, app.do(t, http.MethodPost, "/api/v1/events", map[string]any{
+		"name": "Workshop", "description": "Hands-on workshop", "date": "2030-01-01T10:00:00Z", "location": "Lab", "capacity": 5,
+	}, bearer(token)...), &event)
+	for _, user := range attendees {
+		if _, err := app.models.Attendees.Insert(&database.Attendee{UserId: user.Id, EventId: event.Id}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := "/api/v1/events/" + strconv.Itoa(event.Id)
+
+	tests := []struct {
+		name     string
+		omitted  bool
+		capacity any
+		status   int
+		want     *int
+	}{
+		{name: "left out", omitted: true, status: http.StatusCreated, want: ptr(5)},
+		{name: "raised", capacity: 10, status: http.StatusCreated, want: ptr(10)},
+		{name: "lowered to the attendees", capacity: 2, status: http.StatusCreated, want: ptr(2)},
+		{name: "below the attendees", capacity: 1, status: http.StatusConflict, want: ptr(2)},
+		{name: "removed", capacity: nil, status: http.StatusCreated, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := map[string]any{"name": "Workshop", "description": "Hands-on workshop", "date": "2030-01-01T10:00:00Z", "location": "Lab"}
+			if !tt.omitted {
+				body["capacity"] = tt.capacity
+			}
+			if response := app.do(t, http.MethodPut, path, body, bearer(token)...); response.Code != tt.status {
+				t.Fatalf("update: %d %s", response.Code, response.Body)
+			}
+			stored, err := app.models.Events.Get(event.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (stored.Capacity == nil) != (tt.want == nil) || stored.Capacity != nil && *stored.Capacity != *tt.want {
+				t.Fatalf("capacity = %v, want %v", stored.Capacity, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetEventsByAttendee(t *testing.T) {
+	app := newTestApp(t)
+	_, organizer := app.createUser(t, "alice")
+	attendee, attendeeToken := app.createUser(t, "bob")
+	hidden, hiddenToken := app.createUser(t, "carol")
+	other, otherToken := app.createUser(t, "dave")
+
+	events := map[string]int{}
+	for _, e := range []struct{ name, visibility, attendeeVisibility string }{
+		{"public list", "public", "public"},
+		{"attendee list", "public", "attendees"},
+		{"organizer list", "public", "organizers"},
+		{"private", "private", "public"},
+	} {
+		var event database.Event
+		decode(t, app.do(t, http.MethodPost, "/api/v1/events", map[string]any{
+			"name": e.name, "description": "An event to attend", "date": "2030-01-01T10:00:00Z", "location": "Town hall",
+			"visibility": e.visibility, "attendeeVisibility": e.attendeeVisibility,
+		}, bearer(organizer)...), &event)
+		events[e.name] = event.Id
+		for _, user := range []*database.User{attendee, hidden} {
+			if _, err := app.models.Attendees.Insert(&database.Attendee{UserId: user.Id, EventId: event.Id}); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if _, err := app.models.Attendees.Insert(&database.Attendee{UserId: other.Id, EventId: events["attendee list"]}); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.models.Users.SetHideFromAttendeeLists(hidden.Id, true); err != nil {
+		t.Fatal(err)
+	}
+
+	all := []string{"public list", "attendee list", "organizer list", "private"}
+	tests := []struct {
+		name   string
+		user   *database.User
+		viewer string
+		want   []string
+	}{
+		{"anonymous", attendee, "", nil},
+		{"themselves", attendee, attendeeToken, all},
+		{"hidden user themselves", hidden, hiddenToken, all},
+		{"organizer", attendee, organizer, all},
+		{"organizer of a hidden user", hidden, organizer, all},
+		{"fellow attendee", attendee, otherToken, []string{"public list", "attendee list"}},
+		{"other user of a hidden user", hidden, otherToken, nil},
+		{"non-attendee", other, attendeeToken, []string{"attendee list"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.viewer != "" {
+				headers = bearer(tt.viewer)
+			}
+			response := app.do(t, http.MethodGet, "/api/v1/attendees/"+strconv.Itoa(tt.user.Id)+"/events", nil, headers...)
+			if response.Code != http.StatusOK {
+				t.Fatalf("%d %s", response.Code, response.Body)
+			}
+			var got []database.Event
+			decode(t, response, &got)
+			var names []string
+			for _, event := range got {
+				names = append(names, event.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("events = %q, want %q", names, tt.want)
+			}
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
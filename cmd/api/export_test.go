@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExportAttendees(t *testing.T) {
+	app := newTestApp(t)
+	_, owner := app.createUser(t, "alice")
+	_, stranger := app.createUser(t, "mallory")
+	event := app.createEvent(t, owner)
+	for _, name := range []string{"bob", "=carol"} {
+		attendee, _ := app.createUser(t, name)
+		if response := app.do(t, http.MethodPost, fmt.Sprintf("/api/v1/events/%d/attendees/%d", event.Id, attendee.Id), nil, bearer(owner)...); response.Code != http.StatusCreated {
+			t.Fatalf("adding attendee: %d %s", response.Code, response.Body)
+		}
+	}
+	//bob hides from attendee lists and has checked in
+	if _, err := app.db.Exec("update users set hide_from_attendee_lists = 1 where name = 'bob'"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.db.Exec("update attendees set checked_in_at = '2030-01-01T18:05:00Z' where user_id = (select id from users where name = 'bob')"); err != nil {
+		t.Fatal(err)
+	}
+	path := fmt.Sprintf("/api/v1/events/%d/attendees/export", event.Id)
+
+	tests := []struct {
+		name        string
+		query       string
+		token       string
+		wantCode    int
+		contentType string
+		want        [][]string
+	}{
+		{"every column", "", owner, http.StatusOK, "text/csv; charset=utf-8", [][]string{
+			{"Name", "Email", "RSVP status", "Registered at", "Checked in at"},
+			{"bob", "bob@example.com", "checked_in", "*", "2030-01-01T18:05:00Z"},
+			{"'=carol", "'=carol@example.com", "registered", "*", ""},
+		}},
+		{"chosen columns in order", "?columns=email,%20name", owner, http.StatusOK, "text/csv; charset=utf-8", [][]string{
+			{"Email", "Name"},
+			{"bob@example.com", "bob"},
+			{"'=carol@example.com", "'=carol"},
+		}},
+		{"xlsx", "?format=xlsx", owner, http.StatusOK, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", nil},
+		{"unknown format", "?format=pdf", owner, http.StatusBadRequest, "", nil},
+		{"unknown column", "?columns=name,phone", owner, http.StatusBadRequest, "", nil},
+		{"not the organizer", "", stranger, http.StatusForbidden, "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := app.do(t, http.MethodGet, path+tt.query, nil, bearer(tt.token)...)
+			if response.Code != tt.wantCode {
+				t.Fatalf("export: %d %s, want %d", response.Code, response.Body, tt.wantCode)
+			}
+			if tt.wantCode != http.StatusOK {
+				return
+			}
+			if got := response.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+			if got := response.Header().Get("Content-Disposition"); !strings.Contains(got, fmt.Sprintf(`filename="event-%d-attendees.`, event.Id)) {
+				t.Errorf("Content-Disposition = %q", got)
+			}
+			if tt.want == nil {
+				return
+			}
+			rows, err := csv.NewReader(response.Body).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			//registration times are whenever the test ran
+			for _, row := range rows[1:] {
+				if len(row) > 3 && row[3] != "" {
+					row[3] = "*"
+				}
+			}
+			if !slices.EqualFunc(rows, tt.want, slices.Equal) {
+				t.Errorf("export = %q, want %q", rows, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// Healthz reports that the server is running
+//
+//	@Summary		Reports that the server is running
+//	@Description	Liveness probe. It doesn't look at the database; readyz does.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	map[string]string
+//	@Router			/healthz [get]
+func (app *application) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// Readyz reports whether the server can handle requests
+//
+//	@Summary		Reports whether the server can handle requests
+//	@Description	Readiness probe. It pings the database and checks that all migrations known to the server were applied. Responds with 503 and the failed checks otherwise.
+//	@Tags			health
+//	@Produce		json
+//	@Success		200	{object}	map[string]any
+//	@Failure		503	{object}	map[string]any
+//	@Router			/readyz [get]
+func (app *application) readyz(c *gin.Context) {
+	checks := gin.H{"database": "ok", "migrations": "ok"}
+	ready := true
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 2*time.Second)
+	defer cancel()
+	if err := app.db.PingContext(ctx); err != nil {
+		checks["database"] = err.Error()
+		ready = false
+	}
+
+	version, dirty, err := database.SchemaVersion(app.db)
+	switch {
+	case err != nil:
+		checks["migrations"] = err.Error()
+		ready = false
+	case dirty:
+		checks["migrations"] = fmt.Sprintf("migration %d failed halfway", version)
+		ready = false
+	case version < app.latestMigration:
+		checks["migrations"] = fmt.Sprintf("at version %d, %d is available", version, app.latestMigration)
+		ready = false
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": "ready", "checks": checks})
+}
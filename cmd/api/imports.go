@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/jobs"
+	"github.com/gin-gonic/gin"
+)
+
+// jobInviteEmail sends the invite of an imported email that doesn't belong
+// to a user yet.
+const jobInviteEmail = "invite.email"
+
+const (
+	maxImportBytes = 1 << 20
+	maxImportRows  = 5000
+)
+
+type inviteEmailPayload struct {
+	InviteId int    `json:"inviteId"`
+	Name     string `json:"name,omitempty"`
+}
+
+// importRowError is a row of an import file that can't be imported.
+type importRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+type importReport struct {
+	DryRun  bool                     `json:"dryRun"`
+	Summary map[string]int           `json:"summary"`
+	Rows    []*database.ImportResult `json:"rows"`
+}
+
+// ImportAttendees adds attendees to an event from a CSV file
+//
+//	@Summary		Adds attendees to an event from a CSV file
+//	@Description	Imports a CSV file with a header row that has an email column and optionally a name column. Emails of existing users become attendees; other emails get a single use invite by email. Emails already attending or invited, and repeated rows, are skipped. The import is all or nothing: if a row is invalid or the attendees don't fit in the event's capacity, nothing is imported. With dryRun=true the report is returned without importing anything. The file is sent as the body or as the file field of a multipart form.
+//	@Tags			attendees
+//	@Accept			text/csv
+//	@Accept			multipart/form-data
+//	@Produce		json
+//	@Param			id		path		int		true	"Event ID"
+//	@Param			dryRun	query		bool	false	"Only report what would be imported"
+//	@Param			file	formData	file	false	"CSV file"
+//	@Success		201		{object}	importReport
+//	@Success		200		{object}	importReport
+//	@Router			/api/v1/events/{id}/attendees/import [post]
+//	@Security		BearerAuth
+func (app *application) importAttendees(c *gin.Context) {
+	event, ok := app.ownedEvent(c)
+	if !ok {
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dryRun", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dryRun must be true or false"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+	var file io.Reader = c.Request.Body
+	if strings.HasPrefix(c.ContentType(), "multipart/") {
+		header, err := c.FormFile("file")
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The file field is missing or the upload is too large"})
+			return
+		}
+		f, err := header.Open()
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+			return
+		}
+		defer f.Close()
+		file = f
+	}
+
+	rows, rowErrors, err := parseImport(file)
+	if err != nil {
+		var maxBytes *http.MaxBytesError
+		if errors.As(err, &maxBytes) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("The file must be at most %d bytes", maxImportBytes)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(rowErrors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Some rows are invalid, nothing was imported", "rows": rowErrors})
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	results, err := app.modelsFor(c).Attendees.ImportAttendees(event.Id, user.Id, rows, dryRun)
+	report := importReport{DryRun: dryRun, Summary: map[string]int{}, Rows: results}
+	for _, result := range results {
+		report.Summary[result.Status]++
+	}
+	if errors.Is(err, database.ErrEventFull) {
+		c.JSON(http.StatusConflict, gin.H{"error": "The attendees don't fit in the event, nothing was imported", "report": report})
+		return
+	}
+	if err != nil {
+		log.Printf("failed to import attendees of event %d: %v", event.Id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import attendees"})
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	for _, result := range results {
+		switch result.Status {
+		case database.ImportAdded:
+			app.announceAttendee(event.Id, result.Attendee)
+		case database.ImportInvited:
+			payload := inviteEmailPayload{InviteId: result.InviteId, Name: result.Name}
+			opts := jobs.Options{DedupeKey: fmt.Sprintf("%s:%d", jobInviteEmail, result.InviteId)}
+			if _, err := app.queue.Enqueue(jobInviteEmail, payload, opts); err != nil {
+				log.Printf("failed to enqueue the email of invite %d: %v", result.InviteId, err)
+			}
+		}
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// parseImport reads the rows of an import file. Rows with an invalid email
+// are returned as row errors; an unreadable file is an error.
+func parseImport(file io.Reader) ([]database.ImportRow, []importRowError, error) {
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("The file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("The file is not valid CSV: %w", err)
+	}
+	emailColumn, nameColumn := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))) {
+		case "email":
+			emailColumn = i
+		case "name":
+			nameColumn = i
+		}
+	}
+	if emailColumn < 0 {
+		return nil, nil, errors.New("The header row must have an email column")
+	}
+
+	var rows []database.ImportRow
+	var rowErrors []importRowError
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("The file is not valid CSV: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		if len(rows)+len(rowErrors) == maxImportRows {
+			return nil, nil, fmt.Errorf("The file must have at most %d rows", maxImportRows)
+		}
+
+		row := database.ImportRow{Line: line}
+		if emailColumn < len(record) {
+			row.Email = strings.TrimSpace(record[emailColumn])
+		}
+		if nameColumn >= 0 && nameColumn < len(record) {
+			row.Name = strings.TrimSpace(record[nameColumn])
+		}
+		if address, err := mail.ParseAddress(row.Email); err != nil || address.Address != row.Email {
+			rowErrors = append(rowErrors, importRowError{Line: line, Error: fmt.Sprintf("invalid email %q", row.Email)})
+			continue
+		}
+		rows = append(rows, row)
+	}
+	return rows, rowErrors, nil
+}
+
+// sendInviteEmail sends an imported invite with a new token, since the one
+// it was created with wasn't kept. Invites that were revoked or used in the
+// meantime are skipped.
+func (app *application) sendInviteEmail(ctx context.Context, job *database.Job) error {
+	var payload inviteEmailPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+	invite, err := app.models.WithContext(ctx).Invites.Get(payload.InviteId)
+	if err != nil || invite == nil || invite.Email == nil || !invite.Usable(time.Now()) {
+		return err
+	}
+	event, err := app.models.WithContext(ctx).Events.Get(invite.EventId)
+	if err != nil {
+		return err
+	}
+	inviter, err := app.models.WithContext(ctx).Users.Get(invite.CreatedBy)
+	if err != nil || inviter == nil {
+		return err
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		return err
+	}
+	if err := app.models.WithContext(ctx).Invites.SetToken(invite.Id, token); err != nil {
+		return err
+	}
+	return app.mailer.Send(ctx, inviteMessage(inviter, event, *invite.Email, payload.Name, app.inviteURL(token)))
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/mailer"
+	"github.com/gin-gonic/gin"
+)
+
+type inviteRequest struct {
+	Email     *string    `json:"email" binding:"omitempty,email"`
+	MaxUses   *int       `json:"maxUses" binding:"omitempty,min=1"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// inviteResponse includes the token, which is only shown when the invite is
+// created.
+type inviteResponse struct {
+	*database.Invite
+	Token string `json:"token"`
+	Url   string `json:"url"`
+}
+
+type invitePreview struct {
+	Invite *database.Invite `json:"invite"`
+	Event  *database.Event  `json:"event"`
+}
+
+// CreateInvite creates an invite link for an event
+//
+//	@Summary		Creates an invite link for an event
+//	@Description	Creates an invite link that lets its holders see the event, even if it is private, and RSVP to it. Invites can expire, be limited to a number of uses and be bound to an email address, which is then sent the link.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			id		path		int				true	"Event ID"
+//	@Param			invite	body		inviteRequest	true	"Invite"
+//	@Success		201		{object}	inviteResponse
+//	@Router			/api/v1/events/{id}/invites [post]
+//	@Security		BearerAuth
+func (app *application) createInvite(c *gin.Context) {
+	event, ok := app.ownedEvent(c)
+	if !ok {
+		return
+	}
+
+	var request inviteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expiresAt must be in the future"})
+		return
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	invite := database.Invite{
+		EventId:   event.Id,
+		Email:     request.Email,
+		MaxUses:   request.MaxUses,
+		ExpiresAt: request.ExpiresAt,
+		CreatedBy: user.Id,
+	}
+	if err := app.modelsFor(c).Invites.Insert(&invite, token); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create invite"})
+		return
+	}
+	url := app.inviteURL(token)
+
+	if invite.Email != nil {
+		message := inviteMessage(user, event, *invite.Email, "", url)
+		if err := app.mailer.Send(c.Request.Context(), message); err != nil {
+			log.Printf("failed to send invite %d: %v", invite.Id, err)
+			if err := app.modelsFor(c).Invites.Delete(invite.Id); err != nil {
+				log.Printf("failed to delete invite %d: %v", invite.Id, err)
+			}
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send invite email"})
+			return
+		}
+	}
+	c.JSON(http.StatusCreated, inviteResponse{Invite: &invite, Token: token, Url: url})
+}
+
+// GetInvites returns the invites of an event
+//
+//	@Summary		Returns the invites of an event
+//	@Description	Returns the invites of an event, newest first. Tokens aren't included.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	[]database.Invite
+//	@Router			/api/v1/events/{id}/invites [get]
+//	@Security		BearerAuth
+func (app *application) getInvites(c *gin.Context) {
+	event, ok := app.ownedEvent(c)
+	if !ok {
+		return
+	}
+
+	invites, err := app.modelsFor(c).Invites.GetByEvent(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invites"})
+		return
+	}
+	c.JSON(http.StatusOK, invites)
+}
+
+// RevokeInvite revokes an invite
+//
+//	@Summary		Revokes an invite
+//	@Description	Revokes an invite so its link can't be used anymore. Attendees who already used it stay registered.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int	true	"Event ID"
+//	@Param			inviteId	path	int	true	"Invite ID"
+//	@Success		204
+//	@Router			/api/v1/events/{id}/invites/{inviteId} [delete]
+//	@Security		BearerAuth
+func (app *application) revokeInvite(c *gin.Context) {
+	event, ok := app.ownedEvent(c)
+	if !ok {
+		return
+	}
+	inviteId, err := strconv.Atoi(c.Param("inviteId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invite ID"})
+		return
+	}
+
+	invite, err := app.modelsFor(c).Invites.Get(inviteId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invite"})
+		return
+	}
+	if invite == nil || invite.EventId != event.Id {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found"})
+		return
+	}
+	if err := app.modelsFor(c).Invites.Revoke(invite.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke invite"})
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{"success": "OK"})
+}
+
+// GetInvite returns an invite and its event
+//
+//	@Summary		Returns an invite and its event
+//	@Description	Returns the invite of a token together with the event it is for, so the recipient can decide whether to RSVP. Invites bound to an email address can only be seen by the user with that address.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	path		string	true	"Invite token"
+//	@Success		200		{object}	invitePreview
+//	@Router			/api/v1/invites/{token} [get]
+func (app *application) getInvite(c *gin.Context) {
+	invite, ok := app.usableInvite(c)
+	if !ok {
+		return
+	}
+	if user := app.GetUserFromContext(c); !invite.For(user) {
+		if user.Id == 0 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in as the invited user to see this invite"})
+			return
+		}
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invite is for a different email address"})
+		return
+	}
+
+	event, err := app.modelsFor(c).Events.Get(invite.EventId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+		return
+	}
+	c.JSON(http.StatusOK, invitePreview{Invite: invite, Event: event})
+}
+
+// RsvpInvite accepts an invite
+//
+//	@Summary		Accepts an invite
+//	@Description	Registers the current user as an attendee of the invite's event. Invites bound to an email address can only be used by the user with that address. Events selling paid tickets can't be RSVPed to; invitees claim a ticket with the invite token passed as ?invite= instead.
+//	@Tags			invites
+//	@Accept			json
+//	@Produce		json
+//	@Param			token	path		string	true	"Invite token"
+//	@Success		201		{object}	database.Attendee
+//	@Router			/api/v1/invites/{token}/rsvp [post]
+//	@Security		BearerAuth
+func (app *application) rsvpInvite(c *gin.Context) {
+	invite, ok := app.usableInvite(c)
+	if !ok {
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	if !invite.For(user) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This invite is for a different email address"})
+		return
+	}
+
+	attendee, err := app.modelsFor(c).Invites.Redeem(c.Param("token"), user.Id)
+	switch {
+	case errors.Is(err, database.ErrInviteInvalid):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found or no longer valid"})
+		return
+	case errors.Is(err, database.ErrAlreadyAttending):
+		c.JSON(http.StatusConflict, gin.H{"error": "Attendee already exist"})
+		return
+	case errors.Is(err, database.ErrEventFull):
+		c.JSON(http.StatusConflict, gin.H{"error": "Event is full"})
+		return
+	case errors.Is(err, database.ErrTicketRequired):
+		c.JSON(http.StatusConflict, gin.H{"error": "This event sells tickets, claim one with the invite instead"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invite"})
+		return
+	}
+	app.announceAttendee(attendee.EventId, attendee)
+	c.JSON(http.StatusCreated, attendee)
+}
+
+// usableInvite loads the invite of the token parameter, writing a not found
+// response if there is none or it can't be used anymore.
+func (app *application) usableInvite(c *gin.Context) (*database.Invite, bool) {
+	invite, err := app.modelsFor(c).Invites.GetByToken(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve invite"})
+		return nil, false
+	}
+	if invite == nil || !invite.Usable(time.Now()) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invite not found or no longer valid"})
+		return nil, false
+	}
+	return invite, true
+}
+
+func newInviteToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func (app *application) inviteURL(token string) string {
+	return app.baseURL + "/api/v1/invites/" + token
+}
+
+// inviteMessage is the email sending an invite link. The greeting is left
+// out if the recipient's name isn't known.
+func inviteMessage(inviter *database.User, event *database.Event, to, name, url string) mailer.Message {
+	body := fmt.Sprintf("%s invited you to %s on %s at %s.\n\nRSVP here: %s\n", inviter.Name, event.Name, event.Date, event.Location, url)
+	if name != "" {
+		body = fmt.Sprintf("Hi %s,\n\n%s", name, body)
+	}
+	return mailer.Message{
+		To:      to,
+		Subject: fmt.Sprintf("You're invited to %s", event.Name),
+		Body:    body,
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+// createInvite creates an invite to the event and returns its token.
+func (app *testApp) createInvite(t *testing.T, token string, eventId int, invite map[string]any) string {
+	t.Helper()
+	response := app.do(t, http.MethodPost, fmt.Sprintf("/api/v1/events/%d/invites", eventId), invite, bearer(token)...)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("creating invite: %d %s", response.Code, response.Body)
+	}
+	var created inviteResponse
+	decode(t, response, &created)
+	return created.Token
+}
+
+func TestEmailBoundInvite(t *testing.T) {
+	app := newTestApp(t)
+	_, owner := app.createUser(t, "alice")
+	_, other := app.createUser(t, "bob")
+	_, invited := app.createUser(t, "carol")
+
+	var event database.Event
+	decode(t, app.do(t, http.MethodPost, "/api/v1/events", map[string]any{
+		"name": "Board meeting", "description": "Quarterly board meeting", "date": "2030-01-01T10:00:00Z", "location": "Room 1", "visibility": "private",
+	}, bearer(owner)...), &event)
+	invite := app.createInvite(t, owner, event.Id, map[string]any{"email": "Carol@example.com"})
+
+	tests := []struct {
+		name    string
+		token   string
+		event   int
+		preview int
+	}{
+		{"anonymous", "", http.StatusNotFound, http.StatusUnauthorized},
+		{"another user", other, http.StatusNotFound, http.StatusForbidden},
+		//events are served with 201, like they always were
+		{"invited user", invited, http.StatusCreated, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.token != "" {
+				headers = bearer(tt.token)
+			}
+			path := fmt.Sprintf("/api/v1/events/%d?invite=%s", event.Id, invite)
+			if response := app.do(t, http.MethodGet, path, nil, headers...); response.Code != tt.event {
+				t.Errorf("event: %d %s, want %d", response.Code, response.Body, tt.event)
+			}
+			if response := app.do(t, http.MethodGet, "/api/v1/invites/"+invite, nil, headers...); response.Code != tt.preview {
+				t.Errorf("preview: %d %s, want %d", response.Code, response.Body, tt.preview)
+			}
+		})
+	}
+}
+
+func TestInviteToEventWithPaidTickets(t *testing.T) {
+	app := newTestApp(t)
+	_, owner := app.createUser(t, "alice")
+	invitee, invited := app.createUser(t, "bob")
+
+	var event database.Event
+	decode(t, app.do(t, http.MethodPost, "/api/v1/events", map[string]any{
+		"name": "Gala dinner", "description": "Annual gala dinner", "date": "2030-01-01T19:00:00Z", "location": "Hotel", "visibility": "private",
+	}, bearer(owner)...), &event)
+	var ticketType database.TicketType
+	decode(t, app.do(t, http.MethodPost, fmt.Sprintf("/api/v1/events/%d/tickets", event.Id), map[string]any{
+		"name": "Dinner", "quota": 50, "priceCents": 9000,
+	}, bearer(owner)...), &ticketType)
+	invite := app.createInvite(t, owner, event.Id, map[string]any{})
+
+	if response := app.do(t, http.MethodPost, "/api/v1/invites/"+invite+"/rsvp", nil, bearer(invited)...); response.Code != http.StatusConflict {
+		t.Fatalf("rsvp: %d %s", response.Code, response.Body)
+	}
+	attendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, invitee.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attendee != nil {
+		t.Fatal("the rsvp registered the invitee without a ticket")
+	}
+
+	path := fmt.Sprintf("/api/v1/events/%d/tickets/%d/claim?invite=%s", event.Id, ticketType.Id, invite)
+	response := app.do(t, http.MethodPost, path, map[string]any{"quantity": 1}, bearer(invited)...)
+	if response.Code != http.StatusCreated {
+		t.Fatalf("claim: %d %s", response.Code, response.Body)
+	}
+	var claimed claimResponse
+	decode(t, response, &claimed)
+	if claimed.Status != database.OrderPending || claimed.Payment == nil {
+		t.Fatalf("claim = %+v, want a pending order with a payment", claimed.Order)
+	}
+}
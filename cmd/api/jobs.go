@@ -0,0 +1,127 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+type jobsResponse struct {
+	Jobs []*database.Job `json:"jobs"`
+	pagination
+}
+
+// GetJobs returns background jobs
+//
+//	@Summary		Returns background jobs
+//	@Description	Returns background jobs, newest first, optionally only those with a status (pending, running, done, dead or cancelled) or type. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			status		query		string	false	"Job status"
+//	@Param			type		query		string	false	"Job type"
+//	@Param			page		query		int		false	"Page number"
+//	@Param			pageSize	query		int		false	"Results per page (max 100)"
+//	@Success		200			{object}	jobsResponse
+//	@Router			/api/v1/admin/jobs [get]
+//	@Security		BearerAuth
+func (app *application) getJobs(c *gin.Context) {
+	status := c.Query("status")
+	switch status {
+	case "", database.JobPending, database.JobRunning, database.JobDone, database.JobDead, database.JobCancelled:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid status"})
+		return
+	}
+	page, ok := readPagination(c)
+	if !ok {
+		return
+	}
+
+	jobs, total, err := app.modelsFor(c).Jobs.List(status, c.Query("type"), page.PageSize, page.offset())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve jobs"})
+		return
+	}
+	page.Total = total
+	c.JSON(http.StatusOK, jobsResponse{Jobs: jobs, pagination: page})
+}
+
+// GetJob returns a background job
+//
+//	@Summary		Returns a background job
+//	@Description	Returns a background job with its payload and last error. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Job ID"
+//	@Success		200	{object}	database.Job
+//	@Router			/api/v1/admin/jobs/{id} [get]
+//	@Security		BearerAuth
+func (app *application) getJob(c *gin.Context) {
+	job, ok := app.jobFromParam(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// RetryJob retries a dead or cancelled background job
+//
+//	@Summary		Retries a dead or cancelled background job
+//	@Description	Puts a dead or cancelled job back in the queue with its attempts reset. Administrators only.
+//	@Tags			admin
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Job ID"
+//	@Success		200	{object}	database.Job
+//	@Router			/api/v1/admin/jobs/{id}/retry [post]
+//	@Security		BearerAuth
+func (app *application) retryJob(c *gin.Context) {
+	job, ok := app.jobFromParam(c)
+	if !ok {
+		return
+	}
+
+	audit := app.adminAction(c, "job.retry", database.TargetJob, &job.Id, map[string]any{"type": job.Type})
+	retried, err := app.modelsFor(c).Jobs.Retry(job.Id, audit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retry job"})
+		return
+	}
+	if !retried {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only dead or cancelled jobs can be retried"})
+		return
+	}
+	app.queue.Wake()
+
+	job, err = app.modelsFor(c).Jobs.Get(job.Id)
+	if err != nil || job == nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve job"})
+		return
+	}
+	c.JSON(http.StatusOK, job)
+}
+
+// jobFromParam loads the job named by the id parameter, writing the error
+// response if it doesn't exist.
+func (app *application) jobFromParam(c *gin.Context) (*database.Job, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return nil, false
+	}
+
+	job, err := app.modelsFor(c).Jobs.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve job"})
+		return nil, false
+	}
+	if job == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Job not found"})
+		return nil, false
+	}
+	return job, true
+}
@@ -1,19 +1,59 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
+	"flag"
 	"log"
+	"os"
+	"strings"
+	"time"
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
 	_ "github.com/anshbadoni30/event-management-app/docs"
+	"github.com/anshbadoni30/event-management-app/internal/cache"
+	"github.com/anshbadoni30/event-management-app/internal/config"
 	"github.com/anshbadoni30/event-management-app/internal/database"
-	"github.com/anshbadoni30/event-management-app/internal/env"
+	"github.com/anshbadoni30/event-management-app/internal/jobs"
+	"github.com/anshbadoni30/event-management-app/internal/mailer"
+	"github.com/anshbadoni30/event-management-app/internal/oidc"
+	"github.com/anshbadoni30/event-management-app/internal/payments"
+	"github.com/anshbadoni30/event-management-app/internal/pubsub"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
 	_ "github.com/joho/godotenv/autoload"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 type application struct {
+	db        *sql.DB
 	port      int
 	jwtSecret string
 	models    database.Models
+	webhooks  *webhooks.Dispatcher
+	hub       *pubsub.Hub
+	queue     *jobs.Queue
+	payments  payments.Provider
+	mailer    mailer.Mailer
+	baseURL   string
+	// statsCache keeps computed statistics for a minute.
+	statsCache *cache.Cache[any]
+	metrics    *metrics
+	// metricsAddr is an extra address serving the metrics without
+	// authentication, for a private network. Empty turns it off.
+	metricsAddr string
+	// latestMigration is the newest migration built into the server, which
+	// the database must be at to be ready.
+	latestMigration int
+	// backupDir holds database backups, of which backupKeep are kept.
+	backupDir  string
+	backupKeep int
+	// reminderOffsets are how long before an event attendees are reminded,
+	// largest first.
+	reminderOffsets []time.Duration
+	// oidc is the identity provider users can log in with, if any, and
+	// oidcSignup whether it creates accounts for users it doesn't know.
+	oidc       *oidc.Provider
+	oidcSignup bool
 }
 
 // @title Event Management System API
@@ -23,24 +63,112 @@ type application struct {
 // @in header
 // @name Authorization
 // @description Enter your bearer token in the format **Bearer &lt;token&gt;**
+// @securityDefinitions.apikey ApiKeyAuth
+// @in header
+// @name X-API-Key
+// @description An API key, which only works on routes its scopes cover
 
 func main() {
-	db, err := sql.Open("sqlite3", "./data.db")
+	cfg, printConfig, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if printConfig {
+		out, err := cfg.YAML()
+		if err != nil {
+			log.Fatal(err)
+		}
+		os.Stdout.Write(out)
+		return
+	}
+
+	db, err := sql.Open("sqlite3", cfg.DatabasePath)
 	if err != nil {
 		log.Fatal(err)
 	}
 	defer db.Close()
+	if cfg.AutoMigrate {
+		from, to, err := database.Migrate(cfg.DatabasePath, migrations.FS)
+		if err != nil {
+			log.Fatal("Failed to migrate the database: ", err)
+		}
+		if from != to {
+			log.Printf("Migrated the database from version %d to %d", from, to)
+		}
+	}
 	_, err = db.Exec("PRAGMA foreign_keys = ON")
 	if err != nil {
 		log.Fatal("Failed to enable foreign keys:", err)
 	}
 	models := database.NewModels(db)
 	app := application{
-		port:      env.GetEnvInt("PORT", 8080),
-		jwtSecret: env.GetEnvString("JWT_SECRET", "some-secret-123456"),
-		models:    models,
+		db:          db,
+		port:        cfg.Port,
+		jwtSecret:   cfg.JWTSecret,
+		models:      models,
+		webhooks:    webhooks.NewDispatcher(&models.Webhooks),
+		hub:         pubsub.NewHub(),
+		queue:       jobs.NewQueue(&models.Jobs),
+		baseURL:     strings.TrimSuffix(cfg.BaseURL, "/"),
+		statsCache:  cache.New[any](statsCacheTTL, statsCacheSize),
+		metrics:     newMetrics(db),
+		backupDir:   cfg.BackupDir,
+		backupKeep:  cfg.BackupKeep,
+		metricsAddr: cfg.MetricsAddr,
+	}
+
+	//without an SMTP server, emails are only logged
+	if cfg.SMTP.Host != "" {
+		app.mailer = mailer.NewSMTP(cfg.SMTP.Host, cfg.SMTP.Port, cfg.SMTP.Username, cfg.SMTP.Password, cfg.SMTP.From)
+	} else {
+		app.mailer = mailer.Log{}
+	}
+
+	app.webhooks.AllowPrivate = cfg.WebhookAllowPrivate
+	app.queue.Workers = cfg.JobWorkers
+	app.queue.Register(jobEventReminder, app.handleReminder)
+	app.queue.Register(jobReminderEmail, app.handleReminder)
+	app.queue.Register(jobInviteEmail, app.sendInviteEmail)
+
+	app.latestMigration, err = database.LatestMigration(migrations.FS)
+	if err != nil {
+		log.Fatal("Failed to read the migrations: ", err)
+	}
+
+	app.reminderOffsets, err = parseReminderOffsets(cfg.ReminderOffsets)
+	if err != nil {
+		log.Fatal("Invalid reminderOffsets: ", err)
+	}
+
+	shutdownTracing, err := setupTracing(cfg.TraceExporter)
+	if err != nil {
+		log.Fatal("Failed to set up tracing: ", err)
+	}
+
+	switch cfg.Payments.Provider {
+	case "stripe":
+		app.payments = payments.NewStripe(cfg.Payments.StripeSecretKey, cfg.Payments.StripeWebhookSecret, cfg.Payments.StripeAPIURL)
+	case "fake":
+		app.payments = payments.NewFake(cfg.Payments.WebhookSecret)
+	}
+	if cfg.OIDC.Issuer != "" {
+		app.oidc = oidc.New(oidc.Config{
+			Issuer:       cfg.OIDC.Issuer,
+			ClientID:     cfg.OIDC.ClientID,
+			ClientSecret: cfg.OIDC.ClientSecret,
+			RedirectURL:  app.baseURL + oidcCallbackPath,
+			Scopes:       strings.Fields(cfg.OIDC.Scopes),
+		})
+		app.oidcSignup = cfg.OIDC.Signup
 	}
 	er := app.serve()
+	//flush the spans of the last requests before exiting
+	if err := shutdownTracing(context.Background()); err != nil {
+		log.Printf("Failed to flush traces: %v", err)
+	}
 	if er != nil {
 		log.Fatal(er)
 	}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/cache"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/jobs"
+	"github.com/anshbadoni30/event-management-app/internal/mailer"
+	"github.com/anshbadoni30/event-management-app/internal/payments"
+	"github.com/anshbadoni30/event-management-app/internal/pubsub"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	gin.DefaultWriter = io.Discard
+}
+
+// testApp is the API on a freshly migrated database of its own.
+type testApp struct {
+	*application
+	handler http.Handler
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if _, _, err := database.Migrate(path, migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+
+	models := database.NewModels(db)
+	app := &application{
+		db:         db,
+		jwtSecret:  "test-secret",
+		models:     models,
+		webhooks:   webhooks.NewDispatcher(&models.Webhooks),
+		hub:        pubsub.NewHub(),
+		queue:      jobs.NewQueue(&models.Jobs),
+		payments:   payments.NewFake("test-webhook-secret"),
+		mailer:     mailer.Log{},
+		baseURL:    "http://localhost:8080",
+		statsCache: cache.New[any](statsCacheTTL, statsCacheSize),
+		metrics:    newMetrics(db),
+	}
+	app.latestMigration, err = database.LatestMigration(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testApp{application: app, handler: app.routes()}
+}
+
+// createUser adds a user and returns them with a login token.
+func (app *testApp) createUser(t *testing.T, name string) (*database.User, string) {
+	t.Helper()
+	user := &database.User{Name: name, Email: name + "@example.com", Password: "x"}
+	if err := app.models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	token, err := app.newToken(user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+// createAdmin adds an administrator and returns them with a login token.
+func (app *testApp) createAdmin(t *testing.T, name string) (*database.User, string) {
+	t.Helper()
+	user, token := app.createUser(t, name)
+	if _, err := app.db.Exec("update users set is_admin = 1 where id = ?", user.Id); err != nil {
+		t.Fatal(err)
+	}
+	return user, token
+}
+
+// createEvent adds a public event owned by the holder of token.
+func (app *testApp) createEvent(t *testing.T, token string) *database.Event {
+	t.Helper()
+	recorder := app.do(t, http.MethodPost, "/api/v1/events", map[string]any{
+		"name": "Meetup", "description": "Monthly meetup", "date": "2030-01-01T18:00:00Z", "location": "Town hall",
+	}, bearer(token)...)
+	if recorder.Code != http.StatusCreated {
+		t.Fatalf("creating event: %d %s", recorder.Code, recorder.Body)
+	}
+	var event database.Event
+	decode(t, recorder, &event)
+	return &event
+}
+
+// do sends a request with an optional JSON body and headers, like
+// "Authorization" or "X-API-Key".
+func (app *testApp) do(t *testing.T, method, path string, body any, headers ...string) *httptest.ResponseRecorder {
+	t.Helper()
+	var payload bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&payload).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &payload)
+	req.Header.Set("Content-Type", "application/json")
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	recorder := httptest.NewRecorder()
+	app.handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func bearer(token string) []string {
+	return []string{"Authorization", "Bearer " + token}
+}
+
+// decode reads a JSON response into v.
+func decode(t *testing.T, recorder *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(recorder.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %s: %v", recorder.Body, err)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// metrics are the Prometheus metrics of the server, served to
+// administrators on /metrics, and on a listener of their own if one is
+// configured.
+type metrics struct {
+	registry        *prometheus.Registry
+	requests        *prometheus.CounterVec
+	requestDuration *prometheus.HistogramVec
+	eventsCreated   prometheus.Counter
+	registrations   prometheus.Counter
+	usersRegistered prometheus.Counter
+}
+
+func newMetrics(db *sql.DB) *metrics {
+	m := &metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "HTTP requests by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by method, route template and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		eventsCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "events_created_total",
+			Help: "Events created.",
+		}),
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "attendee_registrations_total",
+			Help: "Attendees added to events, directly, by invite, by ticket or by import.",
+		}),
+		usersRegistered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "users_registered_total",
+			Help: "User accounts created.",
+		}),
+	}
+	m.registry.MustRegister(
+		m.requests,
+		m.requestDuration,
+		m.eventsCreated,
+		m.registrations,
+		m.usersRegistered,
+		collectors.NewDBStatsCollector(db, "sqlite"),
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// handler serves the metrics in the Prometheus text format.
+func (m *metrics) handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// MetricsMiddleware counts requests and measures their latency. Requests are
+// labelled with their route template, like /api/v1/events/:id, so the number
+// of series stays bounded; requests matching no route share one label.
+func (app *application) MetricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		app.metrics.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		app.metrics.requestDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
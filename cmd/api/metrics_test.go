@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	app := newTestApp(t)
+	_, user := app.createUser(t, "alice")
+	_, admin := app.createAdmin(t, "root")
+	//a request so there is a route in the request metrics
+	app.do(t, http.MethodGet, "/healthz", nil)
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"anonymous", "", http.StatusUnauthorized},
+		{"user", user, http.StatusForbidden},
+		{"administrator", admin, http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.token != "" {
+				headers = bearer(tt.token)
+			}
+			response := app.do(t, http.MethodGet, "/metrics", nil, headers...)
+			if response.Code != tt.status {
+				t.Fatalf("metrics: %d %s", response.Code, response.Body)
+			}
+			if tt.status == http.StatusOK && !strings.Contains(response.Body.String(), `http_requests_total{method="GET",route="/healthz",status="200"} 1`) {
+				t.Errorf("metrics don't count the healthz request:\n%s", response.Body)
+			}
+		})
+	}
+}
@@ -1,16 +1,23 @@
 package main
 
 import (
-	"fmt"
+	"context"
 	"net/http"
 	"strings"
 
+	"github.com/anshbadoni30/event-management-app/internal/database"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt"
 )
 
 func (app *application) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(apiKeyHeader); apiKey != "" {
+			if app.useAPIKey(c, apiKey) {
+				c.Next()
+			}
+			return
+		}
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization Header is required"})
@@ -18,46 +25,116 @@ func (app *application) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		if tokenString == authHeader {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Bearer token is required"})
+		user, message := app.authenticate(c.Request.Context(), authHeader)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
 			c.Abort()
 			return
 		}
+		if !app.accountUsable(c, user) {
+			return
+		}
+		c.Set("user", user)
+		c.Next()
+	}
+}
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
+// OptionalAuthMiddleware sets the user on routes that anyone can use but
+// that show more to signed in users. Requests without an Authorization
+// header or API key pass as anonymous, but a bad token or key is rejected.
+func (app *application) OptionalAuthMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if apiKey := c.GetHeader(apiKeyHeader); apiKey != "" {
+			if app.useAPIKey(c, apiKey) {
+				c.Next()
 			}
-			return []byte(app.jwtSecret), nil
-		})
-
-		if err != nil || !token.Valid {
-			fmt.Println("what")
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
+			return
+		}
+		authHeader := c.GetHeader("Authorization")
+		if authHeader == "" {
+			c.Next()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		user, message := app.authenticate(c.Request.Context(), authHeader)
+		if user == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": message})
 			c.Abort()
 			return
 		}
+		if !app.accountUsable(c, user) {
+			return
+		}
+		c.Set("user", user)
+		c.Next()
+	}
+}
+
+// accountUsable writes the error response for users an administrator
+// locked out. Users who must reset their password may still change it.
+func (app *application) accountUsable(c *gin.Context, user *database.User) bool {
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		c.Abort()
+		return false
+	}
+	if user.PasswordResetRequired && c.FullPath() != changePasswordPath {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password reset required"})
+		c.Abort()
+		return false
+	}
+	return true
+}
 
-		userId := claims["userId"].(float64)
-		user, err := app.models.Users.Get(int(userId))
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized access"})
+// AdminMiddleware limits routes to administrators. It must run after
+// AuthMiddleware.
+func (app *application) AdminMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := app.GetUserFromContext(c)
+		if !user.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Administrator access is required"})
 			c.Abort()
 			return
 		}
-		c.Set("user", user)
 		c.Next()
 	}
 }
 
+// authenticate returns the user of a bearer token, or the error message to
+// respond with if the token isn't valid.
+func (app *application) authenticate(ctx context.Context, authHeader string) (*database.User, string) {
+	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
+	if tokenString == authHeader {
+		return nil, "Bearer token is required"
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrSignatureInvalid
+		}
+		return []byte(app.jwtSecret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return nil, "Invalid token"
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, "Invalid token"
+	}
+
+	userId, ok := claims["userId"].(float64)
+	if !ok {
+		return nil, "Invalid token"
+	}
+	user, err := app.models.WithContext(ctx).Users.Get(int(userId))
+	if err != nil {
+		return nil, "Unauthorized access"
+	}
+	return user, ""
+}
+
 /*
 Explaination of above code:
 1 This code is used to protect specific routes which is used by clients in order to protect the session handling from attackers
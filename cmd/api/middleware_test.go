@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestAuthMiddlewareRejectsBadTokens(t *testing.T) {
+	app := newTestApp(t)
+	user, valid := app.createUser(t, "alice")
+
+	sign := func(method jwt.SigningMethod, key any, claims jwt.MapClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	secret := []byte(app.jwtSecret)
+	exp := time.Now().Add(time.Hour).Unix()
+
+	tests := []struct {
+		name   string
+		header string
+		status int
+	}{
+		{name: "valid", header: "Bearer " + valid, status: http.StatusOK},
+		{name: "no bearer prefix", header: valid, status: http.StatusUnauthorized},
+		{name: "wrong secret", header: "Bearer " + sign(jwt.SigningMethodHS256, []byte("other"), jwt.MapClaims{"userId": user.Id, "exp": exp}), status: http.StatusUnauthorized},
+		{name: "expired", header: "Bearer " + sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"userId": user.Id, "exp": time.Now().Add(-time.Hour).Unix()}), status: http.StatusUnauthorized},
+		{name: "no user id", header: "Bearer " + sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"exp": exp}), status: http.StatusUnauthorized},
+		{name: "user id not a number", header: "Bearer " + sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"userId": "1", "exp": exp}), status: http.StatusUnauthorized},
+		{name: "unsigned", header: "Bearer " + sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, jwt.MapClaims{"userId": user.Id, "exp": exp}), status: http.StatusUnauthorized},
+		{name: "unknown user", header: "Bearer " + sign(jwt.SigningMethodHS256, secret, jwt.MapClaims{"userId": 99, "exp": exp}), status: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := app.do(t, http.MethodGet, "/api/v1/me/stats", nil, "Authorization", tt.header)
+			if response.Code != tt.status {
+				t.Fatalf("status %d, want %d: %s", response.Code, tt.status, response.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/oidc"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oidcCallbackPath is where the identity provider sends users back to. It
+// must be registered as a redirect URI at the provider.
+const oidcCallbackPath = "/api/v1/auth/oidc/callback"
+
+// OIDCLogin starts a login with the identity provider
+//
+//	@Summary		Starts a login with the identity provider
+//	@Description	Redirects to the login page of the OpenID Connect provider, which sends the user back to /api/v1/auth/oidc/callback. The login has to be finished within 10 minutes.
+//	@Tags			auth
+//	@Success		302
+//	@Failure		404	{object}	map[string]string
+//	@Router			/api/v1/auth/oidc/login [get]
+func (app *application) oidcLogin(c *gin.Context) {
+	if app.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "OIDC login is not configured"})
+		return
+	}
+
+	var login database.OIDCLogin
+	for _, value := range []*string{&login.State, &login.Nonce, &login.Verifier} {
+		var err error
+		if *value, err = oidc.RandomString(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+	}
+	url, err := app.oidc.AuthURL(c.Request.Context(), login.State, login.Nonce, login.Verifier)
+	if err != nil {
+		log.Printf("failed to start OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to reach the identity provider"})
+		return
+	}
+	if err := app.modelsFor(c).OIDCLogins.Insert(&login); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start login"})
+		return
+	}
+	c.Redirect(http.StatusFound, url)
+}
+
+// OIDCCallback finishes a login with the identity provider
+//
+//	@Summary		Finishes a login with the identity provider
+//	@Description	Exchanges the code the OpenID Connect provider sent the user back with, verifies the ID token and returns a login token. An identity logs in as the user it was linked to before. Otherwise it is linked to the user with its email address, which the provider must have verified, or a new user is created for it if signing up is allowed.
+//	@Tags			auth
+//	@Produce		json
+//	@Param			code	query		string	true	"Authorization code"
+//	@Param			state	query		string	true	"State of the login"
+//	@Success		200		{object}	loginResponse
+//	@Failure		401		{object}	map[string]string
+//	@Failure		403		{object}	map[string]string
+//	@Router			/api/v1/auth/oidc/callback [get]
+func (app *application) oidcCallback(c *gin.Context) {
+	if app.oidc == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "OIDC login is not configured"})
+		return
+	}
+
+	login, err := app.modelsFor(c).OIDCLogins.Take(c.Query("state"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve login"})
+		return
+	}
+	if reason := c.Query("error"); reason != "" {
+		if description := c.Query("error_description"); description != "" {
+			reason += ": " + description
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "The identity provider refused the login (" + reason + ")"})
+		return
+	}
+	if login == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown or expired login, please start again"})
+		return
+	}
+	if c.Query("code") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization code is required"})
+		return
+	}
+
+	identity, err := app.oidc.Exchange(c.Request.Context(), c.Query("code"), login.Verifier, login.Nonce)
+	if errors.Is(err, oidc.ErrInvalidToken) {
+		log.Printf("refused OIDC login: %v", err)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid ID token"})
+		return
+	}
+	if err != nil {
+		log.Printf("failed to finish OIDC login: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to finish the login at the identity provider"})
+		return
+	}
+
+	user, ok := app.userOfIdentity(c, identity)
+	if !ok {
+		return
+	}
+	if user.DisabledAt != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is disabled"})
+		return
+	}
+	token, err := app.newToken(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "error generating token"})
+		return
+	}
+	c.JSON(http.StatusOK, loginResponse{Token: token, PasswordResetRequired: user.PasswordResetRequired})
+}
+
+// userOfIdentity returns the user an identity logs in as, linking it to the
+// user with its email address or creating a user for it the first time.
+// The error response is written if there is no such user.
+func (app *application) userOfIdentity(c *gin.Context, identity *oidc.Identity) (*database.User, bool) {
+	linked, err := app.modelsFor(c).Identities.Get(app.oidc.Issuer(), identity.Subject)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve identity"})
+		return nil, false
+	}
+	if linked != nil {
+		user, err := app.modelsFor(c).Users.Get(linked.UserId)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+			return nil, false
+		}
+		if err := app.modelsFor(c).Identities.RecordLogin(linked.Id, identity.Email); err != nil {
+			log.Printf("failed to record login of identity %d: %v", linked.Id, err)
+		}
+		return user, true
+	}
+
+	//an unverified address could be anyone's, including an existing user's
+	if identity.Email == "" || !identity.EmailVerified {
+		c.JSON(http.StatusForbidden, gin.H{"error": "The identity provider didn't confirm an email address for this account"})
+		return nil, false
+	}
+	link := database.Identity{Provider: app.oidc.Issuer(), Subject: identity.Subject, Email: identity.Email}
+
+	user, err := app.modelsFor(c).Users.GetByEmail(identity.Email)
+	if err != nil && !errors.Is(err, database.ErrUserNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user"})
+		return nil, false
+	}
+	if user != nil {
+		link.UserId = user.Id
+		if err := app.modelsFor(c).Identities.Insert(&link); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+			return nil, false
+		}
+		return user, true
+	}
+
+	if !app.oidcSignup {
+		c.JSON(http.StatusForbidden, gin.H{"error": "There is no account with this email address"})
+		return nil, false
+	}
+	//users who sign up this way log in with the provider only, so a random
+	//password that nobody knows stands in for theirs
+	secret, err := oidc.RandomString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return nil, false
+	}
+	_, span := tracer.Start(c.Request.Context(), "bcrypt.GenerateFromPassword")
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	span.End()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return nil, false
+	}
+	user = &database.User{
+		Email:          identity.Email,
+		Password:       string(hashedPassword),
+		Name:           identity.Name,
+		EmailReminders: true,
+	}
+	if len(strings.TrimSpace(user.Name)) < 2 {
+		user.Name = identity.Email
+	}
+	if err := app.modelsFor(c).Identities.InsertWithUser(user, &link); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return nil, false
+	}
+	app.metrics.usersRegistered.Inc()
+	return user, true
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+type pagination struct {
+	Page     int `json:"page"`
+	PageSize int `json:"pageSize"`
+	Total    int `json:"total"`
+}
+
+func (p pagination) offset() int {
+	return (p.Page - 1) * p.PageSize
+}
+
+// readPagination parses the page and pageSize query parameters, writing a
+// bad request response if either is invalid.
+func readPagination(c *gin.Context) (pagination, bool) {
+	p := pagination{Page: 1, PageSize: defaultPageSize}
+
+	if value := c.Query("page"); value != "" {
+		page, err := strconv.Atoi(value)
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page"})
+			return p, false
+		}
+		p.Page = page
+	}
+	if value := c.Query("pageSize"); value != "" {
+		pageSize, err := strconv.Atoi(value)
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pageSize"})
+			return p, false
+		}
+		p.PageSize = pageSize
+	}
+	return p, true
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/payments"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// pendingOrderTTL is how long an order of paid tickets holds its
+	// tickets while waiting for the payment.
+	pendingOrderTTL     = 30 * time.Minute
+	orderExpiryInterval = time.Minute
+)
+
+// PaymentWebhook receives payment notifications from the payment provider
+//
+//	@Summary		Receives payment notifications
+//	@Description	Webhook endpoint of the payment provider. Confirms pending ticket orders when their payment succeeds and cancels them when it fails. Payments of orders that expired or were cancelled in the meantime are refunded. Notifications about unknown payment intents get a 404, so the provider sends them again. Requests must carry a valid Stripe-Signature header.
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Success		200
+//	@Router			/api/v1/payments/webhook [post]
+func (app *application) paymentWebhook(c *gin.Context) {
+	payload, err := io.ReadAll(io.LimitReader(c.Request.Body, 1<<20))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body"})
+		return
+	}
+
+	event, err := app.payments.VerifyWebhook(payload, c.Request.Header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook"})
+		return
+	}
+
+	switch event.Type {
+	case payments.EventPaymentSucceeded:
+		order, attendee, err := app.modelsFor(c).Orders.ConfirmPayment(event.IntentId)
+		switch {
+		case errors.Is(err, database.ErrUnknownIntent):
+			//the intent may be stored moments after it was created, so the
+			//provider is asked to send the notification again
+			c.JSON(http.StatusNotFound, gin.H{"error": "Unknown payment intent"})
+			return
+		case errors.Is(err, database.ErrOrderClosed):
+			if err := app.refundLatePayment(c, order); err != nil {
+				log.Printf("failed to refund payment %s of %s order %d: %v", event.IntentId, order.Status, order.Id, err)
+				c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to refund payment"})
+				return
+			}
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm order"})
+			return
+		}
+		if attendee != nil {
+			app.announceAttendee(order.EventId, attendee)
+		}
+	case payments.EventPaymentFailed, payments.EventPaymentCanceled:
+		if err := app.modelsFor(c).Orders.CancelPayment(event.IntentId); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel order"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"received": true})
+}
+
+// refundLatePayment refunds the payment of an order that was cancelled,
+// for instance because it expired, before the payment came through.
+func (app *application) refundLatePayment(c *gin.Context, order *database.Order) error {
+	ctx, cancel := paymentContext(c)
+	defer cancel()
+	if err := app.payments.Refund(ctx, *order.PaymentIntentId); err != nil {
+		return err
+	}
+	log.Printf("refunded payment %s of %s order %d", *order.PaymentIntentId, order.Status, order.Id)
+	return app.modelsFor(c).Orders.MarkRefunded(order.Id)
+}
+
+// runOrderExpiry cancels the pending orders that weren't paid in time
+// until ctx is cancelled, releasing the tickets they hold.
+func (app *application) runOrderExpiry(ctx context.Context) {
+	ticker := time.NewTicker(orderExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		app.expireOrders(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// expireOrders cancels the orders pending for longer than pendingOrderTTL
+// together with their payment intents. An order whose intent can't be
+// cancelled, usually because it was just paid, is left for the provider's
+// notification to confirm; if the notification comes after all, the
+// payment is refunded.
+func (app *application) expireOrders(ctx context.Context) {
+	orders, err := app.models.WithContext(ctx).Orders.GetPendingBefore(time.Now().Add(-pendingOrderTTL))
+	if err != nil {
+		log.Printf("orders: loading expired orders: %v", err)
+		return
+	}
+	for _, order := range orders {
+		if order.PaymentIntentId != nil {
+			if err := app.payments.Cancel(ctx, *order.PaymentIntentId); err != nil {
+				log.Printf("orders: cancelling payment %s of order %d: %v", *order.PaymentIntentId, order.Id, err)
+				continue
+			}
+		}
+		if err := app.models.WithContext(ctx).Orders.Cancel(order.Id); err != nil {
+			log.Printf("orders: cancelling order %d: %v", order.Id, err)
+		}
+	}
+}
+
+// startPayment creates the payment intent of a pending order. If the
+// provider fails, the order is cancelled so its tickets are released.
+func (app *application) startPayment(ctx context.Context, order *database.Order) (*payments.Intent, error) {
+	metadata := map[string]string{
+		"orderId": fmt.Sprint(order.Id),
+		"eventId": fmt.Sprint(order.EventId),
+		"userId":  fmt.Sprint(order.UserId),
+	}
+	intent, err := app.payments.CreateIntent(ctx, order.AmountCents, order.Currency, metadata)
+	if err == nil {
+		err = app.models.WithContext(ctx).Orders.SetPaymentIntent(order.Id, intent.Id)
+	}
+	if err != nil {
+		if cancelErr := app.models.WithContext(ctx).Orders.Cancel(order.Id); cancelErr != nil {
+			log.Printf("failed to cancel order %d: %v", order.Id, cancelErr)
+		}
+		return nil, err
+	}
+	order.PaymentIntentId = &intent.Id
+	return intent, nil
+}
+
+// refundOrders refunds paid orders through the payment provider, stopping
+// at the first failure so nothing is removed before its money is returned.
+// It can be run again after a failure: refunded orders aren't confirmed
+// anymore, and the provider doesn't pay out an interrupted refund twice.
+func (app *application) refundOrders(ctx context.Context, orders []*database.Order) error {
+	for _, order := range orders {
+		if err := app.models.WithContext(ctx).Orders.MarkRefundRequested(order.Id); err != nil {
+			return err
+		}
+		if err := app.payments.Refund(ctx, *order.PaymentIntentId); err != nil {
+			return fmt.Errorf("refunding order %d: %w", order.Id, err)
+		}
+		if err := app.models.WithContext(ctx).Orders.MarkRefunded(order.Id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// announceAttendee notifies webhooks and event streams of a new attendee.
+func (app *application) announceAttendee(eventId int, attendee *database.Attendee) {
+	app.metrics.registrations.Inc()
+	event, err := app.models.Events.Get(eventId)
+	if err != nil {
+		log.Printf("failed to announce attendee of event %d: %v", eventId, err)
+		return
+	}
+	user, err := app.models.Users.Get(attendee.UserId)
+	if err != nil {
+		log.Printf("failed to announce attendee of event %d: %v", eventId, err)
+		return
+	}
+	app.emitWebhook(webhooks.AttendeeAdded, event, gin.H{"attendee": attendee, "user": user})
+	app.publishAttendee(eventId, streamAttendeeJoined, attendee.UserId, attendee)
+}
+
+func paymentContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(c.Request.Context(), 15*time.Second)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/jobs"
+	"github.com/anshbadoni30/event-management-app/internal/mailer"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+// Job types of the reminder scheduler. An event reminder job fans out into
+// one email job per attendee, so a failing mailbox doesn't hold up the rest.
+const (
+	jobEventReminder = "event.reminder"
+	jobReminderEmail = "reminder.email"
+)
+
+const reminderPollInterval = time.Minute
+
+type reminderPayload struct {
+	EventId  int       `json:"eventId"`
+	Offset   string    `json:"offset"`
+	StartsAt time.Time `json:"startsAt"`
+	UserId   int       `json:"userId,omitempty"`
+}
+
+// parseReminderOffsets parses a comma separated list of durations, such as
+// "24h,1h", into offsets sorted from largest to smallest.
+func parseReminderOffsets(value string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		offset, err := time.ParseDuration(part)
+		if err != nil {
+			return nil, err
+		}
+		if offset <= 0 {
+			return nil, fmt.Errorf("reminder offset %s must be positive", part)
+		}
+		offsets = append(offsets, offset)
+	}
+	slices.Sort(offsets)
+	slices.Reverse(offsets)
+	return slices.Compact(offsets), nil
+}
+
+// runReminders schedules reminders of upcoming events until ctx is
+// cancelled. The reminders are sent by the job queue, which keeps them in
+// the database, so reminders that were due while the server was down are
+// sent when it starts again.
+func (app *application) runReminders(ctx context.Context) {
+	ticker := time.NewTicker(reminderPollInterval)
+	defer ticker.Stop()
+
+	for {
+		app.scheduleReminders()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleReminders enqueues the reminders of every event starting within
+// the largest offset. Enqueueing is idempotent, so events are simply
+// rescanned on every run.
+func (app *application) scheduleReminders() {
+	if len(app.reminderOffsets) == 0 {
+		return
+	}
+
+	now := time.Now()
+	events, err := app.models.Events.GetStartingBetween(now, now.Add(app.reminderOffsets[0]))
+	if err != nil {
+		log.Printf("reminders: loading upcoming events: %v", err)
+		return
+	}
+	for _, event := range events {
+		if err := app.enqueueReminders(event.Id, event.StartsAt, now); err != nil {
+			log.Printf("reminders: scheduling event %d: %v", event.Id, err)
+		}
+	}
+}
+
+// enqueueReminders enqueues the reminders of an event starting at startsAt.
+// A reminder is left out if the next smaller one is already due, so an event
+// created an hour before it starts doesn't get its 24h and 1h reminders at
+// once.
+func (app *application) enqueueReminders(eventId int, startsAt, now time.Time) error {
+	//events further out are picked up by a later scan
+	if len(app.reminderOffsets) == 0 || !startsAt.After(now) || now.Before(startsAt.Add(-app.reminderOffsets[0])) {
+		return nil
+	}
+	for i, offset := range app.reminderOffsets {
+		if i+1 < len(app.reminderOffsets) && !now.Before(startsAt.Add(-app.reminderOffsets[i+1])) {
+			continue
+		}
+		payload := reminderPayload{EventId: eventId, Offset: offset.String(), StartsAt: startsAt}
+		opts := jobs.Options{RunAt: startsAt.Add(-offset), DedupeKey: reminderKey(payload)}
+		if _, err := app.queue.Enqueue(jobEventReminder, payload, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rescheduleReminders replaces the pending reminders of an event when it is
+// created or its date may have changed. Reminders already sent for a date
+// aren't repeated if the event is moved back to it.
+func (app *application) rescheduleReminders(eventId int) error {
+	if err := app.models.Jobs.CancelByKeyPrefix(fmt.Sprintf("%s:%d:", jobEventReminder, eventId)); err != nil {
+		return err
+	}
+	if err := app.models.Jobs.CancelByKeyPrefix(fmt.Sprintf("%s:%d:", jobReminderEmail, eventId)); err != nil {
+		return err
+	}
+	startsAt, err := app.models.Events.StartsAt(eventId)
+	if err != nil || startsAt == nil {
+		return err
+	}
+	return app.enqueueReminders(eventId, *startsAt, time.Now())
+}
+
+// reminderKey is the dedupe key of a reminder job. It includes the start
+// time, so moving an event schedules new reminders.
+func reminderKey(payload reminderPayload) string {
+	startsAt := payload.StartsAt.UTC().Format(time.RFC3339)
+	if payload.UserId != 0 {
+		return fmt.Sprintf("%s:%d:%s:%s:%d", jobReminderEmail, payload.EventId, payload.Offset, startsAt, payload.UserId)
+	}
+	return fmt.Sprintf("%s:%d:%s:%s", jobEventReminder, payload.EventId, payload.Offset, startsAt)
+}
+
+// handleReminder is the job handler of both reminder job types.
+func (app *application) handleReminder(ctx context.Context, job *database.Job) error {
+	var payload reminderPayload
+	if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+		return err
+	}
+	if job.Type == jobReminderEmail {
+		return app.sendReminderEmail(ctx, payload)
+	}
+	return app.sendEventReminder(payload)
+}
+
+// reminderCurrent reports whether the event of a reminder still exists,
+// starts at the time the reminder was scheduled for and hasn't started yet.
+func (app *application) reminderCurrent(payload reminderPayload) (bool, error) {
+	startsAt, err := app.models.Events.StartsAt(payload.EventId)
+	if err != nil || startsAt == nil {
+		return false, err
+	}
+	return startsAt.Equal(payload.StartsAt) && time.Now().Before(*startsAt), nil
+}
+
+// sendEventReminder enqueues the reminder emails of the attendees and
+// notifies the organizer's webhooks. Emails are enqueued first, with their
+// own dedupe keys, so a retry doesn't send them twice.
+func (app *application) sendEventReminder(payload reminderPayload) error {
+	if current, err := app.reminderCurrent(payload); err != nil || !current {
+		return err
+	}
+	event, err := app.models.Events.Get(payload.EventId)
+	if err != nil {
+		return err
+	}
+	attendees, err := app.models.Attendees.GetAttendeesByEvent(event.Id)
+	if err != nil {
+		return err
+	}
+	for _, attendee := range attendees {
+		email := payload
+		email.UserId = attendee.Id
+		if _, err := app.queue.Enqueue(jobReminderEmail, email, jobs.Options{DedupeKey: reminderKey(email)}); err != nil {
+			return err
+		}
+	}
+	return app.webhooks.Emit(webhooks.EventReminder, event, gin.H{
+		"event":     event,
+		"startsAt":  payload.StartsAt,
+		"offset":    payload.Offset,
+		"attendees": len(attendees),
+	})
+}
+
+// sendReminderEmail emails a reminder to one attendee, unless they left the
+// event or turned reminders off in the meantime.
+func (app *application) sendReminderEmail(ctx context.Context, payload reminderPayload) error {
+	if current, err := app.reminderCurrent(payload); err != nil || !current {
+		return err
+	}
+	attendee, err := app.models.WithContext(ctx).Attendees.GetByEventAndAttendee(payload.EventId, payload.UserId)
+	if err != nil || attendee == nil {
+		return err
+	}
+	user, err := app.models.WithContext(ctx).Users.Get(payload.UserId)
+	if err != nil {
+		return err
+	}
+	if !user.EmailReminders {
+		return nil
+	}
+	event, err := app.models.WithContext(ctx).Events.Get(payload.EventId)
+	if err != nil {
+		return err
+	}
+
+	//reminders sent late, after a restart for example, say how long is left
+	return app.mailer.Send(ctx, mailer.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("Reminder: %s starts in %s", event.Name, formatTimeLeft(time.Until(payload.StartsAt))),
+		Body: fmt.Sprintf("Hi %s,\n\n%s starts on %s at %s.\n\nYou can turn these reminders off in your notification settings.\n",
+			user.Name, event.Name, payload.StartsAt.Format("Mon, 02 Jan 2006 15:04 MST"), event.Location),
+	})
+}
+
+// formatTimeLeft formats the time until an event for people, in whole
+// hours or minutes, like "24 hours".
+func formatTimeLeft(d time.Duration) string {
+	if d >= time.Hour-30*time.Second {
+		hours := d.Round(time.Hour) / time.Hour
+		if hours == 1 {
+			return "1 hour"
+		}
+		return fmt.Sprintf("%d hours", hours)
+	}
+	minutes := max(d.Round(time.Minute)/time.Minute, 1)
+	if minutes == 1 {
+		return "1 minute"
+	}
+	return fmt.Sprintf("%d minutes", minutes)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/mailer"
+)
+
+// recordingMailer keeps the messages it is asked to send.
+type recordingMailer struct {
+	mu   sync.Mutex
+	sent []mailer.Message
+}
+
+func (m *recordingMailer) Send(ctx context.Context, message mailer.Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sent = append(m.sent, message)
+	return nil
+}
+
+// reminderJobs returns the offsets of an event's reminder jobs with the
+// given status, sorted.
+func (app *testApp) reminderJobs(t *testing.T, eventId int, status string) []string {
+	t.Helper()
+	rows, err := app.db.Query("select payload from jobs where type = ? and status = ?", jobEventReminder, status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	offsets := []string{}
+	for rows.Next() {
+		var raw string
+		var payload reminderPayload
+		if err := rows.Scan(&raw); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(raw), &payload); err != nil {
+			t.Fatal(err)
+		}
+		if payload.EventId == eventId {
+			offsets = append(offsets, payload.Offset)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(offsets)
+	return offsets
+}
+
+func TestParseReminderOffsets(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []time.Duration
+		wantErr bool
+	}{
+		{value: "24h,1h", want: []time.Duration{24 * time.Hour, time.Hour}},
+		{value: "1h, 24h, 1h", want: []time.Duration{24 * time.Hour, time.Hour}},
+		{value: "30m,,2h", want: []time.Duration{2 * time.Hour, 30 * time.Minute}},
+		{value: "", want: nil},
+		{value: "tomorrow", wantErr: true},
+		{value: "1h,-1h", wantErr: true},
+		{value: "0s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseReminderOffsets(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseReminderOffsets(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("parseReminderOffsets(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRescheduleReminders(t *testing.T) {
+	app := newTestApp(t)
+	app.reminderOffsets = []time.Duration{24 * time.Hour, time.Hour}
+	_, token := app.createUser(t, "alice")
+	now := time.Now().UTC().Truncate(time.Minute)
+	event := app.createEvent(t, token)
+
+	tests := []struct {
+		name string
+		// startsIn is when the event is moved to, from now.
+		startsIn      time.Duration
+		wantPending   []string
+		wantCancelled []string
+	}{
+		{"too far out", 5 * 24 * time.Hour, []string{}, []string{}},
+		{"within a day", 10 * time.Hour, []string{"1h0m0s", "24h0m0s"}, []string{}},
+		{"moved later that day", 12 * time.Hour, []string{"1h0m0s", "24h0m0s"}, []string{"1h0m0s", "24h0m0s"}},
+		{"within the hour skips the day before", 30 * time.Minute, []string{"1h0m0s"}, []string{"1h0m0s", "1h0m0s", "24h0m0s", "24h0m0s"}},
+		{"moved back revives the reminders", 10 * time.Hour, []string{"1h0m0s", "24h0m0s"}, []string{"1h0m0s", "1h0m0s", "24h0m0s"}},
+		{"moved into the past", -time.Hour, []string{}, []string{"1h0m0s", "1h0m0s", "1h0m0s", "24h0m0s", "24h0m0s"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event.Date = now.Add(tt.startsIn).Format(time.RFC3339)
+			//updates are answered with 201, like they always were
+			response := app.do(t, http.MethodPut, fmt.Sprintf("/api/v1/events/%d", event.Id), event, bearer(token)...)
+			if response.Code != http.StatusCreated {
+				t.Fatalf("update: %d %s", response.Code, response.Body)
+			}
+			if got := app.reminderJobs(t, event.Id, database.JobPending); !slices.Equal(got, tt.wantPending) {
+				t.Errorf("pending reminders = %q, want %q", got, tt.wantPending)
+			}
+			if got := app.reminderJobs(t, event.Id, database.JobCancelled); !slices.Equal(got, tt.wantCancelled) {
+				t.Errorf("cancelled reminders = %q, want %q", got, tt.wantCancelled)
+			}
+		})
+	}
+}
+
+func TestReminderOfMovedEvent(t *testing.T) {
+	app := newTestApp(t)
+	mails := &recordingMailer{}
+	app.mailer = mails
+	app.reminderOffsets = []time.Duration{24 * time.Hour, time.Hour}
+	_, owner := app.createUser(t, "alice")
+	attendee, _ := app.createUser(t, "bob")
+	if _, err := app.db.Exec("update users set email_reminders = 1 where id = ?", attendee.Id); err != nil {
+		t.Fatal(err)
+	}
+	event := app.createEvent(t, owner)
+	if response := app.do(t, http.MethodPost, fmt.Sprintf("/api/v1/events/%d/attendees/%d", event.Id, attendee.Id), nil, bearer(owner)...); response.Code != http.StatusCreated {
+		t.Fatalf("adding attendee: %d %s", response.Code, response.Body)
+	}
+
+	startsAt := time.Now().UTC().Add(12 * time.Hour).Truncate(time.Second)
+	tests := []struct {
+		name      string
+		startsAt  time.Time
+		wantMails int
+	}{
+		{"scheduled for another time", startsAt.Add(time.Hour), 0},
+		{"current", startsAt, 1},
+	}
+	event.Date = startsAt.Format(time.RFC3339)
+	if response := app.do(t, http.MethodPut, fmt.Sprintf("/api/v1/events/%d", event.Id), event, bearer(owner)...); response.Code != http.StatusCreated {
+		t.Fatalf("update: %d %s", response.Code, response.Body)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mails.sent = nil
+			payload, err := json.Marshal(reminderPayload{EventId: event.Id, Offset: "24h0m0s", StartsAt: tt.startsAt})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := app.handleReminder(context.Background(), &database.Job{Type: jobEventReminder, Payload: string(payload)}); err != nil {
+				t.Fatal(err)
+			}
+			//run the email jobs the reminder enqueued
+			for {
+				job, err := app.models.Jobs.Lease([]string{jobReminderEmail}, "test", time.Now(), time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if job == nil {
+					break
+				}
+				if err := app.handleReminder(context.Background(), job); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := app.models.Jobs.Complete(job, "test"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if len(mails.sent) != tt.wantMails {
+				t.Fatalf("%d mails sent, want %d", len(mails.sent), tt.wantMails)
+			}
+			if tt.wantMails > 0 && (mails.sent[0].To != attendee.Email || !strings.Contains(mails.sent[0].Subject, "starts in 12 hours")) {
+				t.Errorf("mail = %+v", mails.sent[0])
+			}
+		})
+	}
+}
+
+func TestFormatTimeLeft(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{24 * time.Hour, "24 hours"},
+		{23*time.Hour + 50*time.Minute, "24 hours"},
+		{time.Hour, "1 hour"},
+		{59*time.Minute + 45*time.Second, "1 hour"},
+		{45 * time.Minute, "45 minutes"},
+		{time.Minute, "1 minute"},
+		{10 * time.Second, "1 minute"},
+		{-time.Minute, "1 minute"},
+	}
+	for _, tt := range tests {
+		if got := formatTimeLeft(tt.d); got != tt.want {
+			t.Errorf("formatTimeLeft(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -6,22 +6,47 @@ import (
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 func (app *application) routes() http.Handler {
 	g := gin.Default()
+	g.Use(otelgin.Middleware(serviceName), app.TraceIDMiddleware())
+	g.Use(app.MetricsMiddleware())
+
+	//operational endpoints, outside the versioned API
+	g.GET("/healthz", app.healthz) //Liveness probe
+	g.GET("/readyz", app.readyz)   //Readiness probe, checks the database and migrations
+	//Prometheus metrics, for administrators
+	g.GET("/metrics", app.AuthMiddleware(), app.AdminMiddleware(), gin.WrapH(app.metrics.handler()))
+
 	v1 := g.Group("/api/v1")
 	{
 		//events
-		v1.GET("/events", app.getAllEvents) //Print all events
-		v1.GET("/events/:id", app.getEvent) //Print Sepcific Event
+		v1.GET("/events", app.getAllEvents)              //Print all events
+		v1.GET("/events/search", app.searchEvents)       //Full-text search of events
+		v1.GET("/events/nearby", app.nearbyEvents)       //Events within a radius of a coordinate
+		v1.GET("/tags", app.getTags)                     //Print all tags with their usage counts
+		v1.POST("/payments/webhook", app.paymentWebhook) //Payment provider notifications
 		//user
-		v1.POST("/auth/register", app.registerUser) // Register a User and put in user table
-		v1.POST("/auth/login", app.login)           // Login user
-		v1.POST("/user/:id", app.getUser)           //Print info of User
+		v1.POST("/auth/register", app.registerUser)     // Register a User and put in user table
+		v1.POST("/auth/login", app.login)               // Login user
+		v1.GET("/auth/oidc/login", app.oidcLogin)       //Log in with the OpenID Connect provider
+		v1.GET("/auth/oidc/callback", app.oidcCallback) //Finish the login at the OpenID Connect provider
+	}
+
+	//routes that show more to signed in users, like their private events
+	optionalAuthGroup := v1.Group("/")
+	optionalAuthGroup.Use(app.OptionalAuthMiddleware())
+	{
+		optionalAuthGroup.GET("/events/:id", app.getEvent)               //Print Sepcific Event
+		optionalAuthGroup.GET("/events/:id/tickets", app.getTicketTypes) //Print ticket types of an event
+		optionalAuthGroup.GET("/invites/:token", app.getInvite)          //Print an invite and its event
+		optionalAuthGroup.POST("/user/:id", app.getUser)                 //Print info of User, in full only to themselves and admins
 		//attendees
-		v1.GET("/attendees/:id/events", app.getEventsByAttendee)  //Print all events associated with an attendee (taking user id)
-		v1.GET("/events/:id/attendees", app.getAttendeesForEvent) //Print all attendees associated with an event
+		optionalAuthGroup.GET("/attendees/:id/events", app.getEventsByAttendee)  //Print all events associated with an attendee (taking user id)
+		optionalAuthGroup.GET("/events/:id/attendees", app.getAttendeesForEvent) //Print all attendees associated with an event
+		optionalAuthGroup.GET("/events/:id/stream", app.streamEvent)             //Live updates of an event as Server-Sent Events
 	}
 
 	authGroup := v1.Group("/")
@@ -32,13 +57,60 @@ func (app *application) routes() http.Handler {
 		authGroup.DELETE("/events/:id", app.deleteEvent)                              //delete an event
 		authGroup.POST("/events/:id/attendees/:userid", app.addAttendeeToEvent)       //Add attendee in attendees table
 		authGroup.DELETE("/events/:id/attendees/:userid", app.deleteAtendeeFromEvent) // Delete an attendee
+		authGroup.POST("/events/:id/attendees/:userid/checkin", app.checkInAttendee)  //Check an attendee in at the event
+		authGroup.GET("/events/:id/attendees/export", app.exportAttendees)            //Download the attendee list as CSV or XLSX
+		authGroup.POST("/events/:id/attendees/import", app.importAttendees)           //Add attendees from a CSV of emails and names
+		authGroup.GET("/events/:id/stats", app.getEventStats)                         //Attendance statistics of an event
+		authGroup.GET("/me/stats", app.getMyStats)                                    //Attendance statistics of all events of the logged in user
+		authGroup.PUT("/user/privacy", app.updatePrivacy)                             //Opt the logged in user out of attendee lists
+		authGroup.PUT("/user/notifications", app.updateNotifications)                 //Turn reminder emails of the logged in user on or off
+		authGroup.PUT("/user/password", app.changePassword)                           //Change the password of the logged in user
+		//tickets
+		authGroup.POST("/events/:id/tickets", app.createTicketType)             //Add a ticket type to an event
+		authGroup.PUT("/events/:id/tickets/:ticketId", app.updateTicketType)    //Update a ticket type
+		authGroup.DELETE("/events/:id/tickets/:ticketId", app.deleteTicketType) //Delete a ticket type without sales
+		authGroup.POST("/events/:id/tickets/:ticketId/claim", app.claimTicket)  //Order tickets for the logged in user
+		authGroup.GET("/orders", app.getOrders)                                 //Print orders of the logged in user
+		//invites
+		authGroup.POST("/events/:id/invites", app.createInvite)             //Create an invite link for an event
+		authGroup.GET("/events/:id/invites", app.getInvites)                //Print the invites of an event
+		authGroup.DELETE("/events/:id/invites/:inviteId", app.revokeInvite) //Revoke an invite
+		authGroup.POST("/invites/:token/rsvp", app.rsvpInvite)              //Accept an invite as the logged in user
+		//webhooks
+		authGroup.POST("/webhooks", app.createWebhook)                      //Register a webhook for the user's events or a single event
+		authGroup.GET("/webhooks", app.getWebhooks)                         //Print all webhooks of the user
+		authGroup.DELETE("/webhooks/:id", app.deleteWebhook)                //Delete a webhook
+		authGroup.GET("/webhooks/:id/deliveries", app.getWebhookDeliveries) //Print the delivery log of a webhook
+		//api keys
+		authGroup.POST("/api-keys", app.createAPIKey)       //Create a scoped API key for the logged in user
+		authGroup.GET("/api-keys", app.getAPIKeys)          //Print the API keys of the logged in user
+		authGroup.DELETE("/api-keys/:id", app.revokeAPIKey) //Revoke an API key
+	}
+
+	adminGroup := authGroup.Group("/admin")
+	adminGroup.Use(app.AdminMiddleware())
+	{
+		adminGroup.GET("/jobs", app.getJobs)                                //Print background jobs
+		adminGroup.GET("/jobs/:id", app.getJob)                             //Print a background job
+		adminGroup.POST("/jobs/:id/retry", app.retryJob)                    //Retry a dead or cancelled job
+		adminGroup.POST("/backups", app.createBackup)                       //Back up the database
+		adminGroup.GET("/backups", app.getBackups)                          //Print the database backups
+		adminGroup.GET("/backups/:name", app.downloadBackup)                //Download a database backup
+		adminGroup.GET("/users", app.getAdminUsers)                         //Print users
+		adminGroup.POST("/users/:id/disable", app.disableUser)              //Disable a user account
+		adminGroup.POST("/users/:id/enable", app.enableUser)                //Enable a disabled user account
+		adminGroup.POST("/users/:id/reset-password", app.resetUserPassword) //Force a user to change their password
+		adminGroup.POST("/events/:id/transfer", app.transferEvent)          //Hand an event over to another user
+		adminGroup.DELETE("/events/:id", app.adminDeleteEvent)              //Delete any event
+		adminGroup.GET("/stats", app.getSystemStats)                        //Statistics of the whole system
+		adminGroup.GET("/actions", app.getAdminActions)                     //Print the audit log of administrator actions
 	}
 
 	g.GET("/swagger/*any",func(c *gin.Context){
 		if c.Request.RequestURI=="/swagger/"{
 			c.Redirect(302,"/swagger/index.html")
 		}
-		ginSwagger.WrapHandler(swaggerFiles.Handler,ginSwagger.URL("http://localhost:8080/swagger/doc.json"))(c)
+		ginSwagger.WrapHandler(swaggerFiles.Handler,ginSwagger.URL(app.baseURL+"/swagger/doc.json"))(c)
 	})
 	return g
 }
@@ -1,23 +1,103 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
-	"fmt"
 )
 
 func (app *application) serve() error {
 	server := &http.Server{
-		Addr: fmt.Sprintf(":%d",app.port),
-		Handler: app.routes(),
-		IdleTimeout: time.Minute,
-		ReadTimeout: 10 *time.Second,
-		WriteTimeout: 30*time.Second,
+		Addr:         fmt.Sprintf(":%d", app.port),
+		Handler:      app.routes(),
+		IdleTimeout:  time.Minute,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	//metrics can also get a listener of their own, so Prometheus can scrape
+	//them on a private network without credentials
+	var metricsServer *http.Server
+	if app.metricsAddr != "" {
+		listener, err := net.Listen("tcp", app.metricsAddr)
+		if err != nil {
+			return fmt.Errorf("listening for metrics: %w", err)
+		}
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", app.metrics.handler())
+		metricsServer = &http.Server{
+			Handler:      mux,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 30 * time.Second,
+		}
+		log.Printf("Serving metrics on %s", listener.Addr())
+		go func() {
+			if err := metricsServer.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("Metrics server failed: %v", err)
+			}
+		}()
 	}
 
-	log.Printf("Starting server on port %d",app.port)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	return server.ListenAndServe()
-		
-}
\ No newline at end of file
+	//background workers run until the server shuts down
+	var workers sync.WaitGroup
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.webhooks.Run(ctx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.queue.Run(ctx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.runReminders(ctx)
+	}()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		app.runOrderExpiry(ctx)
+	}()
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		log.Printf("Shutting down server")
+		//disconnect event streams so Shutdown doesn't wait on them
+		app.hub.Close()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if metricsServer != nil {
+			metricsServer.Shutdown(shutdownCtx)
+		}
+		shutdownErr <- server.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Starting server on port %d", app.port)
+
+	err := server.ListenAndServe()
+	if !errors.Is(err, http.ErrServerClosed) {
+		if metricsServer != nil {
+			metricsServer.Close()
+		}
+		stop()
+		workers.Wait()
+		return err
+	}
+	err = <-shutdownErr
+	workers.Wait()
+	return err
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	statsDateLayout = "2006-01-02"
+	// statsCacheTTL is how long statistics are served from memory before
+	// they are computed again.
+	statsCacheTTL      = time.Minute
+	statsCacheSize     = 1000
+	maxStatsBuckets    = 366
+	defaultDayBuckets  = 30
+	defaultWeekBuckets = 12
+)
+
+// statsRange is the range of the series of a statistics request.
+type statsRange struct {
+	interval string
+	from, to time.Time
+}
+
+func (r statsRange) key() string {
+	return fmt.Sprintf("%s:%s:%s", r.interval, r.from.Format(statsDateLayout), r.to.Format(statsDateLayout))
+}
+
+// readStatsRange parses the interval, from and to query parameters, writing
+// a bad request response if any is invalid. By default the series covers
+// the last 30 days or 12 weeks, up to today.
+func readStatsRange(c *gin.Context) (statsRange, bool) {
+	r := statsRange{interval: c.DefaultQuery("interval", database.IntervalDay)}
+	if r.interval != database.IntervalDay && r.interval != database.IntervalWeek {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be day or week"})
+		return r, false
+	}
+
+	now := time.Now().UTC()
+	r.to = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if value := c.Query("to"); value != "" {
+		to, err := time.Parse(statsDateLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date like 2006-01-02"})
+			return r, false
+		}
+		r.to = to
+	}
+	if r.interval == database.IntervalWeek {
+		r.from = r.to.AddDate(0, 0, -7*(defaultWeekBuckets-1))
+	} else {
+		r.from = r.to.AddDate(0, 0, -(defaultDayBuckets - 1))
+	}
+	if value := c.Query("from"); value != "" {
+		from, err := time.Parse(statsDateLayout, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date like 2006-01-02"})
+			return r, false
+		}
+		r.from = from
+	}
+
+	buckets := int(r.to.Sub(r.from)/(24*time.Hour)) + 1
+	if r.interval == database.IntervalWeek {
+		buckets = (buckets + 6) / 7
+	}
+	if r.from.After(r.to) || buckets > maxStatsBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("from must be before to, with at most %d %ss between them", maxStatsBuckets, r.interval)})
+		return r, false
+	}
+	return r, true
+}
+
+// GetEventStats returns the statistics of an event
+//
+//	@Summary		Returns the statistics of an event
+//	@Description	Returns the attendee numbers of an event: the RSVP breakdown, check-in rate and cancellations, and a series of registrations, check-ins and cancellations per day or week. Weeks start on Monday; dates are in UTC. Only the organizer can see the statistics, which may be up to a minute old.
+//	@Tags			stats
+//	@Produce		json
+//	@Param			id			path		int		true	"Event ID"
+//	@Param			interval	query		string	false	"Bucket size, day (default) or week"
+//	@Param			from		query		string	false	"First date of the series, like 2006-01-02"
+//	@Param			to			query		string	false	"Last date of the series, today by default"
+//	@Success		200			{object}	database.EventStats
+//	@Router			/api/v1/events/{id}/stats [get]
+//	@Security		BearerAuth
+func (app *application) getEventStats(c *gin.Context) {
+	event, ok := app.ownedEvent(c)
+	if !ok {
+		return
+	}
+	r, ok := readStatsRange(c)
+	if !ok {
+		return
+	}
+
+	key := fmt.Sprintf("event:%d:%s", event.Id, r.key())
+	if stats, ok := app.statsCache.Get(key); ok {
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+	stats, err := app.modelsFor(c).Stats.ForEvent(event.Id, r.interval, r.from, r.to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute statistics"})
+		return
+	}
+	app.statsCache.Set(key, stats)
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetMyStats returns the statistics of the current user's events
+//
+//	@Summary		Returns the statistics of the current user's events
+//	@Description	Returns the statistics of all events the current user organizes, like the statistics of a single event, together with the five events with the most attendees.
+//	@Tags			stats
+//	@Produce		json
+//	@Param			interval	query		string	false	"Bucket size, day (default) or week"
+//	@Param			from		query		string	false	"First date of the series, like 2006-01-02"
+//	@Param			to			query		string	false	"Last date of the series, today by default"
+//	@Success		200			{object}	database.OwnerStats
+//	@Router			/api/v1/me/stats [get]
+//	@Security		BearerAuth
+func (app *application) getMyStats(c *gin.Context) {
+	r, ok := readStatsRange(c)
+	if !ok {
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	key := fmt.Sprintf("owner:%d:%s", user.Id, r.key())
+	if stats, ok := app.statsCache.Get(key); ok {
+		c.JSON(http.StatusOK, stats)
+		return
+	}
+	stats, err := app.modelsFor(c).Stats.ForOwner(user.Id, r.interval, r.from, r.to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute statistics"})
+		return
+	}
+	app.statsCache.Set(key, stats)
+	c.JSON(http.StatusOK, stats)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/pubsub"
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+)
+
+// Message types pushed on the event stream.
+const (
+	streamAttendeeJoined = "attendee-joined"
+	streamAttendeeLeft   = "attendee-left"
+	streamEventUpdated   = "event-updated"
+	streamCheckIn        = "check-in"
+)
+
+const heartbeatInterval = 15 * time.Second
+
+// attendeeMessage is the data of attendee-joined, attendee-left and check-in
+// messages. Subscribers who may not see the user on the event's attendee
+// list only learn that the list changed, see streamedAttendee.
+type attendeeMessage struct {
+	eventId int
+	userId  int
+	// hidden is whether the user opted out of attendee lists.
+	hidden bool
+	data   any
+}
+
+// publishAttendee publishes an attendee message on the stream of an event.
+func (app *application) publishAttendee(eventId int, messageType string, userId int, data any) {
+	message := attendeeMessage{eventId: eventId, userId: userId, data: data}
+	user, err := app.models.Users.Get(userId)
+	if err != nil {
+		//a user that can't be looked up is kept off the list
+		log.Printf("failed to look up attendee %d of event %d: %v", userId, eventId, err)
+		message.hidden = true
+	} else {
+		message.hidden = user.HideFromAttendeeLists
+	}
+	app.hub.Publish(eventId, messageType, message)
+}
+
+// StreamEvent streams live updates of an event
+//
+//	@Summary		Streams live updates of an event
+//	@Description	Server-Sent Events stream of attendee-joined, attendee-left, event-updated and check-in messages. Attendee messages only name the attendee to subscribers the attendee list would show them to; others get just the event id. Send Last-Event-ID to resume after a disconnect.
+//	@Tags			events
+//	@Produce		text/event-stream
+//	@Param			id				path	int		true	"Event ID"
+//	@Param			Last-Event-ID	header	string	false	"Id of the last message received"
+//	@Success		200
+//	@Router			/api/v1/events/{id}/stream [get]
+func (app *application) streamEvent(c *gin.Context) {
+	event, ok := app.viewableEvent(c)
+	if !ok {
+		return
+	}
+
+	lastId, _ := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	sub, missed := app.hub.Subscribe(event.Id, lastId)
+	defer sub.Close()
+
+	//the stream outlives the server's write timeout
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	viewer := app.GetUserFromContext(c)
+	send := func(message pubsub.Message) {
+		switch data := message.Data.(type) {
+		case *database.Event:
+			//later messages are checked against the updated settings
+			event = data
+		case attendeeMessage:
+			message.Data = app.streamedAttendee(event, viewer, data)
+		}
+		writeStreamMessage(c, message)
+	}
+
+	for _, message := range missed {
+		send(message)
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case message, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			send(message)
+		case now := <-heartbeat.C:
+			c.Render(-1, sse.Event{Event: "ping", Data: now.UTC().Format(time.RFC3339)})
+		}
+		c.Writer.Flush()
+	}
+}
+
+// streamedAttendee returns the data of an attendee message for a viewer,
+// who sees as much as the attendee list would show them: organizers see
+// every attendee, others only if they may list the attendees and the user
+// didn't opt out. Everyone else gets just the event id.
+func (app *application) streamedAttendee(event *database.Event, viewer *database.User, message attendeeMessage) any {
+	if viewer.Id != 0 && viewer.Id == event.OwnerId {
+		return message.data
+	}
+	if !message.hidden || message.userId == viewer.Id {
+		attends := false
+		if viewer.Id != 0 && event.AttendeeVisibility == database.AttendeesAttendees {
+			attendee, err := app.models.Attendees.GetByEventAndAttendee(event.Id, viewer.Id)
+			if err != nil {
+				log.Printf("failed to look up attendee %d of event %d: %v", viewer.Id, event.Id, err)
+			}
+			attends = attendee != nil
+		}
+		if canListAttendees(event, viewer, attends) {
+			return message.data
+		}
+	}
+	return gin.H{"eventId": message.eventId}
+}
+
+func writeStreamMessage(c *gin.Context, message pubsub.Message) {
+	c.Render(-1, sse.Event{
+		Id:    strconv.FormatUint(message.Id, 10),
+		Event: message.Type,
+		Data:  message.Data,
+	})
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+func TestStreamedAttendee(t *testing.T) {
+	app := newTestApp(t)
+	organizer, _ := app.createUser(t, "alice")
+	attendee, _ := app.createUser(t, "bob")
+	outsider, _ := app.createUser(t, "carol")
+	anonymous := &database.User{Name: "anonymous"}
+
+	event := &database.Event{OwnerId: organizer.Id, Name: "Meetup", Description: "Monthly meetup", Date: "2030-01-01T10:00:00Z", Location: "Cafe"}
+	if err := app.models.Events.Insert(event); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.models.Attendees.Insert(&database.Attendee{UserId: attendee.Id, EventId: event.Id}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		visibility string
+		hidden     bool
+		viewer     *database.User
+		named      bool
+	}{
+		{visibility: database.AttendeesPublic, viewer: anonymous, named: false},
+		{visibility: database.AttendeesPublic, viewer: outsider, named: true},
+		{visibility: database.AttendeesPublic, viewer: outsider, hidden: true, named: false},
+		{visibility: database.AttendeesPublic, viewer: organizer, hidden: true, named: true},
+		{visibility: database.AttendeesAttendees, viewer: outsider, named: false},
+		{visibility: database.AttendeesAttendees, viewer: attendee, named: true},
+		{visibility: database.AttendeesOrganizers, viewer: attendee, named: false},
+		{visibility: database.AttendeesOrganizers, viewer: organizer, named: true},
+	}
+	for _, tt := range tests {
+		name := tt.visibility + "/" + tt.viewer.Name
+		if tt.hidden {
+			name += "/hidden"
+		}
+		t.Run(name, func(t *testing.T) {
+			event.AttendeeVisibility = tt.visibility
+			data := app.streamedAttendee(event, tt.viewer, attendeeMessage{eventId: event.Id, userId: 99, hidden: tt.hidden, data: "attendee"})
+			if named := data == "attendee"; named != tt.named {
+				t.Fatalf("got %v, want named = %v", data, tt.named)
+			}
+		})
+	}
+}
+
+func TestStreamStripsAttendeesForAnonymousSubscribers(t *testing.T) {
+	app := newTestApp(t)
+	organizer, _ := app.createUser(t, "alice")
+	attendee, _ := app.createUser(t, "bob")
+	event := &database.Event{OwnerId: organizer.Id, Name: "Meetup", Description: "Monthly meetup", Date: "2030-01-01T10:00:00Z", Location: "Cafe"}
+	if err := app.models.Events.Insert(event); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(app.handler)
+	defer server.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/api/v1/events/"+strconv.Itoa(event.Id)+"/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	//the subscription exists once the headers are sent
+	app.publishAttendee(event.Id, streamAttendeeJoined, attendee.Id, &database.Attendee{UserId: attendee.Id, EventId: event.Id})
+
+	lines := bufio.NewScanner(resp.Body)
+	for lines.Scan() {
+		data, ok := strings.CutPrefix(lines.Text(), "data:")
+		if !ok {
+			continue
+		}
+		if want := `{"eventId":` + strconv.Itoa(event.Id) + `}`; data != want {
+			t.Fatalf("data = %s, want %s", data, want)
+		}
+		return
+	}
+	t.Fatalf("stream ended without a message: %v", lines.Err())
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetTags returns all tags in use
+//
+//	@Summary		Returns all tags in use
+//	@Description	Returns all tags in use with the number of events carrying each, most used first
+//	@Tags			events
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]database.Tag
+//	@Router			/api/v1/tags [get]
+func (app *application) getTags(c *gin.Context) {
+	tags, err := app.modelsFor(c).Tags.GetAll()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve tags"})
+		return
+	}
+	c.JSON(http.StatusOK, tags)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/payments"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+type claimRequest struct {
+	Quantity int `json:"quantity" binding:"required,min=1,max=10"`
+}
+
+type claimResponse struct {
+	*database.Order
+	Payment *payments.Intent `json:"payment,omitempty"`
+}
+
+// GetTicketTypes returns the ticket types of an event
+//
+//	@Summary		Returns the ticket types of an event
+//	@Description	Returns the ticket types of an event with the number of tickets sold
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Event ID"
+//	@Success		200	{object}	[]database.TicketType
+//	@Router			/api/v1/events/{id}/tickets [get]
+func (app *application) getTicketTypes(c *gin.Context) {
+	event, ok := app.viewableEvent(c)
+	if !ok {
+		return
+	}
+
+	ticketTypes, err := app.modelsFor(c).TicketTypes.GetByEvent(event.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ticket types"})
+		return
+	}
+	c.JSON(http.StatusOK, ticketTypes)
+}
+
+// CreateTicketType adds a ticket type to an event
+//
+//	@Summary		Adds a ticket type to an event
+//	@Description	Adds a ticket type with its own quota, price and optional sales window to an event
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Event ID"
+//	@Param			ticketType	body		database.TicketType	true	"Ticket type"
+//	@Success		201			{object}	database.TicketType
+//	@Router			/api/v1/events/{id}/tickets [post]
+//	@Security		BearerAuth
+func (app *application) createTicketType(c *gin.Context) {
+	event, ok := app.ownedEvent(c)
+	if !ok {
+		return
+	}
+
+	var ticketType database.TicketType
+	if !bindTicketType(c, &ticketType) {
+		return
+	}
+	ticketType.EventId = event.Id
+	if err := app.modelsFor(c).TicketTypes.Insert(&ticketType); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ticket type"})
+		return
+	}
+	c.JSON(http.StatusCreated, ticketType)
+}
+
+// UpdateTicketType updates a ticket type
+//
+//	@Summary		Updates a ticket type
+//	@Description	Updates a ticket type. The quota can't be lowered below the number of tickets sold.
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int					true	"Event ID"
+//	@Param			ticketId	path		int					true	"Ticket type ID"
+//	@Param			ticketType	body		database.TicketType	true	"Ticket type"
+//	@Success		200			{object}	database.TicketType
+//	@Router			/api/v1/events/{id}/tickets/{ticketId} [put]
+//	@Security		BearerAuth
+func (app *application) updateTicketType(c *gin.Context) {
+	existing, ok := app.ownedTicketType(c)
+	if !ok {
+		return
+	}
+
+	var ticketType database.TicketType
+	if !bindTicketType(c, &ticketType) {
+		return
+	}
+	ticketType.Id = existing.Id
+	ticketType.EventId = existing.EventId
+	ticketType.Sold = existing.Sold
+	ticketType.CreatedAt = existing.CreatedAt
+
+	err := app.modelsFor(c).TicketTypes.Update(&ticketType)
+	if errors.Is(err, database.ErrQuotaBelowSold) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Quota is lower than the number of tickets sold"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ticket type"})
+		return
+	}
+	c.JSON(http.StatusOK, ticketType)
+}
+
+// DeleteTicketType deletes a ticket type
+//
+//	@Summary		Deletes a ticket type
+//	@Description	Deletes a ticket type that has no tickets sold
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path	int	true	"Event ID"
+//	@Param			ticketId	path	int	true	"Ticket type ID"
+//	@Success		204
+//	@Router			/api/v1/events/{id}/tickets/{ticketId} [delete]
+//	@Security		BearerAuth
+func (app *application) deleteTicketType(c *gin.Context) {
+	ticketType, ok := app.ownedTicketType(c)
+	if !ok {
+		return
+	}
+	if ticketType.Sold > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tickets of this type have already been sold"})
+		return
+	}
+	if err := app.modelsFor(c).TicketTypes.Delete(ticketType.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete ticket type"})
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{"success": "OK"})
+}
+
+// ClaimTicket orders tickets for the current user
+//
+//	@Summary		Orders tickets for the current user
+//	@Description	Orders tickets of a ticket type and registers the user as an attendee of the event. Users attending the event already can't order tickets, and free tickets count against the event's capacity. Orders of paid tickets stay pending until the payment provider confirms the payment described in the payment field, and expire after 30 minutes. Users can hold 3 unpaid orders at a time.
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Param			id			path		int				true	"Event ID"
+//	@Param			ticketId	path		int				true	"Ticket type ID"
+//	@Param			order		body		claimRequest	true	"Order"
+//	@Success		201			{object}	claimResponse
+//	@Router			/api/v1/events/{id}/tickets/{ticketId}/claim [post]
+//	@Security		BearerAuth
+func (app *application) claimTicket(c *gin.Context) {
+	ticketType, event, ok := app.eventTicketType(c)
+	if !ok {
+		return
+	}
+
+	var request claimRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user := app.GetUserFromContext(c)
+	order := database.Order{
+		TicketTypeId: ticketType.Id,
+		UserId:       user.Id,
+		Quantity:     request.Quantity,
+	}
+	attendee, err := app.modelsFor(c).Orders.Claim(&order)
+	switch {
+	case errors.Is(err, database.ErrSoldOut):
+		c.JSON(http.StatusConflict, gin.H{"error": "Not enough tickets left"})
+		return
+	case errors.Is(err, database.ErrSalesClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "Ticket sales are closed"})
+		return
+	case errors.Is(err, database.ErrAlreadyAttending):
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already attending this event"})
+		return
+	case errors.Is(err, database.ErrEventFull):
+		c.JSON(http.StatusConflict, gin.H{"error": "Event is full"})
+		return
+	case errors.Is(err, database.ErrPendingOrders):
+		c.JSON(http.StatusConflict, gin.H{"error": "Too many unpaid orders, pay them or wait for them to expire"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to order tickets"})
+		return
+	}
+
+	if order.Status == database.OrderPending {
+		ctx, cancel := paymentContext(c)
+		defer cancel()
+		intent, err := app.startPayment(ctx, &order)
+		if err != nil {
+			log.Printf("failed to start payment of order %d: %v", order.Id, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to start payment"})
+			return
+		}
+		c.JSON(http.StatusCreated, claimResponse{Order: &order, Payment: intent})
+		return
+	}
+
+	app.emitWebhook(webhooks.AttendeeAdded, event, gin.H{"attendee": attendee, "user": user})
+	app.publishAttendee(event.Id, streamAttendeeJoined, attendee.UserId, attendee)
+	c.JSON(http.StatusCreated, claimResponse{Order: &order})
+}
+
+// GetOrders returns the current user's orders
+//
+//	@Summary		Returns the current user's orders
+//	@Description	Returns the current user's ticket orders, newest first
+//	@Tags			tickets
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]database.Order
+//	@Router			/api/v1/orders [get]
+//	@Security		BearerAuth
+func (app *application) getOrders(c *gin.Context) {
+	user := app.GetUserFromContext(c)
+	orders, err := app.modelsFor(c).Orders.GetByUser(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve orders"})
+		return
+	}
+	c.JSON(http.StatusOK, orders)
+}
+
+// bindTicketType binds a ticket type from the request body, writing a bad
+// request response if it is invalid.
+func bindTicketType(c *gin.Context, ticketType *database.TicketType) bool {
+	if err := c.ShouldBindJSON(ticketType); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	if ticketType.SalesStart != nil && ticketType.SalesEnd != nil && !ticketType.SalesEnd.After(*ticketType.SalesStart) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "salesEnd must be after salesStart"})
+		return false
+	}
+	return true
+}
+
+// eventTicketType loads the event and ticket type named by the id and
+// ticketId parameters, writing the error response if either doesn't exist
+// or the event isn't visible to the caller.
+func (app *application) eventTicketType(c *gin.Context) (*database.TicketType, *database.Event, bool) {
+	ticketId, err := strconv.Atoi(c.Param("ticketId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ticket type ID"})
+		return nil, nil, false
+	}
+
+	event, ok := app.viewableEvent(c)
+	if !ok {
+		return nil, nil, false
+	}
+
+	ticketType, err := app.modelsFor(c).TicketTypes.Get(ticketId)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve ticket type"})
+		return nil, nil, false
+	}
+	if ticketType == nil || ticketType.EventId != event.Id {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ticket type not found"})
+		return nil, nil, false
+	}
+	return ticketType, event, true
+}
+
+// ownedTicketType is eventTicketType for routes only the event owner may use.
+func (app *application) ownedTicketType(c *gin.Context) (*database.TicketType, bool) {
+	ticketType, event, ok := app.eventTicketType(c)
+	if !ok {
+		return nil, false
+	}
+	user := app.GetUserFromContext(c)
+	if user.Id != event.OwnerId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to manage tickets of this event"})
+		return nil, false
+	}
+	return ticketType, true
+}
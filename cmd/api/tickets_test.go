@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/payments"
+)
+
+func TestClaimFreeTickets(t *testing.T) {
+	app := newTestApp(t)
+	_, owner := app.createUser(t, "alice")
+
+	var event database.Event
+	decode(t, app.do(t, http.MethodPost, "/api/v1/events", map[string]any{
+		"name": "Workshop", "description": "Hands-on workshop", "date": "2030-01-01T10:00:00Z", "location": "Lab", "capacity": 2,
+	}, bearer(owner)...), &event)
+	var ticketType database.TicketType
+	decode(t, app.do(t, http.MethodPost, fmt.Sprintf("/api/v1/events/%d/tickets", event.Id), map[string]any{
+		"name": "Free", "quota": 10,
+	}, bearer(owner)...), &ticketType)
+	claim := fmt.Sprintf("/api/v1/events/%d/tickets/%d/claim", event.Id, ticketType.Id)
+
+	tokens := map[string]string{}
+	for _, name := range []string{"bob", "carol", "dave"} {
+		_, tokens[name] = app.createUser(t, name)
+	}
+
+	tests := []struct {
+		name   string
+		user   string
+		status int
+		// sold is the number of tickets sold after the claim.
+		sold int
+	}{
+		{"first claim", "bob", http.StatusCreated, 1},
+		{"attendee claims again", "bob", http.StatusConflict, 1},
+		{"last place", "carol", http.StatusCreated, 2},
+		{"event full", "dave", http.StatusConflict, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := app.do(t, http.MethodPost, claim, map[string]any{"quantity": 1}, bearer(tokens[tt.user])...)
+			if response.Code != tt.status {
+				t.Fatalf("claim: %d %s", response.Code, response.Body)
+			}
+			stored, err := app.models.TicketTypes.Get(ticketType.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Sold != tt.sold {
+				t.Errorf("sold = %d, want %d", stored.Sold, tt.sold)
+			}
+		})
+	}
+
+	attendees, err := app.models.Attendees.GetAttendeesByEvent(event.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attendees) != 2 {
+		t.Errorf("%d attendees, want 2", len(attendees))
+	}
+}
+
+// paidTicketClaim creates an event with a paid ticket type and returns the
+// path claiming it.
+func (app *testApp) paidTicketClaim(t *testing.T) string {
+	t.Helper()
+	_, owner := app.createUser(t, "organizer")
+	event := app.createEvent(t, owner)
+	var ticketType database.TicketType
+	decode(t, app.do(t, http.MethodPost, fmt.Sprintf("/api/v1/events/%d/tickets", event.Id), map[string]any{
+		"name": "Regular", "quota": 10, "priceCents": 1500,
+	}, bearer(owner)...), &ticketType)
+	return fmt.Sprintf("/api/v1/events/%d/tickets/%d/claim", event.Id, ticketType.Id)
+}
+
+// notifyPayment sends a payment notification signed like the fake
+// provider's.
+func (app *testApp) notifyPayment(t *testing.T, eventType, intentId string) *httptest.ResponseRecorder {
+	t.Helper()
+	payload, err := json.Marshal(map[string]any{"id": "evt_" + intentId, "type": eventType, "data": map[string]any{"object": map[string]any{"id": intentId}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/payments/webhook", strings.NewReader(string(payload)))
+	req.Header.Set("Stripe-Signature", payments.SignWebhook("test-webhook-secret", payload, time.Now()))
+	recorder := httptest.NewRecorder()
+	app.handler.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestPaymentWebhook(t *testing.T) {
+	app := newTestApp(t)
+	claim := app.paidTicketClaim(t)
+	fake := app.payments.(*payments.Fake)
+
+	tests := []struct {
+		name string
+		// expire lets the order expire before the payment succeeds.
+		expire     bool
+		unknown    bool
+		status     int
+		wantStatus string
+		refunded   bool
+	}{
+		{name: "paid", status: http.StatusOK, wantStatus: database.OrderConfirmed},
+		{name: "paid after expiry", expire: true, status: http.StatusOK, wantStatus: database.OrderRefunded, refunded: true},
+		{name: "unknown intent", unknown: true, status: http.StatusNotFound},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, token := app.createUser(t, fmt.Sprintf("buyer%d", i))
+			var claimed claimResponse
+			decode(t, app.do(t, http.MethodPost, claim, map[string]any{"quantity": 1}, bearer(token)...), &claimed)
+			if claimed.Order == nil || claimed.Payment == nil {
+				t.Fatalf("claim returned no pending order: %+v", claimed)
+			}
+			intentId := claimed.Payment.Id
+			if tt.unknown {
+				intentId = "pi_unknown"
+			}
+			if tt.expire {
+				if _, err := app.db.Exec("update orders set created_at = ? where id = ?", time.Now().UTC().Add(-time.Hour), claimed.Id); err != nil {
+					t.Fatal(err)
+				}
+				app.expireOrders(context.Background())
+			}
+
+			if response := app.notifyPayment(t, payments.EventPaymentSucceeded, intentId); response.Code != tt.status {
+				t.Fatalf("webhook: %d %s", response.Code, response.Body)
+			}
+			if tt.unknown {
+				return
+			}
+			orders, err := app.models.Orders.GetByUser(claimed.UserId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != 1 || orders[0].Status != tt.wantStatus {
+				t.Fatalf("orders = %+v, want one %s order", orders, tt.wantStatus)
+			}
+			if refunded := slices.Contains(fake.Refunds(), claimed.Payment.Id); refunded != tt.refunded {
+				t.Errorf("refunded = %v, want %v", refunded, tt.refunded)
+			}
+			attendee, err := app.models.Attendees.GetByEventAndAttendee(claimed.EventId, claimed.UserId)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (attendee != nil) != (tt.wantStatus == database.OrderConfirmed) {
+				t.Errorf("attendee = %+v after a %s order", attendee, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestExpireOrdersReleasesTickets(t *testing.T) {
+	app := newTestApp(t)
+	claim := app.paidTicketClaim(t)
+	_, token := app.createUser(t, "bob")
+
+	var fresh, stale claimResponse
+	decode(t, app.do(t, http.MethodPost, claim, map[string]any{"quantity": 1}, bearer(token)...), &stale)
+	decode(t, app.do(t, http.MethodPost, claim, map[string]any{"quantity": 1}, bearer(token)...), &fresh)
+	if _, err := app.db.Exec("update orders set created_at = ? where id = ?", time.Now().UTC().Add(-pendingOrderTTL-time.Minute), stale.Id); err != nil {
+		t.Fatal(err)
+	}
+	app.expireOrders(context.Background())
+
+	orders, err := app.models.Orders.GetByUser(stale.UserId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[int]string{}
+	for _, order := range orders {
+		statuses[order.Id] = order.Status
+	}
+	if statuses[stale.Id] != database.OrderCancelled || statuses[fresh.Id] != database.OrderPending {
+		t.Fatalf("statuses = %v, want the stale order cancelled and the fresh one pending", statuses)
+	}
+	//the cancelled intent can't be paid anymore
+	if _, err := app.payments.Confirm(context.Background(), stale.Payment.Id, "pm_card_visa"); err == nil {
+		t.Error("the payment of the expired order went through")
+	}
+}
+
+func TestClaimLimitsUnpaidOrders(t *testing.T) {
+	app := newTestApp(t)
+	claim := app.paidTicketClaim(t)
+	_, token := app.createUser(t, "bob")
+
+	for i := range database.MaxPendingOrders + 1 {
+		want := http.StatusCreated
+		if i == database.MaxPendingOrders {
+			want = http.StatusConflict
+		}
+		if response := app.do(t, http.MethodPost, claim, map[string]any{"quantity": 1}, bearer(token)...); response.Code != want {
+			t.Fatalf("claim %d: %d %s", i+1, response.Code, response.Body)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const serviceName = "event-management-api"
+
+// tracer traces work in handlers that isn't a query, like hashing passwords.
+var tracer = otel.Tracer("github.com/anshbadoni30/event-management-app/cmd/api")
+
+// setupTracing installs the global tracer provider and W3C trace context
+// propagation. The exporter is "otlp", which sends spans over OTLP/HTTP to
+// the endpoint in the standard OTEL_EXPORTER_OTLP_* variables, "stdout",
+// which prints them for local use, or "none". The returned function flushes
+// pending spans.
+func setupTracing(exporter string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var spanExporter sdktrace.SpanExporter
+	var err error
+	switch exporter {
+	case "none", "":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		spanExporter, err = otlptracehttp.New(context.Background())
+	case "stdout":
+		spanExporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(spanExporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// TraceIDMiddleware returns the trace ID of each request in the X-Trace-Id
+// header, so a slow or failed request can be looked up in the traces. It
+// must run after the middleware that starts the request's span.
+func (app *application) TraceIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if spanContext := trace.SpanContextFromContext(c.Request.Context()); spanContext.HasTraceID() {
+			c.Header("X-Trace-Id", spanContext.TraceID().String())
+		}
+		c.Next()
+	}
+}
+
+// modelsFor returns the models bound to the request's context, so their
+// queries show up in the request's trace.
+func (app *application) modelsFor(c *gin.Context) *database.Models {
+	return app.models.WithContext(c.Request.Context())
+}
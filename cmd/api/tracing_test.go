@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanRecorder records the spans of every test. Tracers created before the
+// global provider is set only follow the first provider set, so it is set
+// once, before any test runs.
+var spanRecorder = tracetest.NewSpanRecorder()
+
+func init() {
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spanRecorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+}
+
+// traceSpans returns the ended spans of a trace.
+func traceSpans(traceId string) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range spanRecorder.Ended() {
+		if span.SpanContext().TraceID().String() == traceId {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+func TestTracing(t *testing.T) {
+	app := newTestApp(t)
+	_, token := app.createUser(t, "alice")
+	event := app.createEvent(t, token)
+	path := fmt.Sprintf("/api/v1/events/%d", event.Id)
+
+	tests := []struct {
+		name    string
+		headers []string
+		// parent is the trace the request continues, if any.
+		parent      string
+		wantQueries []string
+	}{
+		{"anonymous", nil, "", []string{"EventModel.Get"}},
+		{"logged in", bearer(token), "", []string{"UserModel.Get", "EventModel.Get"}},
+		{"continues the caller's trace", []string{"Traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"}, "4bf92f3577b34da6a3ce929d0e0e4736", []string{"EventModel.Get"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := app.do(t, http.MethodGet, path, nil, tt.headers...)
+			//events are answered with 201, like they always were
+			if response.Code != http.StatusCreated {
+				t.Fatalf("get event: %d %s", response.Code, response.Body)
+			}
+			traceId := response.Header().Get("X-Trace-Id")
+			if traceId == "" {
+				t.Fatal("no X-Trace-Id header")
+			}
+			if tt.parent != "" && traceId != tt.parent {
+				t.Errorf("trace %s, want the caller's trace %s", traceId, tt.parent)
+			}
+
+			spans := traceSpans(traceId)
+			i := slices.IndexFunc(spans, func(span sdktrace.ReadOnlySpan) bool { return span.SpanKind() == trace.SpanKindServer })
+			if i < 0 {
+				t.Fatalf("no request span among %d spans", len(spans))
+			}
+			request := spans[i]
+			if request.Name() != "/api/v1/events/:id" {
+				t.Errorf("request span = %q, want the route", request.Name())
+			}
+			var queries []string
+			for _, span := range spans {
+				if span.SpanKind() != trace.SpanKindClient {
+					continue
+				}
+				queries = append(queries, span.Name())
+				if span.Parent().SpanID() != request.SpanContext().SpanID() {
+					t.Errorf("%s is not a child of the request span", span.Name())
+				}
+				if !slices.Contains(span.Attributes(), attribute.String("db.system", "sqlite")) {
+					t.Errorf("%s has attributes %v, want db.system", span.Name(), span.Attributes())
+				}
+			}
+			for _, query := range tt.wantQueries {
+				if !slices.Contains(queries, query) {
+					t.Errorf("query spans = %q, want %s among them", queries, query)
+				}
+			}
+		})
+	}
+}
+
+func TestSetupTracingExporters(t *testing.T) {
+	tests := []struct {
+		exporter string
+		wantErr  bool
+	}{
+		{"", false},
+		{"none", false},
+		{"jaeger", true},
+	}
+	for _, tt := range tests {
+		shutdown, err := setupTracing(tt.exporter)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("setupTracing(%q) error = %v, want error %v", tt.exporter, err, tt.wantErr)
+			continue
+		}
+		if err == nil {
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutting down %q tracing: %v", tt.exporter, err)
+			}
+		}
+	}
+}
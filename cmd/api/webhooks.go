@@ -0,0 +1,198 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/anshbadoni30/event-management-app/internal/webhooks"
+	"github.com/gin-gonic/gin"
+)
+
+type webhookRequest struct {
+	Url        string   `json:"url" binding:"required,url"`
+	EventId    *int     `json:"eventId"`
+	EventTypes []string `json:"eventTypes"`
+	Secret     string   `json:"secret" binding:"omitempty,min=16"`
+}
+
+type webhookResponse struct {
+	*database.Webhook
+	Secret string `json:"secret"`
+}
+
+// emitWebhook queues webhook deliveries for an event. Failures are logged
+// rather than returned so they never fail the request that triggered them.
+func (app *application) emitWebhook(eventType string, event *database.Event, data any) {
+	if err := app.webhooks.Emit(eventType, event, data); err != nil {
+		log.Printf("failed to queue %s webhook for event %d: %v", eventType, event.Id, err)
+	}
+}
+
+// CreateWebhook registers a webhook endpoint
+//
+//	@Summary		Registers a webhook endpoint
+//	@Description	Registers a webhook for all of the user's events, or for a single event when eventId is set. The signing secret is only returned once. URLs resolving to loopback, private or link-local addresses are refused.
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			webhook	body		webhookRequest	true	"Webhook"
+//	@Success		201		{object}	webhookResponse
+//	@Router			/api/v1/webhooks [post]
+//	@Security		BearerAuth
+func (app *application) createWebhook(c *gin.Context) {
+	var request webhookRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := app.webhooks.CheckURL(c.Request.Context(), request.Url); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	for _, eventType := range request.EventTypes {
+		if !slices.Contains(webhooks.EventTypes, eventType) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown event type " + eventType})
+			return
+		}
+	}
+
+	user := app.GetUserFromContext(c)
+	if request.EventId != nil {
+		event, err := app.modelsFor(c).Events.Get(*request.EventId)
+		if event == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Event not found"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve event"})
+			return
+		}
+		if event.OwnerId != user.Id {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not authorized to add webhooks to this event"})
+			return
+		}
+	}
+
+	secret := request.Secret
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+		secret = hex.EncodeToString(buf)
+	}
+
+	webhook := database.Webhook{
+		OwnerId:    user.Id,
+		EventId:    request.EventId,
+		Url:        request.Url,
+		Secret:     secret,
+		EventTypes: request.EventTypes,
+		Active:     true,
+	}
+	if webhook.EventTypes == nil {
+		webhook.EventTypes = []string{}
+	}
+	if err := app.modelsFor(c).Webhooks.Insert(&webhook); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create webhook"})
+		return
+	}
+	c.JSON(http.StatusCreated, webhookResponse{Webhook: &webhook, Secret: secret})
+}
+
+// GetWebhooks returns the user's webhooks
+//
+//	@Summary		Returns the user's webhooks
+//	@Description	Returns the user's webhooks
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Success		200	{object}	[]database.Webhook
+//	@Router			/api/v1/webhooks [get]
+//	@Security		BearerAuth
+func (app *application) getWebhooks(c *gin.Context) {
+	user := app.GetUserFromContext(c)
+	hooks, err := app.modelsFor(c).Webhooks.GetByOwner(user.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhooks"})
+		return
+	}
+	c.JSON(http.StatusOK, hooks)
+}
+
+// DeleteWebhook deletes a webhook
+//
+//	@Summary		Deletes a webhook
+//	@Description	Deletes a webhook and its delivery log
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path	int	true	"Webhook ID"
+//	@Success		204
+//	@Router			/api/v1/webhooks/{id} [delete]
+//	@Security		BearerAuth
+func (app *application) deleteWebhook(c *gin.Context) {
+	webhook, ok := app.ownedWebhook(c)
+	if !ok {
+		return
+	}
+	if err := app.modelsFor(c).Webhooks.Delete(webhook.Id); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete webhook"})
+		return
+	}
+	c.JSON(http.StatusNoContent, gin.H{"success": "OK"})
+}
+
+// GetWebhookDeliveries returns the delivery log of a webhook
+//
+//	@Summary		Returns the delivery log of a webhook
+//	@Description	Returns the 100 most recent deliveries of a webhook
+//	@Tags			webhooks
+//	@Accept			json
+//	@Produce		json
+//	@Param			id	path		int	true	"Webhook ID"
+//	@Success		200	{object}	[]database.WebhookDelivery
+//	@Router			/api/v1/webhooks/{id}/deliveries [get]
+//	@Security		BearerAuth
+func (app *application) getWebhookDeliveries(c *gin.Context) {
+	webhook, ok := app.ownedWebhook(c)
+	if !ok {
+		return
+	}
+	deliveries, err := app.modelsFor(c).Webhooks.GetDeliveries(webhook.Id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve deliveries"})
+		return
+	}
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// ownedWebhook loads the webhook named by the id parameter and checks that it
+// belongs to the current user, writing the error response if it doesn't.
+func (app *application) ownedWebhook(c *gin.Context) (*database.Webhook, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid webhook ID"})
+		return nil, false
+	}
+
+	webhook, err := app.modelsFor(c).Webhooks.Get(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve webhook"})
+		return nil, false
+	}
+	user := app.GetUserFromContext(c)
+	if webhook == nil || webhook.OwnerId != user.Id {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Webhook not found"})
+		return nil, false
+	}
+	return webhook, true
+}
@@ -2,20 +2,79 @@ package main
 
 import (
 	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
 
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/database"
 	"github.com/golang-migrate/migrate"
 	"github.com/golang-migrate/migrate/database/sqlite3"
+	"github.com/golang-migrate/migrate/source"
 	"github.com/golang-migrate/migrate/source/file"
 )
 
+const usage = `Usage: migrate [flags] <command>
+
+Commands:
+  status        list the migrations and whether they were applied
+  version       print the version of the database
+  up [N]        apply all pending migrations, or the next N
+  down [N|all]  roll back the last migration, the last N, or all of them
+  goto V        migrate up or down to version V
+  force V       set the version to V without running anything, to recover
+                from a dirty state once it was fixed by hand
+  create NAME   create empty up and down files for a new migration
+
+Flags:
+`
+
+// defaultDir is where create writes migrations unless --path is given.
+const defaultDir = "cmd/migrate/migrations"
+
+// migrationName is what create accepts as the name of a migration.
+var migrationName = regexp.MustCompile(`^[a-z0-9_]+$`)
+
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("Please provide a migration direction: up or down")
+	log.SetFlags(0)
+	dsn := flag.String("dsn", envOr("DB_PATH", "./data.db"), "SQLite database to migrate ($DB_PATH)")
+	path := flag.String("path", envOr("MIGRATIONS_DIR", ""), "directory of the migrations, instead of the ones built in; create writes to cmd/migrate/migrations by default ($MIGRATIONS_DIR)")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
 	}
-	direction := os.Args[1]
-	db, err := sql.Open("sqlite3", "./data.db")
+	command, args := flag.Arg(0), flag.Args()[1:]
+
+	//create only writes files, so it doesn't need the database
+	if command == "create" {
+		if len(args) != 1 {
+			log.Fatal("create needs the name of the migration")
+		}
+		dir := *path
+		if dir == "" {
+			dir = defaultDir
+		}
+		if err := create(dir, args[0], time.Now()); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	db, err := sql.Open("sqlite3", *dsn)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -27,26 +86,179 @@ func main() {
 		log.Fatal(err)
 	}
 
-	fSrc, err := (&file.File{}).Open("cmd/migrate/migrations")
+	//the built-in migrations include those of the search implementation
+	//this build has, see the migrations package
+	var fSrc source.Driver
+	if *path == "" {
+		fSrc, err = database.MigrationSource(migrations.FS)
+	} else {
+		fSrc, err = (&file.File{}).Open(*path)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	m, err := migrate.NewWithInstance("file", fSrc, "sqlite3", instance)
+	m, err := migrate.NewWithInstance("source", fSrc, "sqlite3", instance)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	switch direction {
+	switch command {
+	case "status":
+		err = status(m, fSrc)
+	case "version":
+		err = printVersion(m)
 	case "up":
-		if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
+		var n int
+		if n, err = optionalCount(args, 0); err == nil {
+			if n == 0 {
+				err = m.Up()
+			} else {
+				err = m.Steps(n)
+			}
 		}
 	case "down":
-		if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
+		if len(args) == 1 && args[0] == "all" {
+			err = m.Down()
+			break
+		}
+		var n int
+		if n, err = optionalCount(args, 1); err == nil {
+			err = m.Steps(-n)
+		}
+	case "goto":
+		var version int
+		if version, err = versionArg(args); err == nil {
+			if version < 1 {
+				log.Fatal("Use down all to roll back every migration")
+			}
+			err = m.Migrate(uint(version))
+		}
+	case "force":
+		//-1 marks the database as having no migration applied
+		var version int
+		if version, err = versionArg(args); err == nil {
+			err = m.Force(version)
+		}
+	default:
+		log.Fatalf("Unknown command %q, run with -h for help", command)
+	}
+	if errors.Is(err, migrate.ErrNoChange) {
+		log.Print("No change")
+		return
+	}
+	var dirty migrate.ErrDirty
+	if errors.As(err, &dirty) {
+		log.Fatalf("The database is dirty at version %d: a migration failed halfway. Fix the schema by hand, then run force %d if it was fully applied or force with the previous version if it wasn't.", dirty.Version, dirty.Version)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	if command != "status" && command != "version" {
+		printVersion(m)
+	}
+}
+
+// status prints every migration and whether it was applied.
+func status(m *migrate.Migrate, src source.Driver) error {
+	current, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return err
+	}
+	applied := err == nil
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	version, err := src.First()
+	for err == nil {
+		name := ""
+		if r, identifier, err := src.ReadUp(version); err == nil {
+			r.Close()
+			name = identifier
+		}
+		state := "pending"
+		switch {
+		case applied && version == current && dirty:
+			state = "dirty"
+		case applied && version <= current:
+			state = "applied"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\n", version, name, state)
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return w.Flush()
+}
+
+func printVersion(m *migrate.Migrate) error {
+	version, dirty, err := m.Version()
+	switch {
+	case err == migrate.ErrNilVersion:
+		fmt.Println("No migration applied")
+	case err != nil:
+		return err
+	case dirty:
+		fmt.Printf("Version %d (dirty)\n", version)
+	default:
+		fmt.Printf("Version %d\n", version)
+	}
+	return nil
+}
+
+// create writes empty up and down files for a migration named after the
+// time, so migrations from different branches don't share a version.
+func create(dir, name string, now time.Time) error {
+	name = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")
+	if !migrationName.MatchString(name) {
+		return fmt.Errorf("invalid migration name %q: use letters, digits and underscores", name)
+	}
+	base := filepath.Join(dir, now.UTC().Format("20060102150405")+"_"+name)
+	for _, direction := range []string{"up", "down"} {
+		path := base + "." + direction + ".sql"
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Println("Created", path)
+	}
+	return nil
+}
+
+// optionalCount parses the optional number of migrations of up and down.
+func optionalCount(args []string, fallback int) (int, error) {
+	switch len(args) {
+	case 0:
+		return fallback, nil
+	case 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("invalid number of migrations %q", args[0])
 		}
+		return n, nil
 	default:
-		log.Fatal("Invalid direction. Use 'up' or 'down'")
+		return 0, errors.New("too many arguments")
+	}
+}
+
+func versionArg(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, errors.New("a version is required")
+	}
+	version, err := strconv.Atoi(args[0])
+	if err != nil || version < -1 {
+		return 0, fmt.Errorf("invalid version %q", args[0])
+	}
+	return version, nil
+}
+
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
 	}
+	return fallback
 }
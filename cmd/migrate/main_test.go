@@ -0,0 +1,170 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+func TestMain(m *testing.M) {
+	//the tests run commands by running the test binary as migrate, since
+	//failing commands exit
+	if os.Getenv("MIGRATE_TEST_MAIN") == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMigrate runs migrate with args against the database at dsn and returns
+// its output and whether it succeeded.
+func runMigrate(t *testing.T, dsn string, args ...string) (string, bool) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "MIGRATE_TEST_MAIN=1", "DB_PATH="+dsn, "MIGRATIONS_DIR=")
+	output, err := cmd.CombinedOutput()
+	var exit *exec.ExitError
+	if err != nil && !errors.As(err, &exit) {
+		t.Fatal(err)
+	}
+	return string(output), err == nil
+}
+
+// columns collapses the padding of tables in output to single spaces.
+func columns(output string) string {
+	lines := strings.SplitAfter(output, "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = strings.Join(strings.Fields(line), " ") + "\n"
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+func TestCommands(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	latest, err := database.LatestMigration(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	//the commands run in order, on the same database
+	tests := []struct {
+		args       []string
+		wantOK     bool
+		wantOutput string
+	}{
+		{[]string{"version"}, true, "No migration applied\n"},
+		{[]string{"up", "2"}, true, "Version 2\n"},
+		{[]string{"status"}, true, "VERSION NAME STATUS\n1 create_user_table applied\n2 create_events_table applied\n3 create_attendees_table pending\n"},
+		{[]string{"down"}, true, "Version 1\n"},
+		{[]string{"up"}, true, fmt.Sprintf("Version %d\n", latest)},
+		{[]string{"up"}, true, "No change\n"},
+		{[]string{"goto", "3"}, true, "Version 3\n"},
+		{[]string{"down", "2"}, true, "Version 1\n"},
+		{[]string{"down", "all"}, true, "No migration applied\n"},
+		{[]string{"up", "0"}, false, "invalid number of migrations \"0\"\n"},
+		{[]string{"down", "1", "2"}, false, "too many arguments\n"},
+		{[]string{"goto", "0"}, false, "Use down all to roll back every migration\n"},
+		{[]string{"force"}, false, "a version is required\n"},
+		{[]string{"rollback"}, false, "Unknown command \"rollback\", run with -h for help\n"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			output, ok := runMigrate(t, dsn, tt.args...)
+			if ok != tt.wantOK {
+				t.Fatalf("succeeded = %v, want %v: %s", ok, tt.wantOK, output)
+			}
+			//status lists every migration, only compare its first rows
+			if tt.args[0] == "status" {
+				output = columns(strings.Join(strings.SplitAfter(output, "\n")[:4], ""))
+			}
+			if output != tt.wantOutput {
+				t.Errorf("output = %q, want %q", output, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestDirtyDatabase(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "test.db")
+	if output, ok := runMigrate(t, dsn, "up", "2"); !ok {
+		t.Fatal(output)
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	//as if migration 2 failed halfway
+	if _, err := db.Exec("update schema_migrations set dirty = 1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args       []string
+		wantOK     bool
+		wantOutput string
+	}{
+		{[]string{"version"}, true, "Version 2 (dirty)"},
+		{[]string{"status"}, true, "2 create_events_table dirty\n"},
+		{[]string{"up"}, false, "The database is dirty at version 2"},
+		{[]string{"force", "2"}, true, "Version 2\n"},
+		{[]string{"up", "1"}, true, "Version 3\n"},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			output, ok := runMigrate(t, dsn, tt.args...)
+			output = columns(output)
+			if ok != tt.wantOK || !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("output = %q, succeeded = %v, want %q, succeeded = %v", output, ok, tt.wantOutput, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 10, 19, 14, 16, 12, 0, time.FixedZone("CEST", 2*3600))
+
+	tests := []struct {
+		name     string
+		wantBase string
+		wantErr  bool
+	}{
+		{"add_tags", "20261019121612_add_tags", false},
+		{"  Add Event Tags ", "20261019121612_add_event_tags", false},
+		{"add_tags", "", true},
+		{"drop-users!", "", true},
+		{"", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := create(dir, tt.name, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("create(%q) error = %v, want error %v", tt.name, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, direction := range []string{"up", "down"} {
+				info, err := os.Stat(filepath.Join(dir, tt.wantBase+"."+direction+".sql"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if info.Size() != 0 {
+					t.Errorf("%s migration has %d bytes, want an empty file", direction, info.Size())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+create table if not EXISTS webhooks (
+ id integer primary key AUTOINCREMENT,
+ owner_id integer not null,
+ event_id integer,
+ url text not null,
+ secret text not null,
+ event_types text not null default '',
+ active boolean not null default 1,
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ foreign key (owner_id) references users(id) on delete cascade
+);
+
+create table if not EXISTS webhook_deliveries (
+ id integer primary key AUTOINCREMENT,
+ webhook_id integer not null,
+ event_type text not null,
+ payload text not null,
+ status text not null default 'pending',
+ attempts integer not null default 0,
+ next_attempt_at datetime not null,
+ last_error text not null default '',
+ response_status integer not null default 0,
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ delivered_at datetime,
+ foreign key (webhook_id) references webhooks(id) on delete cascade
+);
@@ -0,0 +1 @@
+alter table attendees drop column checked_in_at;
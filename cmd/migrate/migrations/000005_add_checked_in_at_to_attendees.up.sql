@@ -0,0 +1 @@
+alter table attendees add column checked_in_at datetime;
@@ -0,0 +1,4 @@
+drop trigger if exists events_fts_update;
+drop trigger if exists events_fts_delete;
+drop trigger if exists events_fts_insert;
+drop table if exists events_fts;
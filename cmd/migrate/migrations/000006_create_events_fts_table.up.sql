@@ -0,0 +1,23 @@
+create virtual table if not EXISTS events_fts using fts5(
+ name,
+ description,
+ location,
+ content='events',
+ content_rowid='id',
+ tokenize='unicode61 remove_diacritics 2'
+);
+
+insert into events_fts(events_fts) values('rebuild');
+
+create trigger if not EXISTS events_fts_insert after insert on events begin
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
+
+create trigger if not EXISTS events_fts_delete after delete on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+end;
+
+create trigger if not EXISTS events_fts_update after update of name, description, location on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
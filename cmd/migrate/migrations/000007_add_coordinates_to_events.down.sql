@@ -0,0 +1,3 @@
+drop index if exists idx_events_location_coordinates;
+alter table events drop column longitude;
+alter table events drop column latitude;
@@ -0,0 +1,3 @@
+alter table events add column latitude real;
+alter table events add column longitude real;
+create index if not EXISTS idx_events_location_coordinates on events (latitude, longitude);
@@ -0,0 +1,2 @@
+drop table if exists event_tags;
+drop table if exists tags;
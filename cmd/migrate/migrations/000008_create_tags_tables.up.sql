@@ -0,0 +1,14 @@
+create table if not EXISTS tags (
+ id integer primary key AUTOINCREMENT,
+ slug text not null UNIQUE
+);
+
+create table if not EXISTS event_tags (
+ event_id integer not null,
+ tag_id integer not null,
+ primary key (event_id, tag_id),
+ foreign key (event_id) references events(id) on delete cascade,
+ foreign key (tag_id) references tags(id) on delete cascade
+);
+
+create index if not EXISTS idx_event_tags_tag_id on event_tags (tag_id);
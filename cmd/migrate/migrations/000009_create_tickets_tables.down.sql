@@ -0,0 +1,2 @@
+drop table if exists orders;
+drop table if exists ticket_types;
@@ -0,0 +1,31 @@
+create table if not EXISTS ticket_types (
+ id integer primary key AUTOINCREMENT,
+ event_id integer not null,
+ name text not null,
+ description text not null default '',
+ price_cents integer not null default 0 check (price_cents >= 0),
+ currency text not null default 'EUR',
+ quota integer not null check (quota >= 0),
+ sales_start datetime,
+ sales_end datetime,
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ foreign key (event_id) references events(id) on delete cascade
+);
+
+create index if not EXISTS idx_ticket_types_event_id on ticket_types (event_id);
+
+create table if not EXISTS orders (
+ id integer primary key AUTOINCREMENT,
+ ticket_type_id integer not null,
+ event_id integer not null,
+ user_id integer not null,
+ quantity integer not null check (quantity > 0),
+ status text not null default 'confirmed',
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ foreign key (ticket_type_id) references ticket_types(id) on delete cascade,
+ foreign key (event_id) references events(id) on delete cascade,
+ foreign key (user_id) references users(id) on delete cascade
+);
+
+create index if not EXISTS idx_orders_ticket_type_id on orders (ticket_type_id);
+create index if not EXISTS idx_orders_user_id on orders (user_id);
@@ -0,0 +1,4 @@
+drop index if exists idx_orders_payment_intent_id;
+alter table orders drop column payment_intent_id;
+alter table orders drop column currency;
+alter table orders drop column amount_cents;
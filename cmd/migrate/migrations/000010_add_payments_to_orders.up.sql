@@ -0,0 +1,4 @@
+alter table orders add column amount_cents integer not null default 0;
+alter table orders add column currency text not null default '';
+alter table orders add column payment_intent_id text;
+create unique index if not EXISTS idx_orders_payment_intent_id on orders (payment_intent_id);
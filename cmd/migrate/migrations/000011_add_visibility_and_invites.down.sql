@@ -0,0 +1,2 @@
+drop table if exists invites;
+alter table events drop column visibility;
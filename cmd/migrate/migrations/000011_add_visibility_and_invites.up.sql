@@ -0,0 +1,18 @@
+alter table events add column visibility text not null default 'public' check (visibility in ('public', 'unlisted', 'private'));
+
+create table if not EXISTS invites (
+ id integer primary key AUTOINCREMENT,
+ event_id integer not null,
+ token_hash text not null UNIQUE,
+ email text,
+ max_uses integer,
+ uses integer not null default 0,
+ expires_at datetime,
+ revoked boolean not null default 0,
+ created_by integer not null,
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ foreign key (event_id) references events(id) on delete cascade,
+ foreign key (created_by) references users(id) on delete cascade
+);
+
+create index if not EXISTS idx_invites_event_id on invites (event_id);
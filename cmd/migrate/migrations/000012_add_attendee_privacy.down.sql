@@ -0,0 +1,2 @@
+alter table users drop column hide_from_attendee_lists;
+alter table events drop column attendee_visibility;
@@ -0,0 +1,3 @@
+alter table events add column attendee_visibility text not null default 'public' check (attendee_visibility in ('public', 'attendees', 'organizers'));
+
+alter table users add column hide_from_attendee_lists boolean not null default 0;
@@ -0,0 +1,2 @@
+alter table users drop column email_reminders;
+drop table if exists jobs;
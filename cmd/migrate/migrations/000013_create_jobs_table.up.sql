@@ -0,0 +1,16 @@
+create table if not EXISTS jobs (
+ id integer primary key AUTOINCREMENT,
+ type text not null,
+ payload text not null,
+ dedupe_key text UNIQUE,
+ status text not null default 'pending' check (status in ('pending', 'done', 'failed', 'cancelled')),
+ attempts integer not null default 0,
+ run_at datetime not null,
+ last_error text not null default '',
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ updated_at datetime not null default CURRENT_TIMESTAMP
+);
+
+create index if not EXISTS idx_jobs_status_run_at on jobs (status, run_at);
+
+alter table users add column email_reminders boolean not null default 1;
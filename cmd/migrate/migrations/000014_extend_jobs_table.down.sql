@@ -0,0 +1,20 @@
+create table if not EXISTS jobs_old (
+ id integer primary key AUTOINCREMENT,
+ type text not null,
+ payload text not null,
+ dedupe_key text UNIQUE,
+ status text not null default 'pending' check (status in ('pending', 'done', 'failed', 'cancelled')),
+ attempts integer not null default 0,
+ run_at datetime not null,
+ last_error text not null default '',
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ updated_at datetime not null default CURRENT_TIMESTAMP
+);
+
+insert into jobs_old (id, type, payload, dedupe_key, status, attempts, run_at, last_error, created_at, updated_at)
+ select id, type, payload, dedupe_key, case status when 'dead' then 'failed' when 'running' then 'pending' else status end, attempts, run_at, last_error, created_at, updated_at from jobs;
+
+drop table jobs;
+alter table jobs_old rename to jobs;
+
+create index if not EXISTS idx_jobs_status_run_at on jobs (status, run_at);
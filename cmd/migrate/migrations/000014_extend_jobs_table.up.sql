@@ -0,0 +1,25 @@
+create table if not EXISTS jobs_new (
+ id integer primary key AUTOINCREMENT,
+ type text not null,
+ payload text not null,
+ dedupe_key text UNIQUE,
+ status text not null default 'pending' check (status in ('pending', 'running', 'done', 'dead', 'cancelled')),
+ priority integer not null default 0,
+ attempts integer not null default 0,
+ max_attempts integer not null default 5 check (max_attempts > 0),
+ run_at datetime not null,
+ locked_by text,
+ locked_until datetime,
+ last_error text not null default '',
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ updated_at datetime not null default CURRENT_TIMESTAMP
+);
+
+insert into jobs_new (id, type, payload, dedupe_key, status, attempts, run_at, last_error, created_at, updated_at)
+ select id, type, payload, dedupe_key, case status when 'failed' then 'dead' else status end, attempts, run_at, last_error, created_at, updated_at from jobs;
+
+drop table jobs;
+alter table jobs_new rename to jobs;
+
+create index if not EXISTS idx_jobs_status_run_at on jobs (status, priority, run_at);
+create index if not EXISTS idx_jobs_locked_until on jobs (status, locked_until);
@@ -0,0 +1 @@
+alter table attendees drop column registered_at;
@@ -0,0 +1 @@
+alter table attendees add column registered_at datetime;
@@ -0,0 +1 @@
+alter table events drop column capacity;
@@ -0,0 +1 @@
+alter table events add column capacity integer check (capacity > 0);
@@ -0,0 +1,10 @@
+create table if not EXISTS attendee_cancellations (
+ id integer primary key AUTOINCREMENT,
+ event_id integer not null,
+ user_id integer not null,
+ cancelled_at datetime not null,
+ foreign key (event_id) references events(id) on delete cascade,
+ foreign key (user_id) references users(id) on delete cascade
+);
+
+create index if not EXISTS idx_attendee_cancellations_event_id on attendee_cancellations (event_id, cancelled_at);
@@ -0,0 +1,51 @@
+create table if not EXISTS events_old (
+ id integer primary key AUTOINCREMENT,
+ owner_id integer not null,
+ name text not null,
+ description text not null,
+ date datetime not null,
+ location text not null,
+ latitude real,
+ longitude real,
+ visibility text not null default 'public' check (visibility in ('public', 'unlisted', 'private')),
+ attendee_visibility text not null default 'public' check (attendee_visibility in ('public', 'attendees', 'organizers')),
+ capacity integer check (capacity > 0),
+ foreign key (owner_id) references users(id) on delete cascade
+);
+
+insert into events_old (id, owner_id, name, description, date, location, latitude, longitude, visibility, attendee_visibility, capacity)
+ select id, owner_id, name, description, date, location, latitude, longitude, visibility, attendee_visibility, capacity from events;
+update sqlite_sequence set seq = (select seq from sqlite_sequence where name = 'events') where name = 'events_old';
+drop table events;
+alter table events_old rename to events;
+
+create index if not EXISTS idx_events_location_coordinates on events (latitude, longitude);
+
+create trigger if not EXISTS events_fts_insert after insert on events begin
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
+
+create trigger if not EXISTS events_fts_delete after delete on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+end;
+
+create trigger if not EXISTS events_fts_update after update of name, description, location on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
+
+create table if not EXISTS attendees_old (
+ id integer primary key AUTOINCREMENT,
+ user_id integer not null,
+ event_id integer not null,
+ checked_in_at datetime,
+ registered_at datetime,
+ foreign key (user_id) references users(id) on delete cascade,
+ foreign key (event_id) references events(id) on delete cascade
+);
+
+insert into attendees_old (id, user_id, event_id, checked_in_at, registered_at)
+ select id, user_id, event_id, checked_in_at, registered_at from attendees;
+update sqlite_sequence set seq = (select seq from sqlite_sequence where name = 'attendees') where name = 'attendees_old';
+drop table attendees;
+alter table attendees_old rename to attendees;
@@ -0,0 +1,68 @@
+-- keep one row per attendee and event, with the earliest registration and check-in
+update attendees set
+ registered_at = (select min(a.registered_at) from attendees a where a.user_id = attendees.user_id and a.event_id = attendees.event_id),
+ checked_in_at = (select min(a.checked_in_at) from attendees a where a.user_id = attendees.user_id and a.event_id = attendees.event_id)
+where id in (select min(id) from attendees group by user_id, event_id having count(*) > 1);
+
+delete from attendees where id not in (select min(id) from attendees group by user_id, event_id);
+
+create table if not EXISTS attendees_new (
+ id integer primary key AUTOINCREMENT,
+ user_id integer not null,
+ event_id integer not null,
+ checked_in_at datetime,
+ registered_at datetime,
+ unique (user_id, event_id),
+ foreign key (user_id) references users(id) on delete cascade,
+ foreign key (event_id) references events(id) on delete cascade
+);
+
+insert into attendees_new (id, user_id, event_id, checked_in_at, registered_at)
+ select id, user_id, event_id, checked_in_at, registered_at from attendees;
+update sqlite_sequence set seq = (select seq from sqlite_sequence where name = 'attendees') where name = 'attendees_new';
+drop table attendees;
+alter table attendees_new rename to attendees;
+
+create index if not EXISTS idx_attendees_event_id on attendees (event_id);
+
+-- events get range checks on coordinates, which only come in pairs, so half a pair is dropped
+create table if not EXISTS events_new (
+ id integer primary key AUTOINCREMENT,
+ owner_id integer not null,
+ name text not null,
+ description text not null,
+ date datetime not null,
+ location text not null,
+ latitude real check (latitude between -90 and 90),
+ longitude real check (longitude between -180 and 180),
+ visibility text not null default 'public' check (visibility in ('public', 'unlisted', 'private')),
+ attendee_visibility text not null default 'public' check (attendee_visibility in ('public', 'attendees', 'organizers')),
+ capacity integer check (capacity > 0),
+ check ((latitude is null) = (longitude is null)),
+ foreign key (owner_id) references users(id) on delete cascade
+);
+
+insert into events_new (id, owner_id, name, description, date, location, latitude, longitude, visibility, attendee_visibility, capacity)
+ select id, owner_id, name, description, date, location,
+  case when longitude is null then null else latitude end,
+  case when latitude is null then null else longitude end,
+  visibility, attendee_visibility, capacity from events;
+update sqlite_sequence set seq = (select seq from sqlite_sequence where name = 'events') where name = 'events_new';
+drop table events;
+alter table events_new rename to events;
+
+create index if not EXISTS idx_events_owner_id on events (owner_id);
+create index if not EXISTS idx_events_location_coordinates on events (latitude, longitude);
+
+create trigger if not EXISTS events_fts_insert after insert on events begin
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
+
+create trigger if not EXISTS events_fts_delete after delete on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+end;
+
+create trigger if not EXISTS events_fts_update after update of name, description, location on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
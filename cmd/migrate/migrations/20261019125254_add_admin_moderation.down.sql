@@ -0,0 +1,4 @@
+drop table if EXISTS admin_actions;
+alter table users drop column password_reset_required;
+alter table users drop column disabled_at;
+alter table users drop column is_admin;
@@ -0,0 +1,17 @@
+alter table users add column is_admin boolean not null default 0;
+alter table users add column disabled_at datetime;
+alter table users add column password_reset_required boolean not null default 0;
+
+create table if not EXISTS admin_actions (
+ id integer primary key AUTOINCREMENT,
+ admin_id integer,
+ action text not null,
+ target_type text not null,
+ target_id integer,
+ details text not null default '{}',
+ created_at datetime not null,
+ foreign key (admin_id) references users(id) on delete set null
+);
+
+create index if not EXISTS idx_admin_actions_created_at on admin_actions (created_at);
+create index if not EXISTS idx_admin_actions_target on admin_actions (target_type, target_id);
@@ -0,0 +1,15 @@
+create table if not EXISTS api_keys (
+ id integer primary key AUTOINCREMENT,
+ user_id integer not null,
+ name text not null,
+ prefix text not null,
+ key_hash text not null unique,
+ scopes text not null default '',
+ created_at datetime not null,
+ expires_at datetime,
+ last_used_at datetime,
+ revoked_at datetime,
+ foreign key (user_id) references users(id) on delete cascade
+);
+
+create index if not EXISTS idx_api_keys_user_id on api_keys (user_id);
@@ -0,0 +1,2 @@
+drop table if EXISTS oidc_logins;
+drop table if EXISTS user_identities;
@@ -0,0 +1,20 @@
+create table if not EXISTS user_identities (
+ id integer primary key AUTOINCREMENT,
+ user_id integer not null,
+ provider text not null,
+ subject text not null,
+ email text,
+ created_at datetime not null,
+ last_login_at datetime,
+ unique (provider, subject),
+ foreign key (user_id) references users(id) on delete cascade
+);
+
+create index if not EXISTS idx_user_identities_user_id on user_identities (user_id);
+
+create table if not EXISTS oidc_logins (
+ state text primary key,
+ nonce text not null,
+ verifier text not null,
+ created_at datetime not null
+);
@@ -0,0 +1 @@
+alter table orders drop column refund_requested_at;
@@ -0,0 +1 @@
+alter table orders add column refund_requested_at datetime;
@@ -0,0 +1,18 @@
+create table if not EXISTS webhooks_old (
+ id integer primary key AUTOINCREMENT,
+ owner_id integer not null,
+ event_id integer,
+ url text not null,
+ secret text not null,
+ event_types text not null default '',
+ active boolean not null default 1,
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ foreign key (owner_id) references users(id) on delete cascade
+);
+
+insert into webhooks_old (id, owner_id, event_id, url, secret, event_types, active, created_at)
+ select id, owner_id, event_id, url, secret, event_types, active, created_at from webhooks;
+update sqlite_sequence set seq = (select seq from sqlite_sequence where name = 'webhooks') where name = 'webhooks_old';
+drop index if exists idx_webhooks_event_id;
+drop table webhooks;
+alter table webhooks_old rename to webhooks;
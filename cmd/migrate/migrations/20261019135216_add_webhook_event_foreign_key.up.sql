@@ -0,0 +1,23 @@
+-- webhooks of a single event go with the event
+delete from webhooks where event_id is not null and event_id not in (select id from events);
+
+create table if not EXISTS webhooks_new (
+ id integer primary key AUTOINCREMENT,
+ owner_id integer not null,
+ event_id integer,
+ url text not null,
+ secret text not null,
+ event_types text not null default '',
+ active boolean not null default 1,
+ created_at datetime not null default CURRENT_TIMESTAMP,
+ foreign key (owner_id) references users(id) on delete cascade,
+ foreign key (event_id) references events(id) on delete cascade
+);
+
+insert into webhooks_new (id, owner_id, event_id, url, secret, event_types, active, created_at)
+ select id, owner_id, event_id, url, secret, event_types, active, created_at from webhooks;
+update sqlite_sequence set seq = (select seq from sqlite_sequence where name = 'webhooks') where name = 'webhooks_new';
+drop table webhooks;
+alter table webhooks_new rename to webhooks;
+
+create index if not EXISTS idx_webhooks_event_id on webhooks (event_id);
@@ -0,0 +1 @@
+alter table users drop column has_password;
@@ -0,0 +1 @@
+alter table users add column has_password boolean not null default 1;
@@ -0,0 +1,5 @@
+drop trigger if exists events_fts_update;
+drop trigger if exists events_fts_delete;
+drop trigger if exists events_fts_insert;
+drop trigger if exists events_fts_ignore;
+drop table if exists events_fts;
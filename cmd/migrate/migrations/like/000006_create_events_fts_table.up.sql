@@ -0,0 +1,28 @@
+-- without FTS5, search runs LIKE queries on the events table. events_fts is
+-- a stand-in that ignores every insert, so the triggers keeping the FTS5
+-- table up to date, here and in later migrations, work in both builds.
+create table if not EXISTS events_fts (
+ events_fts text,
+ name text,
+ description text,
+ location text
+);
+
+create trigger if not EXISTS events_fts_ignore before insert on events_fts begin
+ select raise(ignore);
+end;
+
+insert into events_fts(events_fts) values('rebuild');
+
+create trigger if not EXISTS events_fts_insert after insert on events begin
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
+
+create trigger if not EXISTS events_fts_delete after delete on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+end;
+
+create trigger if not EXISTS events_fts_update after update of name, description, location on events begin
+ insert into events_fts(events_fts, rowid, name, description, location) values ('delete', old.id, old.name, old.description, old.location);
+ insert into events_fts(rowid, name, description, location) values (new.id, new.name, new.description, new.location);
+end;
@@ -0,0 +1,51 @@
+// Package migrations embeds the SQL migrations, so the API can apply them
+// without the files on disk.
+package migrations
+
+import (
+	"embed"
+	"errors"
+	"io/fs"
+	"sort"
+)
+
+//go:embed *.sql
+var common embed.FS
+
+// FS holds the migrations of this build: the common ones, with the files of
+// search, which depend on the sqlite_fts5 build tag, in place of those of
+// the same name.
+var FS fs.FS = overlay{search, common}
+
+// overlay is a flat file system made of layers, the first layer holding a
+// file taking precedence.
+type overlay []fs.FS
+
+func (o overlay) Open(name string) (fs.File, error) {
+	for _, layer := range o[:len(o)-1] {
+		f, err := layer.Open(name)
+		if err == nil || !errors.Is(err, fs.ErrNotExist) {
+			return f, err
+		}
+	}
+	return o[len(o)-1].Open(name)
+}
+
+func (o overlay) ReadDir(name string) ([]fs.DirEntry, error) {
+	var entries []fs.DirEntry
+	seen := map[string]bool{}
+	for _, layer := range o {
+		layerEntries, err := fs.ReadDir(layer, name)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range layerEntries {
+			if !seen[entry.Name()] {
+				seen[entry.Name()] = true
+				entries = append(entries, entry)
+			}
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
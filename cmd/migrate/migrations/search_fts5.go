@@ -0,0 +1,8 @@
+//go:build sqlite_fts5
+
+package migrations
+
+import "embed"
+
+// search is empty: the common migrations create the FTS5 table.
+var search embed.FS
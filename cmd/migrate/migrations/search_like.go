@@ -0,0 +1,15 @@
+//go:build !sqlite_fts5
+
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed like/*.sql
+var like embed.FS
+
+// search replaces the migration creating the FTS5 table, which SQLite
+// doesn't support without the sqlite_fts5 build tag.
+var search, _ = fs.Sub(like, "like")
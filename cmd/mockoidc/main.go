@@ -0,0 +1,40 @@
+// Command mockoidc runs a local OpenID Connect provider to try out the OIDC
+// login without a real identity provider. It signs everyone in without a
+// password, as the --email user or as the login_hint of the request.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/anshbadoni30/event-management-app/internal/oidc/oidctest"
+)
+
+func main() {
+	log.SetFlags(0)
+	addr := flag.String("addr", "localhost:9000", "address to listen on")
+	issuer := flag.String("issuer", "", "issuer URL (default http://<addr>)")
+	clientID := flag.String("client-id", "events", "client id of the API")
+	clientSecret := flag.String("client-secret", "events-secret", "client secret of the API; empty for a public client")
+	email := flag.String("email", "alice@example.com", "email of the user signed in without a login_hint")
+	name := flag.String("name", "Alice Example", "name of that user")
+	flag.Parse()
+
+	if *issuer == "" {
+		*issuer = "http://" + *addr
+	}
+	server, err := oidctest.NewServer(*issuer, *clientID, *clientSecret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.User.Subject, server.User.Email, server.User.Name = *email, *email, *name
+
+	log.Printf("OpenID Connect provider %s for client %q, signing in %s", server.Issuer, *clientID, *email)
+	log.Printf("Start the API with OIDC_ISSUER=%s OIDC_CLIENT_ID=%s OIDC_CLIENT_SECRET=%s", server.Issuer, *clientID, *clientSecret)
+	if !strings.HasPrefix(*addr, "localhost:") && !strings.HasPrefix(*addr, "127.0.0.1:") {
+		log.Print("Warning: anyone who can reach this server can sign in as anyone")
+	}
+	log.Fatal(http.ListenAndServe(*addr, server))
+}
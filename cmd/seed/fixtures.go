@@ -0,0 +1,218 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"github.com/gin-gonic/gin/binding"
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// fixture is the YAML format of seed data. Events refer to their owner and
+// attendees by email, so fixtures don't depend on ids.
+type fixture struct {
+	Users  []fixtureUser  `yaml:"users"`
+	Events []fixtureEvent `yaml:"events"`
+}
+
+// fixtureUser is a user to create, with either a password or, in dumps, the
+// hash of one. The binding tags are those of the API's registration.
+type fixtureUser struct {
+	Name                  string `yaml:"name" binding:"required,min=2"`
+	Email                 string `yaml:"email" binding:"required,email"`
+	Password              string `yaml:"password,omitempty" binding:"required_without=PasswordHash,omitempty,min=8"`
+	PasswordHash          string `yaml:"passwordHash,omitempty"`
+	HideFromAttendeeLists bool   `yaml:"hideFromAttendeeLists,omitempty"`
+	// EmailReminders defaults to true, like for users who register.
+	EmailReminders *bool `yaml:"emailReminders,omitempty"`
+}
+
+type fixtureEvent struct {
+	Owner              string   `yaml:"owner"`
+	Name               string   `yaml:"name"`
+	Description        string   `yaml:"description"`
+	Date               string   `yaml:"date"`
+	Location           string   `yaml:"location"`
+	Latitude           *float64 `yaml:"latitude,omitempty"`
+	Longitude          *float64 `yaml:"longitude,omitempty"`
+	Tags               []string `yaml:"tags,omitempty"`
+	Visibility         string   `yaml:"visibility,omitempty"`
+	AttendeeVisibility string   `yaml:"attendeeVisibility,omitempty"`
+	Capacity           *int     `yaml:"capacity,omitempty"`
+	Attendees          []string `yaml:"attendees,omitempty"`
+}
+
+func readFixture(path string) (*fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f fixture
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &f, nil
+}
+
+func writeFixture(path string, f *fixture) error {
+	data, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if path == "-" {
+		_, err = os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// load stores the users and events of a fixture. Users whose email is taken
+// are reused rather than created again, so fixtures can build on each
+// other; events are always created. Loading stops at the first invalid
+// user or event, keeping what was stored before it.
+func load(models *database.Models, f *fixture) error {
+	ids := map[string]int{}
+	hashes := map[string]string{}
+	for _, u := range f.Users {
+		if err := binding.Validator.ValidateStruct(&u); err != nil {
+			return fmt.Errorf("user %q: %w", u.Email, err)
+		}
+		if existing, err := models.Users.GetByEmail(u.Email); err == nil {
+			ids[u.Email] = existing.Id
+			continue
+		}
+
+		hash := u.PasswordHash
+		if hash == "" {
+			//users often share a test password, which only needs hashing once
+			if hash = hashes[u.Password]; hash == "" {
+				hashed, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+				if err != nil {
+					return err
+				}
+				hash = string(hashed)
+				hashes[u.Password] = hash
+			}
+		}
+		user := database.User{
+			Name:                  u.Name,
+			Email:                 u.Email,
+			Password:              hash,
+			HideFromAttendeeLists: u.HideFromAttendeeLists,
+			EmailReminders:        u.EmailReminders == nil || *u.EmailReminders,
+		}
+		if err := models.Users.Insert(&user); err != nil {
+			return fmt.Errorf("user %q: %w", u.Email, err)
+		}
+		ids[u.Email] = user.Id
+	}
+
+	userId := func(email string) (int, error) {
+		if id, ok := ids[email]; ok {
+			return id, nil
+		}
+		user, err := models.Users.GetByEmail(email)
+		if err != nil {
+			return 0, err
+		}
+		ids[email] = user.Id
+		return user.Id, nil
+	}
+	for _, e := range f.Events {
+		ownerId, err := userId(e.Owner)
+		if err != nil {
+			return fmt.Errorf("event %q: owner: %w", e.Name, err)
+		}
+		event := database.Event{
+			OwnerId:            ownerId,
+			Name:               e.Name,
+			Description:        e.Description,
+			Date:               e.Date,
+			Location:           e.Location,
+			Latitude:           e.Latitude,
+			Longitude:          e.Longitude,
+			Tags:               e.Tags,
+			Visibility:         e.Visibility,
+			AttendeeVisibility: e.AttendeeVisibility,
+			Capacity:           e.Capacity,
+		}
+		if event.Tags == nil {
+			event.Tags = []string{}
+		}
+		if err := binding.Validator.ValidateStruct(&event); err != nil {
+			return fmt.Errorf("event %q: %w", e.Name, err)
+		}
+		if err := models.Events.Insert(&event); err != nil {
+			return fmt.Errorf("event %q: %w", e.Name, err)
+		}
+
+		for _, email := range e.Attendees {
+			id, err := userId(email)
+			if err != nil {
+				return fmt.Errorf("event %q: attendee: %w", e.Name, err)
+			}
+			_, err = models.Attendees.Insert(&database.Attendee{UserId: id, EventId: event.Id})
+			if err != nil && !errors.Is(err, database.ErrAlreadyAttending) {
+				return fmt.Errorf("event %q: attendee %s: %w", e.Name, email, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dump reads every user with their events and attendees into a fixture.
+// Passwords are kept as hashes, so dumped users log in as before.
+func dump(models *database.Models) (*fixture, error) {
+	users, err := models.Users.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	f := &fixture{Users: []fixtureUser{}, Events: []fixtureEvent{}}
+	for _, user := range users {
+		emailReminders := user.EmailReminders
+		u := fixtureUser{
+			Name:                  user.Name,
+			Email:                 user.Email,
+			PasswordHash:          user.Password,
+			HideFromAttendeeLists: user.HideFromAttendeeLists,
+		}
+		if !emailReminders {
+			u.EmailReminders = &emailReminders
+		}
+		f.Users = append(f.Users, u)
+	}
+
+	for _, user := range users {
+		events, err := models.Events.GetByOwner(user.Id)
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range events {
+			attendees, err := models.Attendees.GetAttendeesByEvent(event.Id)
+			if err != nil {
+				return nil, err
+			}
+			e := fixtureEvent{
+				Owner:              user.Email,
+				Name:               event.Name,
+				Description:        event.Description,
+				Date:               event.Date,
+				Location:           event.Location,
+				Latitude:           event.Latitude,
+				Longitude:          event.Longitude,
+				Tags:               event.Tags,
+				Visibility:         event.Visibility,
+				AttendeeVisibility: event.AttendeeVisibility,
+				Capacity:           event.Capacity,
+			}
+			for _, attendee := range attendees {
+				e.Attendees = append(e.Attendees, attendee.Email)
+			}
+			f.Events = append(f.Events, e)
+		}
+	}
+	return f, nil
+}
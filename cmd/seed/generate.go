@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+type generateOptions struct {
+	Users    int
+	Events   int
+	Seed     uint64
+	Start    time.Time
+	Password string
+}
+
+var (
+	firstNames = []string{"Alice", "Bob", "Carla", "David", "Elif", "Farid", "Greta", "Hiro", "Ines", "Jonas", "Kemi", "Lars", "Maya", "Nikolai", "Olivia", "Pablo", "Qiu", "Rosa", "Sven", "Tara"}
+	lastNames  = []string{"Schmidt", "Novak", "Rossi", "Kowalski", "Yilmaz", "Haddad", "Lindqvist", "Tanaka", "Garcia", "Weber", "Okafor", "Jensen", "Patel", "Ivanov", "Brown", "Moreno", "Chen", "Silva", "Berg", "Singh"}
+	topics     = []string{"Go", "Rust", "Kubernetes", "Design", "Startup", "Photography", "Jazz", "Yoga", "Board Games", "Open Source", "Data Science", "Climbing"}
+	formats    = []string{"Meetup", "Workshop", "Conference", "Night", "Hackathon", "Social", "Talks"}
+)
+
+type city struct {
+	name      string
+	latitude  float64
+	longitude float64
+}
+
+var cities = []city{
+	{"Berlin", 52.5200, 13.4050},
+	{"Munich", 48.1351, 11.5820},
+	{"Amsterdam", 52.3676, 4.9041},
+	{"Paris", 48.8566, 2.3522},
+	{"London", 51.5074, -0.1278},
+	{"Lisbon", 38.7223, -9.1393},
+	{"Barcelona", 41.3874, 2.1686},
+	{"Vienna", 48.2082, 16.3738},
+	{"Prague", 50.0755, 14.4378},
+	{"Stockholm", 59.3293, 18.0686},
+	{"Warsaw", 52.2297, 21.0122},
+	{"Zurich", 47.3769, 8.5417},
+}
+
+// generate makes up a fixture of users, events and attendees. The same
+// options always give the same fixture. Events take place from a month
+// before the start date to half a year after it, so there are past events
+// for statistics and upcoming ones to sign up for.
+func generate(opts generateOptions) (*fixture, error) {
+	if opts.Users < 1 {
+		return nil, errors.New("at least one user is needed to own the events")
+	}
+	if opts.Events < 0 {
+		return nil, errors.New("the number of events can't be negative")
+	}
+	r := rand.New(rand.NewPCG(opts.Seed, opts.Seed))
+
+	f := &fixture{}
+	for i := range opts.Users {
+		first, last := firstNames[r.IntN(len(firstNames))], lastNames[r.IntN(len(lastNames))]
+		f.Users = append(f.Users, fixtureUser{
+			Name:                  first + " " + last,
+			Email:                 fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), i+1),
+			Password:              opts.Password,
+			HideFromAttendeeLists: r.IntN(10) == 0,
+		})
+	}
+
+	for range opts.Events {
+		owner := r.IntN(len(f.Users))
+		topic, format, city := topics[r.IntN(len(topics))], formats[r.IntN(len(formats))], cities[r.IntN(len(cities))]
+		date := opts.Start.AddDate(0, 0, r.IntN(210)-30).Add(time.Duration(9+r.IntN(12)) * time.Hour)
+		latitude, longitude := city.latitude+r.Float64()*0.1-0.05, city.longitude+r.Float64()*0.1-0.05
+
+		event := fixtureEvent{
+			Owner:              f.Users[owner].Email,
+			Name:               fmt.Sprintf("%s %s %s", city.name, topic, format),
+			Description:        fmt.Sprintf("A %s for everyone into %s in %s, hosted by %s.", strings.ToLower(format), topic, city.name, f.Users[owner].Name),
+			Date:               date.Format("2006-01-02 15:04:05"),
+			Location:           city.name,
+			Latitude:           &latitude,
+			Longitude:          &longitude,
+			Tags:               []string{database.Slugify(topic), database.Slugify(format), database.Slugify(city.name)},
+			Visibility:         pick(r, visibilities),
+			AttendeeVisibility: pick(r, attendeeVisibilities),
+		}
+
+		attendees := r.IntN(len(f.Users))
+		if r.IntN(10) < 3 {
+			capacity := 5 + r.IntN(46)
+			event.Capacity = &capacity
+			attendees = min(attendees, capacity)
+		}
+		for _, i := range r.Perm(len(f.Users)) {
+			if len(event.Attendees) == attendees {
+				break
+			}
+			if i != owner {
+				event.Attendees = append(event.Attendees, f.Users[i].Email)
+			}
+		}
+		f.Events = append(f.Events, event)
+	}
+	return f, nil
+}
+
+// weighted is a value picked with a probability proportional to its weight.
+type weighted struct {
+	value  string
+	weight int
+}
+
+// Most events are public, and most show their attendees to everyone.
+var (
+	visibilities         = []weighted{{database.VisibilityPublic, 8}, {database.VisibilityUnlisted, 1}, {database.VisibilityPrivate, 1}}
+	attendeeVisibilities = []weighted{{database.AttendeesPublic, 6}, {database.AttendeesAttendees, 2}, {database.AttendeesOrganizers, 2}}
+)
+
+func pick(r *rand.Rand, values []weighted) string {
+	total := 0
+	for _, v := range values {
+		total += v.weight
+	}
+	n := r.IntN(total)
+	for _, v := range values {
+		if n -= v.weight; n < 0 {
+			return v.value
+		}
+	}
+	return values[len(values)-1].value
+}
@@ -0,0 +1,110 @@
+// Command seed fills a database with data to develop and demo against. It
+// generates users, events and attendees from a random seed, and loads and
+// dumps YAML fixtures for reproducible scenarios. Everything is stored
+// through database.Models, after the validation the API applies.
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const usage = `Usage: seed [flags] <command>
+
+Commands:
+  generate      generate users, events and attendees
+  load FILE     load a YAML fixture
+  dump FILE     write the users, events and attendees to a YAML fixture,
+                or to stdout if FILE is -
+
+Flags:
+`
+
+func main() {
+	log.SetFlags(0)
+	dsn := flag.String("dsn", envOr("DB_PATH", "./data.db"), "SQLite database to seed ($DB_PATH)")
+	users := flag.Int("users", 20, "number of users to generate")
+	events := flag.Int("events", 30, "number of events to generate")
+	seed := flag.Uint64("seed", 1, "random seed; the same seed and start generate the same data")
+	start := flag.String("start", time.Now().UTC().Format(time.DateOnly), "date generated events are spread around, as YYYY-MM-DD")
+	password := flag.String("password", "password123", "password of the generated users")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, args := flag.Arg(0), flag.Args()[1:]
+	if command != "generate" && len(args) != 1 {
+		log.Fatalf("%s needs a file", command)
+	}
+
+	db, err := sql.Open("sqlite3", *dsn)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		log.Fatal("Failed to enable foreign keys:", err)
+	}
+	models := database.NewModels(db)
+
+	switch command {
+	case "generate":
+		startDate, err := time.Parse(time.DateOnly, *start)
+		if err != nil {
+			log.Fatalf("Invalid start date %q", *start)
+		}
+		f, err := generate(generateOptions{
+			Users:    *users,
+			Events:   *events,
+			Seed:     *seed,
+			Start:    startDate,
+			Password: *password,
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := load(&models, f); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Generated %d users, all with the password %q, and %d events", len(f.Users), *password, len(f.Events))
+	case "load":
+		f, err := readFixture(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := load(&models, f); err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("Loaded %d users and %d events from %s", len(f.Users), len(f.Events), args[0])
+	case "dump":
+		f, err := dump(&models)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := writeFixture(args[0], f); err != nil {
+			log.Fatal(err)
+		}
+	default:
+		log.Fatalf("Unknown command %q, run with -h for help", command)
+	}
+}
+
+func envOr(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestModels returns the models of a freshly migrated database.
+func newTestModels(t *testing.T) *database.Models {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if _, _, err := database.Migrate(path, migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+	models := database.NewModels(db)
+	return &models
+}
+
+// start is the date generated events are spread around.
+var start = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    generateOptions
+		wantErr bool
+	}{
+		{"default", generateOptions{Users: 20, Events: 30, Seed: 1, Start: start, Password: "password123"}, false},
+		{"more users than names", generateOptions{Users: 500, Events: 5, Seed: 2, Start: start, Password: "password123"}, false},
+		{"one user", generateOptions{Users: 1, Events: 3, Seed: 3, Start: start, Password: "password123"}, false},
+		{"no events", generateOptions{Users: 3, Events: 0, Seed: 4, Start: start, Password: "password123"}, false},
+		{"no users", generateOptions{Users: 0, Events: 3, Seed: 1, Start: start, Password: "password123"}, true},
+		{"negative events", generateOptions{Users: 3, Events: -1, Seed: 1, Start: start, Password: "password123"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := generate(tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("generate() error = %v, want error %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(f.Users) != tt.opts.Users || len(f.Events) != tt.opts.Events {
+				t.Fatalf("generated %d users and %d events", len(f.Users), len(f.Events))
+			}
+			again, err := generate(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(f, again) {
+				t.Error("the same options generated different data")
+			}
+
+			emails := map[string]bool{}
+			for _, user := range f.Users {
+				if emails[user.Email] {
+					t.Errorf("email %s generated twice", user.Email)
+				}
+				emails[user.Email] = true
+			}
+			for _, event := range f.Events {
+				date, err := time.Parse("2006-01-02 15:04:05", event.Date)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if date.Before(start.AddDate(0, 0, -30)) || date.After(start.AddDate(0, 0, 180)) {
+					t.Errorf("%s is on %s, too far from the start", event.Name, event.Date)
+				}
+				if slices.Contains(event.Attendees, event.Owner) {
+					t.Errorf("%s is attended by its owner", event.Name)
+				}
+				if event.Capacity != nil && len(event.Attendees) > *event.Capacity {
+					t.Errorf("%s has %d attendees, over its capacity of %d", event.Name, len(event.Attendees), *event.Capacity)
+				}
+				sorted := slices.Clone(event.Attendees)
+				slices.Sort(sorted)
+				if len(slices.Compact(sorted)) != len(event.Attendees) {
+					t.Errorf("%s has an attendee twice", event.Name)
+				}
+			}
+		})
+	}
+
+	var seeded []*fixture
+	for _, seed := range []uint64{1, 2} {
+		f, err := generate(generateOptions{Users: 20, Events: 30, Seed: seed, Start: start, Password: "password123"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		seeded = append(seeded, f)
+	}
+	if reflect.DeepEqual(seeded[0], seeded[1]) {
+		t.Error("different seeds generated the same data")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	alice := fixtureUser{Name: "Alice", Email: "alice@example.com", Password: "password123"}
+	bob := fixtureUser{Name: "Bob", Email: "bob@example.com", Password: "password123"}
+	meetup := fixtureEvent{Owner: "alice@example.com", Name: "Meetup", Description: "Monthly meetup", Date: "2030-01-01 18:00:00", Location: "Town hall", Attendees: []string{"bob@example.com", "bob@example.com"}}
+
+	tests := []struct {
+		name       string
+		fixture    fixture
+		wantErr    string
+		wantUsers  int
+		wantEvents int
+	}{
+		{"users and events", fixture{Users: []fixtureUser{alice, bob}, Events: []fixtureEvent{meetup}}, "", 2, 1},
+		{"existing users are reused", fixture{Users: []fixtureUser{alice, alice}, Events: []fixtureEvent{{Owner: "alice@example.com", Name: "Workshop", Description: "Hands on soldering", Date: "2030-02-01 18:00:00", Location: "Lab"}}}, "", 1, 1},
+		{"password too short", fixture{Users: []fixtureUser{{Name: "Eve", Email: "eve@example.com", Password: "short"}}}, `user "eve@example.com"`, 0, 0},
+		{"no password", fixture{Users: []fixtureUser{{Name: "Eve", Email: "eve@example.com"}}}, `user "eve@example.com"`, 0, 0},
+		{"invalid email", fixture{Users: []fixtureUser{{Name: "Eve", Email: "eve", Password: "password123"}}}, `user "eve"`, 0, 0},
+		{"unknown owner", fixture{Users: []fixtureUser{bob}, Events: []fixtureEvent{meetup}}, `event "Meetup": owner`, 1, 0},
+		{"invalid event keeps what came before", fixture{Users: []fixtureUser{alice}, Events: []fixtureEvent{
+			{Owner: "alice@example.com", Name: "Workshop", Description: "Hands on soldering", Date: "2030-02-01 18:00:00", Location: "Lab"},
+			{Owner: "alice@example.com", Name: "Meetup", Description: "Monthly meetup", Date: "2030-01-01 18:00:00", Location: "Town hall", Visibility: "secret"},
+		}}, `event "Meetup"`, 1, 1},
+		{"unknown attendee", fixture{Users: []fixtureUser{alice}, Events: []fixtureEvent{meetup}}, `event "Meetup": attendee`, 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			models := newTestModels(t)
+			err := load(models, &tt.fixture)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("load() error = %v, want %q", err, tt.wantErr)
+			}
+			f, err := dump(models)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(f.Users) != tt.wantUsers || len(f.Events) != tt.wantEvents {
+				t.Errorf("stored %d users and %d events, want %d and %d", len(f.Users), len(f.Events), tt.wantUsers, tt.wantEvents)
+			}
+		})
+	}
+}
+
+// TestDumpLoad checks that a dump loads into an empty database as the same
+// data, with users keeping their passwords.
+func TestDumpLoad(t *testing.T) {
+	generated, err := generate(generateOptions{Users: 8, Events: 10, Seed: 5, Start: start, Password: "password123"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated.Users[0].EmailReminders = new(bool)
+	models := newTestModels(t)
+	if err := load(models, generated); err != nil {
+		t.Fatal(err)
+	}
+	dumped, err := dump(models)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "fixtures.yaml")
+	if err := writeFixture(path, dumped); err != nil {
+		t.Fatal(err)
+	}
+	read, err := readFixture(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := newTestModels(t)
+	if err := load(other, read); err != nil {
+		t.Fatal(err)
+	}
+	again, err := dump(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(dumped, again) {
+		t.Errorf("dump of the loaded dump differs:\n%+v\n%+v", dumped, again)
+	}
+
+	user, err := other.Users.GetByEmail(generated.Users[0].Email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("password123")); err != nil {
+		t.Errorf("loaded user can't log in with their password: %v", err)
+	}
+	if user.EmailReminders {
+		t.Error("loaded user gets email reminders they turned off")
+	}
+}
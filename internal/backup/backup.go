@@ -0,0 +1,374 @@
+// Package backup takes and restores backups of the SQLite database.
+//
+// Backups are taken online with VACUUM INTO, which writes a consistent copy
+// of the database while the server keeps running. Each backup is a gzipped
+// copy named after the time it was taken and its schema version, like
+// backup-20260102T150405Z-v17.db.gz, next to a .sha256 file in the format
+// of sha256sum.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const timeLayout = "20060102T150405Z"
+
+var (
+	backupName = regexp.MustCompile(`^backup-(\d{8}T\d{6}Z)-v(\d+)\.db\.gz$`)
+
+	ErrNotFound         = errors.New("backup not found")
+	ErrChecksumMismatch = errors.New("backup doesn't match its checksum")
+)
+
+// mu keeps backups and pruning in one process from running over each other.
+var mu sync.Mutex
+
+type Backup struct {
+	Name          string    `json:"name"`
+	Size          int64     `json:"size"`
+	SHA256        string    `json:"sha256"`
+	CreatedAt     time.Time `json:"createdAt"`
+	SchemaVersion int       `json:"schemaVersion"`
+}
+
+// Create backs up db into dir, creating dir if needed.
+func Create(ctx context.Context, db *sql.DB, dir string) (*Backup, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	createdAt := time.Now().UTC().Truncate(time.Second)
+
+	//VACUUM INTO refuses to overwrite, so it gets a fresh name
+	tmp, err := os.CreateTemp(dir, ".backup-*.db")
+	if err != nil {
+		return nil, err
+	}
+	tmp.Close()
+	os.Remove(tmp.Name())
+	defer os.Remove(tmp.Name())
+	if _, err := db.ExecContext(ctx, "vacuum into ?", tmp.Name()); err != nil {
+		return nil, fmt.Errorf("copying the database: %w", err)
+	}
+	version, err := schemaVersion(tmp.Name())
+	if err != nil {
+		return nil, err
+	}
+
+	b := &Backup{
+		Name:          fmt.Sprintf("backup-%s-v%d.db.gz", createdAt.Format(timeLayout), version),
+		CreatedAt:     createdAt,
+		SchemaVersion: version,
+	}
+	path := filepath.Join(dir, b.Name)
+	if b.Size, b.SHA256, err = compress(tmp.Name(), path); err != nil {
+		return nil, err
+	}
+	checksum := fmt.Sprintf("%s  %s\n", b.SHA256, b.Name)
+	if err := os.WriteFile(path+".sha256", []byte(checksum), 0o640); err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return b, nil
+}
+
+// compress gzips the file at src into a new file at dst, returning its size
+// and checksum. dst only appears once it's complete.
+func compress(src, dst string) (int64, string, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, "", err
+	}
+	defer in.Close()
+
+	partial := dst + ".partial"
+	out, err := os.OpenFile(partial, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o640)
+	if err != nil {
+		return 0, "", err
+	}
+	defer os.Remove(partial)
+	defer out.Close()
+
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(out, hash)}
+	gz := gzip.NewWriter(counter)
+	if _, err := io.Copy(gz, in); err != nil {
+		return 0, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return 0, "", err
+	}
+	if err := out.Sync(); err != nil {
+		return 0, "", err
+	}
+	if err := out.Close(); err != nil {
+		return 0, "", err
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return 0, "", fmt.Errorf("%s already exists", filepath.Base(dst))
+	}
+	if err := os.Rename(partial, dst); err != nil {
+		return 0, "", err
+	}
+	return counter.n, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// List returns the backups in dir, newest first. A missing dir has no
+// backups.
+func List(dir string) ([]*Backup, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return []*Backup{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	backups := []*Backup{}
+	for _, entry := range entries {
+		b, err := Get(dir, entry.Name())
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, b)
+	}
+	slices.SortFunc(backups, func(a, b *Backup) int { return b.CreatedAt.Compare(a.CreatedAt) })
+	return backups, nil
+}
+
+// Get returns the backup named name in dir, or ErrNotFound if there is
+// none. Names that aren't backups, like ../data.db, are never found.
+func Get(dir, name string) (*Backup, error) {
+	b, err := parseName(name)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	info, err := os.Stat(filepath.Join(dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	b.Size = info.Size()
+	if b.SHA256, err = readChecksum(filepath.Join(dir, name)); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Prune deletes all but the newest keep backups in dir and returns the
+// names of the deleted ones.
+func Prune(dir string, keep int) ([]string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	backups, err := List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var deleted []string
+	for _, b := range backups[min(keep, len(backups)):] {
+		path := filepath.Join(dir, b.Name)
+		if err := os.Remove(path); err != nil {
+			return deleted, err
+		}
+		if err := os.Remove(path + ".sha256"); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return deleted, err
+		}
+		deleted = append(deleted, b.Name)
+	}
+	return deleted, nil
+}
+
+// Verify checks the backup at path against its checksum file.
+func Verify(path string) error {
+	want, err := readChecksum(path)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return err
+	}
+	if hex.EncodeToString(hash.Sum(nil)) != want {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// Restore replaces the database at dbPath with the backup at path, which
+// must match its checksum, pass SQLite's integrity check and have a clean
+// schema no newer than latestMigration. The replaced database is kept next
+// to it, and its path returned, unless there was none. The server must not
+// be running while a backup is restored. Backups of older schemas are
+// restored as they are, to be migrated afterwards.
+func Restore(path, dbPath string, latestMigration int) (version int, previous string, err error) {
+	if err := Verify(path); err != nil {
+		return 0, "", err
+	}
+
+	tmp := dbPath + ".restoring"
+	if err := decompress(path, tmp); err != nil {
+		os.Remove(tmp)
+		return 0, "", err
+	}
+	defer os.Remove(tmp)
+	if err := checkIntegrity(tmp); err != nil {
+		return 0, "", err
+	}
+	db, err := sql.Open("sqlite3", tmp)
+	if err != nil {
+		return 0, "", err
+	}
+	version, dirty, err := database.SchemaVersion(db)
+	db.Close()
+	switch {
+	case err != nil:
+		return 0, "", fmt.Errorf("reading the schema version: %w", err)
+	case dirty:
+		return version, "", fmt.Errorf("the backup was taken while migration %d had failed halfway", version)
+	case version > latestMigration:
+		return version, "", fmt.Errorf("the backup is at schema version %d, newer than the latest migration %d", version, latestMigration)
+	}
+
+	if _, err := os.Stat(dbPath); err == nil {
+		previous = fmt.Sprintf("%s.before-restore-%s", dbPath, time.Now().UTC().Format(timeLayout))
+		if err := os.Rename(dbPath, previous); err != nil {
+			return version, "", err
+		}
+	}
+	//a journal left by the replaced database would be applied to the backup
+	for _, suffix := range []string{"-wal", "-shm", "-journal"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return version, previous, err
+		}
+	}
+	return version, previous, os.Rename(tmp, dbPath)
+}
+
+func decompress(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return err
+	}
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o640)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, gz); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func checkIntegrity(path string) error {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var result string
+	if err := db.QueryRow("pragma integrity_check").Scan(&result); err != nil {
+		return fmt.Errorf("checking integrity: %w", err)
+	}
+	if result != "ok" {
+		return fmt.Errorf("the backup failed the integrity check: %s", result)
+	}
+	return nil
+}
+
+func schemaVersion(path string) (int, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	version, _, err := database.SchemaVersion(db)
+	return version, err
+}
+
+func parseName(name string) (*Backup, error) {
+	match := backupName.FindStringSubmatch(name)
+	if match == nil {
+		return nil, ErrNotFound
+	}
+	createdAt, err := time.Parse(timeLayout, match[1])
+	if err != nil {
+		return nil, err
+	}
+	version, err := strconv.Atoi(match[2])
+	if err != nil {
+		return nil, err
+	}
+	return &Backup{Name: name, CreatedAt: createdAt, SchemaVersion: version}, nil
+}
+
+// readChecksum reads the checksum of the backup at path from its .sha256
+// file.
+func readChecksum(path string) (string, error) {
+	f, err := os.Open(path + ".sha256")
+	if err != nil {
+		return "", fmt.Errorf("reading the checksum: %w", err)
+	}
+	defer f.Close()
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	checksum, _, _ := strings.Cut(line, " ")
+	if len(checksum) != sha256.Size*2 {
+		return "", fmt.Errorf("invalid checksum file %s.sha256", filepath.Base(path))
+	}
+	return checksum, nil
+}
@@ -0,0 +1,335 @@
+package backup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+// newTestDB returns a freshly migrated database with one user, named name.
+func newTestDB(t *testing.T, path, name string) *sql.DB {
+	t.Helper()
+	if _, _, err := database.Migrate(path, migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	models := database.NewModels(db)
+	if err := models.Users.Insert(&database.User{Name: name, Email: name + "@example.com", Password: "x"}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// userNames returns the names of the users in the database at path.
+func userNames(t *testing.T, path string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	rows, err := db.Query("select name from users order by id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// writeBackup writes a backup named name, with its checksum, of whatever
+// data is, so tests can make backups that Create never would.
+func writeBackup(t *testing.T, dir, name string, data []byte) string {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(src, data, 0o640); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	_, checksum, err := compress(src, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".sha256", []byte(checksum+"  "+name+"\n"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCreate(t *testing.T) {
+	dir := t.TempDir()
+	db := newTestDB(t, filepath.Join(dir, "data.db"), "alice")
+	latest, err := database.LatestMigration(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backups := filepath.Join(dir, "backups")
+	b, err := Create(context.Background(), db, backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprintf("backup-%s-v%d.db.gz", b.CreatedAt.Format(timeLayout), latest); b.Name != want || b.SchemaVersion != latest {
+		t.Errorf("backup %s at version %d, want %s", b.Name, b.SchemaVersion, want)
+	}
+	if err := Verify(filepath.Join(backups, b.Name)); err != nil {
+		t.Errorf("Verify() = %v", err)
+	}
+	checksum, err := os.ReadFile(filepath.Join(backups, b.Name+".sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := b.SHA256 + "  " + b.Name + "\n"; string(checksum) != want {
+		t.Errorf("checksum file = %q, want %q", checksum, want)
+	}
+	got, err := Get(backups, b.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *got != *b {
+		t.Errorf("Get() = %+v, want %+v", got, b)
+	}
+
+	//no temporary files are left behind
+	entries, err := os.ReadDir(backups)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("backup directory has %d files, want the backup and its checksum", len(entries))
+	}
+}
+
+func TestGet(t *testing.T) {
+	dir := t.TempDir()
+	name := "backup-20260102T150405Z-v17.db.gz"
+	writeBackup(t, dir, name, []byte("data"))
+	writeBackup(t, dir, "backup-20260103T150405Z-v17.db.gz", []byte("data"))
+	if err := os.Remove(filepath.Join(dir, "backup-20260103T150405Z-v17.db.gz.sha256")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		wantErr error
+	}{
+		{name, nil},
+		{"backup-20260104T150405Z-v17.db.gz", ErrNotFound},
+		{"../data.db", ErrNotFound},
+		{"backup-20260102T150405Z-v17.db.gz.sha256", ErrNotFound},
+		{"backup-yesterday-v17.db.gz", ErrNotFound},
+		{"../" + filepath.Base(dir) + "/" + name, ErrNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := Get(dir, tt.name)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (b.SchemaVersion != 17 || !b.CreatedAt.Equal(time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)) || len(b.SHA256) != 64) {
+				t.Errorf("Get() = %+v", b)
+			}
+		})
+	}
+
+	if _, err := Get(dir, "backup-20260103T150405Z-v17.db.gz"); err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of a backup without checksum = %v, want an error reading it", err)
+	}
+}
+
+func TestListAndPrune(t *testing.T) {
+	dir := t.TempDir()
+	names := []string{
+		"backup-20260102T150405Z-v16.db.gz",
+		"backup-20260104T150405Z-v17.db.gz",
+		"backup-20260103T150405Z-v17.db.gz",
+	}
+	for _, name := range names {
+		writeBackup(t, dir, name, []byte(name))
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("keep me"), 0o640); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keep        int
+		wantDeleted []string
+		wantLeft    []string
+	}{
+		{5, nil, []string{names[1], names[2], names[0]}},
+		{2, []string{names[0]}, []string{names[1], names[2]}},
+		{0, []string{names[1], names[2]}, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("keep %d", tt.keep), func(t *testing.T) {
+			deleted, err := Prune(dir, tt.keep)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(deleted, tt.wantDeleted) {
+				t.Errorf("Prune() deleted %q, want %q", deleted, tt.wantDeleted)
+			}
+			backups, err := List(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			left := []string{}
+			for _, b := range backups {
+				left = append(left, b.Name)
+			}
+			if !slices.Equal(left, tt.wantLeft) {
+				t.Errorf("List() = %q, want %q, newest first", left, tt.wantLeft)
+			}
+			for _, name := range tt.wantDeleted {
+				if _, err := os.Stat(filepath.Join(dir, name+".sha256")); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("checksum of %s left behind", name)
+				}
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+		t.Errorf("Prune() touched a file that isn't a backup: %v", err)
+	}
+	if backups, err := List(filepath.Join(dir, "missing")); err != nil || len(backups) != 0 {
+		t.Errorf("List() of a missing directory = %v, %v", backups, err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	latest, err := database.LatestMigration(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// backupOf returns a backup of a database with a user alice, changed by
+	// change.
+	backupOf := func(t *testing.T, change string) string {
+		t.Helper()
+		dir := t.TempDir()
+		db := newTestDB(t, filepath.Join(dir, "source.db"), "alice")
+		if change != "" {
+			if _, err := db.Exec(change); err != nil {
+				t.Fatal(err)
+			}
+		}
+		b, err := Create(context.Background(), db, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return filepath.Join(dir, b.Name)
+	}
+
+	tests := []struct {
+		name string
+		// backup returns the path of the backup to restore.
+		backup       func(t *testing.T) string
+		noDatabase   bool
+		wantVersion  int
+		wantErr      string
+		wantChecksum bool
+	}{
+		{"backup", func(t *testing.T) string { return backupOf(t, "") }, false, latest, "", false},
+		{"into an empty place", func(t *testing.T) string { return backupOf(t, "") }, true, latest, "", false},
+		{"older schema", func(t *testing.T) string { return backupOf(t, "update schema_migrations set version = 17") }, false, 17, "", false},
+		{"newer schema", func(t *testing.T) string {
+			return backupOf(t, fmt.Sprintf("update schema_migrations set version = %d", latest+1))
+		}, false, latest + 1, "newer than the latest migration", false},
+		{"dirty", func(t *testing.T) string { return backupOf(t, "update schema_migrations set dirty = 1") }, false, latest, "failed halfway", false},
+		{"tampered", func(t *testing.T) string {
+			path := backupOf(t, "")
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data[len(data)/2] ^= 0xff
+			if err := os.WriteFile(path, data, 0o640); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}, false, 0, "", true},
+		{"no checksum", func(t *testing.T) string {
+			path := backupOf(t, "")
+			if err := os.Remove(path + ".sha256"); err != nil {
+				t.Fatal(err)
+			}
+			return path
+		}, false, 0, "reading the checksum", false},
+		{"not a database", func(t *testing.T) string {
+			return writeBackup(t, t.TempDir(), "backup-20260102T150405Z-v17.db.gz", []byte(strings.Repeat("not sqlite ", 1000)))
+		}, false, 0, "not a database", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dbPath := filepath.Join(t.TempDir(), "data.db")
+			if !tt.noDatabase {
+				newTestDB(t, dbPath, "bob").Close()
+				if err := os.WriteFile(dbPath+"-wal", []byte("stale"), 0o640); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			version, previous, err := Restore(tt.backup(t), dbPath, latest)
+			switch {
+			case tt.wantChecksum:
+				if !errors.Is(err, ErrChecksumMismatch) {
+					t.Fatalf("Restore() error = %v, want %v", err, ErrChecksumMismatch)
+				}
+			case tt.wantErr != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Restore() error = %v, want %q", err, tt.wantErr)
+				}
+			case err != nil:
+				t.Fatal(err)
+			}
+			if version != tt.wantVersion {
+				t.Errorf("Restore() version = %d, want %d", version, tt.wantVersion)
+			}
+
+			if err != nil {
+				//a refused backup leaves the database alone
+				if !tt.noDatabase && !slices.Equal(userNames(t, dbPath), []string{"bob"}) {
+					t.Error("a refused restore changed the database")
+				}
+				if _, err := os.Stat(dbPath + ".restoring"); !errors.Is(err, os.ErrNotExist) {
+					t.Error("a refused restore left its copy behind")
+				}
+				return
+			}
+			if got := userNames(t, dbPath); !slices.Equal(got, []string{"alice"}) {
+				t.Errorf("restored users = %q, want the backup's", got)
+			}
+			if tt.noDatabase {
+				if previous != "" {
+					t.Errorf("Restore() kept %q, but there was no database", previous)
+				}
+				return
+			}
+			if got := userNames(t, previous); !slices.Equal(got, []string{"bob"}) {
+				t.Errorf("kept database has users %q, want the replaced ones", got)
+			}
+			if _, err := os.Stat(dbPath + "-wal"); !errors.Is(err, os.ErrNotExist) {
+				t.Error("the replaced database's WAL was left behind")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// Package cache keeps values in memory for a limited time, for results that
+// are expensive to compute and fine to serve slightly stale.
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// Cache is a size-bounded map whose entries expire after a fixed time. It is
+// safe for concurrent use.
+type Cache[V any] struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	size    int
+	entries map[string]entry[V]
+}
+
+// New returns a cache keeping entries for ttl and at most size entries.
+func New[V any](ttl time.Duration, size int) *Cache[V] {
+	return &Cache[V]{ttl: ttl, size: size, entries: map[string]entry[V]{}}
+}
+
+// Get returns the value of a key if it was set less than the ttl ago.
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok || !time.Now().Before(e.expires) {
+		var zero V
+		return zero, false
+	}
+	return e.value, true
+}
+
+// Set stores the value of a key. When the cache is full, expired entries are
+// dropped first and then arbitrary ones.
+func (c *Cache[V]) Set(key string, value V) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.size {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.size {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry[V]{value: value, expires: now.Add(c.ttl)}
+}
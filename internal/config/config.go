@@ -0,0 +1,164 @@
+// Package config loads the settings of the API server. Settings come from
+// defaults, an optional YAML or TOML file, environment variables and
+// command line flags, each overriding the ones before.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+)
+
+const (
+	ModeDevelopment = "development"
+	ModeProduction  = "production"
+)
+
+// DefaultJWTSecret is only accepted in development mode.
+const DefaultJWTSecret = "some-secret-123456"
+
+// Config holds every setting of the server. Each setting has a key in the
+// config file, an environment variable and a flag, given by its tags.
+// Settings tagged secret are redacted when the config is printed.
+type Config struct {
+	Mode            string `yaml:"mode" toml:"mode" env:"APP_ENV" flag:"mode" usage:"development or production"`
+	Port            int    `yaml:"port" toml:"port" env:"PORT" flag:"port" usage:"port to listen on"`
+	MetricsAddr     string `yaml:"metricsAddr" toml:"metricsAddr" env:"METRICS_ADDR" flag:"metrics-addr" usage:"extra address serving Prometheus metrics without authentication; off when empty"`
+	DatabasePath    string `yaml:"databasePath" toml:"databasePath" env:"DB_PATH" flag:"db" usage:"path of the SQLite database"`
+	AutoMigrate     bool   `yaml:"autoMigrate" toml:"autoMigrate" env:"AUTO_MIGRATE" flag:"auto-migrate" usage:"apply pending migrations at startup"`
+	JWTSecret       string `yaml:"jwtSecret" toml:"jwtSecret" env:"JWT_SECRET" flag:"jwt-secret" secret:"true" usage:"secret signing the login tokens"`
+	BaseURL         string `yaml:"baseURL" toml:"baseURL" env:"BASE_URL" flag:"base-url" usage:"public URL of the server, used in links"`
+	JobWorkers      int    `yaml:"jobWorkers" toml:"jobWorkers" env:"JOB_WORKERS" flag:"job-workers" usage:"number of background job workers"`
+	ReminderOffsets string `yaml:"reminderOffsets" toml:"reminderOffsets" env:"REMINDER_OFFSETS" flag:"reminder-offsets" usage:"how long before events reminders go out, like 24h,1h"`
+	TraceExporter   string `yaml:"traceExporter" toml:"traceExporter" env:"OTEL_TRACES_EXPORTER" flag:"trace-exporter" usage:"otlp, stdout or none"`
+	BackupDir       string `yaml:"backupDir" toml:"backupDir" env:"BACKUP_DIR" flag:"backup-dir" usage:"directory of database backups"`
+	BackupKeep      int    `yaml:"backupKeep" toml:"backupKeep" env:"BACKUP_KEEP" flag:"backup-keep" usage:"number of backups to keep"`
+
+	WebhookAllowPrivate bool `yaml:"webhookAllowPrivate" toml:"webhookAllowPrivate" env:"WEBHOOK_ALLOW_PRIVATE" flag:"webhook-allow-private" usage:"let webhooks post to loopback and private addresses"`
+
+	SMTP     SMTP     `yaml:"smtp" toml:"smtp"`
+	Payments Payments `yaml:"payments" toml:"payments"`
+	OIDC     OIDC     `yaml:"oidc" toml:"oidc"`
+}
+
+// SMTP configures outgoing email. Without a host, emails are only logged.
+type SMTP struct {
+	Host     string `yaml:"host" toml:"host" env:"SMTP_HOST" flag:"smtp-host" usage:"SMTP server; emails are logged when empty"`
+	Port     int    `yaml:"port" toml:"port" env:"SMTP_PORT" flag:"smtp-port" usage:"SMTP port"`
+	Username string `yaml:"username" toml:"username" env:"SMTP_USERNAME" flag:"smtp-username" usage:"SMTP user"`
+	Password string `yaml:"password" toml:"password" env:"SMTP_PASSWORD" flag:"smtp-password" secret:"true" usage:"SMTP password"`
+	From     string `yaml:"from" toml:"from" env:"MAIL_FROM" flag:"mail-from" usage:"sender of emails"`
+}
+
+type Payments struct {
+	Provider            string `yaml:"provider" toml:"provider" env:"PAYMENT_PROVIDER" flag:"payment-provider" usage:"stripe or fake"`
+	WebhookSecret       string `yaml:"webhookSecret" toml:"webhookSecret" env:"PAYMENT_WEBHOOK_SECRET" flag:"payment-webhook-secret" secret:"true" usage:"webhook secret of the fake provider"`
+	StripeSecretKey     string `yaml:"stripeSecretKey" toml:"stripeSecretKey" env:"STRIPE_SECRET_KEY" flag:"stripe-secret-key" secret:"true" usage:"Stripe API key"`
+	StripeWebhookSecret string `yaml:"stripeWebhookSecret" toml:"stripeWebhookSecret" env:"STRIPE_WEBHOOK_SECRET" flag:"stripe-webhook-secret" secret:"true" usage:"Stripe webhook signing secret"`
+	StripeAPIURL        string `yaml:"stripeAPIURL" toml:"stripeAPIURL" env:"STRIPE_API_URL" flag:"stripe-api-url" usage:"Stripe API URL, for testing"`
+}
+
+// OIDC configures login with an OpenID Connect provider. Without an
+// issuer, the login is turned off.
+type OIDC struct {
+	Issuer       string `yaml:"issuer" toml:"issuer" env:"OIDC_ISSUER" flag:"oidc-issuer" usage:"OpenID Connect issuer URL; OIDC login is off when empty"`
+	ClientID     string `yaml:"clientID" toml:"clientID" env:"OIDC_CLIENT_ID" flag:"oidc-client-id" usage:"client id at the OpenID Connect provider"`
+	ClientSecret string `yaml:"clientSecret" toml:"clientSecret" env:"OIDC_CLIENT_SECRET" flag:"oidc-client-secret" secret:"true" usage:"client secret; empty for a public client"`
+	Scopes       string `yaml:"scopes" toml:"scopes" env:"OIDC_SCOPES" flag:"oidc-scopes" usage:"scopes to request, separated by spaces"`
+	Signup       bool   `yaml:"signup" toml:"signup" env:"OIDC_SIGNUP" flag:"oidc-signup" usage:"create accounts for unknown users who sign in with OIDC"`
+}
+
+// Default returns the settings used when nothing else is configured, which
+// suit local development.
+func Default() *Config {
+	return &Config{
+		Mode:            ModeDevelopment,
+		Port:            8080,
+		DatabasePath:    "./data.db",
+		JWTSecret:       DefaultJWTSecret,
+		BaseURL:         "http://localhost:8080",
+		JobWorkers:      4,
+		ReminderOffsets: "24h,1h",
+		TraceExporter:   "none",
+		BackupDir:       "./backups",
+		BackupKeep:      7,
+		SMTP: SMTP{
+			Port: 587,
+			From: "events@localhost",
+		},
+		Payments: Payments{
+			Provider:      "fake",
+			WebhookSecret: "fake-webhook-secret",
+		},
+		OIDC: OIDC{
+			Scopes: "openid email profile",
+			Signup: true,
+		},
+	}
+}
+
+// Validate checks the settings, returning all problems at once.
+func (c *Config) Validate() error {
+	var errs []error
+	if !slices.Contains([]string{ModeDevelopment, ModeProduction}, c.Mode) {
+		errs = append(errs, fmt.Errorf("mode must be %s or %s, not %q", ModeDevelopment, ModeProduction, c.Mode))
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		errs = append(errs, fmt.Errorf("port %d is out of range", c.Port))
+	}
+	if c.MetricsAddr != "" {
+		if _, port, err := net.SplitHostPort(c.MetricsAddr); err != nil || port == "" {
+			errs = append(errs, fmt.Errorf("metricsAddr %q must be a host:port address", c.MetricsAddr))
+		}
+	}
+	if c.DatabasePath == "" {
+		errs = append(errs, errors.New("databasePath is required"))
+	}
+	if c.JWTSecret == "" {
+		errs = append(errs, errors.New("jwtSecret is required"))
+	} else if c.JWTSecret == DefaultJWTSecret && c.Mode != ModeDevelopment {
+		errs = append(errs, errors.New("jwtSecret must be changed from the default outside development mode"))
+	}
+	if u, err := url.Parse(c.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("baseURL %q must be an absolute http or https URL", c.BaseURL))
+	}
+	if c.JobWorkers < 1 {
+		errs = append(errs, errors.New("jobWorkers must be at least 1"))
+	}
+	if c.BackupDir == "" {
+		errs = append(errs, errors.New("backupDir is required"))
+	}
+	if c.BackupKeep < 1 {
+		errs = append(errs, errors.New("backupKeep must be at least 1"))
+	}
+	if !slices.Contains([]string{"otlp", "stdout", "none"}, c.TraceExporter) {
+		errs = append(errs, fmt.Errorf("traceExporter must be otlp, stdout or none, not %q", c.TraceExporter))
+	}
+	if c.SMTP.Host != "" && (c.SMTP.Port < 1 || c.SMTP.Port > 65535) {
+		errs = append(errs, fmt.Errorf("smtp.port %d is out of range", c.SMTP.Port))
+	}
+	switch c.Payments.Provider {
+	case "fake":
+		if c.Mode != ModeDevelopment {
+			errs = append(errs, errors.New("the fake payment provider is only allowed in development mode"))
+		}
+	case "stripe":
+		if c.Payments.StripeSecretKey == "" || c.Payments.StripeWebhookSecret == "" {
+			errs = append(errs, errors.New("payments.stripeSecretKey and payments.stripeWebhookSecret are required with the stripe provider"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("payments.provider must be stripe or fake, not %q", c.Payments.Provider))
+	}
+	if c.OIDC.Issuer != "" {
+		//plain http lets anyone on the way make up identities
+		if u, err := url.Parse(c.OIDC.Issuer); err != nil || u.Host == "" || (u.Scheme != "https" && (u.Scheme != "http" || c.Mode != ModeDevelopment)) {
+			errs = append(errs, fmt.Errorf("oidc.issuer %q must be an https URL, or http in development mode", c.OIDC.Issuer))
+		}
+		if c.OIDC.ClientID == "" {
+			errs = append(errs, errors.New("oidc.clientID is required with an oidc.issuer"))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// development returns the defaults, which are valid in development mode.
+func development() *Config {
+	return Default()
+}
+
+// production returns a valid production config.
+func production() *Config {
+	cfg := Default()
+	cfg.Mode = ModeProduction
+	cfg.JWTSecret = "a-real-secret"
+	cfg.BaseURL = "https://events.example.com"
+	cfg.Payments = Payments{Provider: "stripe", StripeSecretKey: "sk_live", StripeWebhookSecret: "whsec"}
+	return cfg
+}
+
+func TestDefaultIsDevelopment(t *testing.T) {
+	cfg := Default()
+	if cfg.Mode != ModeDevelopment {
+		t.Fatalf("mode = %q, want %q", cfg.Mode, ModeDevelopment)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("the defaults are invalid: %v", err)
+	}
+
+	//the default secret and payment provider are only for development
+	cfg.Mode = ModeProduction
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("the defaults are valid in production mode")
+	}
+	for _, want := range []string{"jwtSecret must be changed", "fake payment provider"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q doesn't mention %q", err, want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config func() *Config
+		// wantErr is part of the expected error, or empty for a valid config.
+		wantErr string
+	}{
+		{"development defaults", development, ""},
+		{"production", production, ""},
+		{"unknown mode", func() *Config { c := development(); c.Mode = "staging"; return c }, `mode must be development or production, not "staging"`},
+		{"port out of range", func() *Config { c := development(); c.Port = 70000; return c }, "port 70000 is out of range"},
+		{"metrics listener on localhost", func() *Config { c := development(); c.MetricsAddr = "localhost:9090"; return c }, ""},
+		{"metrics listener on all interfaces", func() *Config { c := development(); c.MetricsAddr = ":9090"; return c }, ""},
+		{"metrics listener without port", func() *Config { c := development(); c.MetricsAddr = "localhost"; return c }, `metricsAddr "localhost" must be a host:port address`},
+		{"no database", func() *Config { c := development(); c.DatabasePath = ""; return c }, "databasePath is required"},
+		{"no jwt secret", func() *Config { c := development(); c.JWTSecret = ""; return c }, "jwtSecret is required"},
+		{"default jwt secret in production", func() *Config { c := production(); c.JWTSecret = DefaultJWTSecret; return c }, "jwtSecret must be changed"},
+		{"relative base url", func() *Config { c := development(); c.BaseURL = "/events"; return c }, `baseURL "/events" must be an absolute http or https URL`},
+		{"no job workers", func() *Config { c := development(); c.JobWorkers = 0; return c }, "jobWorkers must be at least 1"},
+		{"no backup dir", func() *Config { c := development(); c.BackupDir = ""; return c }, "backupDir is required"},
+		{"no backups kept", func() *Config { c := development(); c.BackupKeep = 0; return c }, "backupKeep must be at least 1"},
+		{"unknown trace exporter", func() *Config { c := development(); c.TraceExporter = "jaeger"; return c }, `traceExporter must be otlp, stdout or none, not "jaeger"`},
+		{"smtp port out of range", func() *Config { c := development(); c.SMTP.Host = "smtp.example.com"; c.SMTP.Port = 0; return c }, "smtp.port 0 is out of range"},
+		{"fake payments in production", func() *Config { c := production(); c.Payments.Provider = "fake"; return c }, "fake payment provider is only allowed in development mode"},
+		{"stripe without keys", func() *Config { c := development(); c.Payments.Provider = "stripe"; return c }, "payments.stripeSecretKey and payments.stripeWebhookSecret are required"},
+		{"unknown payment provider", func() *Config { c := development(); c.Payments.Provider = "paypal"; return c }, `payments.provider must be stripe or fake, not "paypal"`},
+		{"oidc", func() *Config {
+			c := production()
+			c.OIDC.Issuer = "https://accounts.example.com"
+			c.OIDC.ClientID = "events"
+			return c
+		}, ""},
+		{"oidc over http in development", func() *Config {
+			c := development()
+			c.OIDC.Issuer = "http://localhost:9000"
+			c.OIDC.ClientID = "events"
+			return c
+		}, ""},
+		{"oidc over http in production", func() *Config {
+			c := production()
+			c.OIDC.Issuer = "http://accounts.example.com"
+			c.OIDC.ClientID = "events"
+			return c
+		}, "must be an https URL, or http in development mode"},
+		{"oidc without client id", func() *Config { c := production(); c.OIDC.Issuer = "https://accounts.example.com"; return c }, "oidc.clientID is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config().Validate()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("Validate() = %v, want no error", err)
+			case tt.wantErr != "" && err == nil:
+				t.Errorf("Validate() = nil, want an error containing %q", tt.wantErr)
+			case tt.wantErr != "" && !strings.Contains(err.Error(), tt.wantErr):
+				t.Errorf("Validate() = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateReportsAllProblems(t *testing.T) {
+	c := development()
+	c.Port = 0
+	c.DatabasePath = ""
+	c.JobWorkers = 0
+	err := c.Validate()
+	if err == nil {
+		t.Fatal("Validate() = nil, want errors")
+	}
+	if got := len(strings.Split(err.Error(), "\n")); got != 3 {
+		t.Errorf("Validate() reported %d problems, want 3: %v", got, err)
+	}
+}
+
+func TestLoadMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      string
+		args     []string
+		wantMode string
+	}{
+		{"default", "", nil, ModeDevelopment},
+		{"environment", "production", nil, ModeProduction},
+		{"flag", "", []string{"--mode", "production"}, ModeProduction},
+		{"flag over environment", "production", []string{"--mode", "development"}, ModeDevelopment},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.env != "" {
+				t.Setenv("APP_ENV", tt.env)
+			}
+			//--print-config skips validation, which production would fail
+			cfg, _, err := Load(append(tt.args, "--print-config"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Mode != tt.wantMode {
+				t.Errorf("mode = %q, want %q", cfg.Mode, tt.wantMode)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Load builds the config from the defaults, the config file, the
+// environment and the command line arguments, in that order, and validates
+// it. The config file is named by the --config flag or the CONFIG_FILE
+// variable and is read as TOML if its name ends in .toml and as YAML
+// otherwise. printConfig reports whether --print-config was given, in which
+// case the config isn't validated, so a broken one can be inspected.
+func Load(args []string) (cfg *Config, printConfig bool, err error) {
+	cfg = Default()
+
+	fs := flag.NewFlagSet(filepath.Base(os.Args[0]), flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML config file")
+	fs.BoolVar(&printConfig, "print-config", false, "print the effective config, with secrets redacted, and exit")
+	settings := map[string]*flagValue{}
+	for _, s := range settingsOf(cfg) {
+		value := &flagValue{isBool: s.value.Kind() == reflect.Bool}
+		settings[s.flag] = value
+		fs.Var(value, s.flag, s.usage+" ($"+s.env+")")
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	if *configFile != "" {
+		if err := cfg.readFile(*configFile); err != nil {
+			return nil, false, err
+		}
+	}
+	for _, s := range settingsOf(cfg) {
+		if value, ok := os.LookupEnv(s.env); ok {
+			if err := s.set(value); err != nil {
+				return nil, false, fmt.Errorf("$%s: %w", s.env, err)
+			}
+		}
+	}
+	var flagErr error
+	fs.Visit(func(f *flag.Flag) {
+		value, ok := settings[f.Name]
+		if !ok || flagErr != nil {
+			return
+		}
+		for _, s := range settingsOf(cfg) {
+			if s.flag == f.Name {
+				if err := s.set(value.value); err != nil {
+					flagErr = fmt.Errorf("--%s: %w", f.Name, err)
+				}
+			}
+		}
+	})
+	if flagErr != nil {
+		return nil, false, flagErr
+	}
+
+	if printConfig {
+		return cfg, true, nil
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, false, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, false, nil
+}
+
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(path) == ".toml" {
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	} else {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", path, err)
+	}
+	return nil
+}
+
+// YAML returns the config in the config file format, with secrets replaced
+// by "[redacted]".
+func (c *Config) YAML() ([]byte, error) {
+	redacted := *c
+	for _, s := range settingsOf(&redacted) {
+		if s.secret && s.value.Kind() == reflect.String && s.value.String() != "" {
+			s.value.SetString("[redacted]")
+		}
+	}
+	return yaml.Marshal(&redacted)
+}
+
+// setting is one field of a config, with its tags.
+type setting struct {
+	value  reflect.Value
+	env    string
+	flag   string
+	usage  string
+	secret bool
+}
+
+func (s setting) set(value string) error {
+	switch s.value.Kind() {
+	case reflect.Int:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		s.value.SetInt(int64(n))
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("%q is not true or false", value)
+		}
+		s.value.SetBool(b)
+	default:
+		s.value.SetString(value)
+	}
+	return nil
+}
+
+// settingsOf lists the settings of a config, including those of nested
+// sections.
+func settingsOf(c *Config) []setting {
+	var settings []setting
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if field.Type.Kind() == reflect.Struct {
+				walk(v.Field(i))
+				continue
+			}
+			settings = append(settings, setting{
+				value:  v.Field(i),
+				env:    field.Tag.Get("env"),
+				flag:   field.Tag.Get("flag"),
+				usage:  field.Tag.Get("usage"),
+				secret: field.Tag.Get("secret") == "true",
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem())
+	return settings
+}
+
+// flagValue holds a setting given on the command line until it is applied
+// over the file and the environment.
+type flagValue struct {
+	value  string
+	isBool bool
+}
+
+func (f *flagValue) String() string     { return f.value }
+func (f *flagValue) Set(v string) error { f.value = v; return nil }
+func (f *flagValue) IsBoolFlag() bool   { return f.isBool }
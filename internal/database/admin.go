@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+// Types of the targets of admin actions.
+const (
+	TargetUser   = "user"
+	TargetEvent  = "event"
+	TargetJob    = "job"
+	TargetBackup = "backup"
+)
+
+type AdminActionModel struct {
+	db *sql.DB
+}
+
+// AdminAction is an entry of the audit log of administrator actions.
+// AdminId is nil once the administrator's account is gone. TargetId is nil
+// for targets without an id, like backups, which are named in the details.
+type AdminAction struct {
+	Id         int            `json:"id"`
+	AdminId    *int           `json:"adminId"`
+	Action     string         `json:"action"`
+	TargetType string         `json:"targetType"`
+	TargetId   *int           `json:"targetId,omitempty"`
+	Details    map[string]any `json:"details"`
+	CreatedAt  time.Time      `json:"createdAt"`
+}
+
+func (m *AdminActionModel) Insert(action *AdminAction) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	return insertAdminAction(ctx, m.db, action)
+}
+
+// insertAdminAction records an action with q, which models pass the
+// transaction of the action itself so that neither is kept without the
+// other. A nil action records nothing.
+func insertAdminAction(ctx context.Context, q execer, action *AdminAction) error {
+	if action == nil {
+		return nil
+	}
+	if action.Details == nil {
+		action.Details = map[string]any{}
+	}
+	details, err := json.Marshal(action.Details)
+	if err != nil {
+		return err
+	}
+	action.CreatedAt = time.Now().UTC()
+	query := "insert into admin_actions (admin_id, action, target_type, target_id, details, created_at) values (?,?,?,?,?,?)"
+	result, err := q.ExecContext(ctx, query, action.AdminId, action.Action, action.TargetType, action.TargetId, string(details), action.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	action.Id = int(id)
+	return nil
+}
+
+// List returns a page of the audit log, newest first, optionally only the
+// actions on one target, with the total number of matching actions.
+func (m *AdminActionModel) List(targetType string, targetId, limit, offset int) ([]*AdminAction, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	where := " where (? = '' or target_type = ?) and (? = 0 or target_id = ?)"
+	args := []any{targetType, targetType, targetId, targetId}
+
+	var total int
+	if err := m.db.QueryRowContext(ctx, "select count(*) from admin_actions"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	query := "select id, admin_id, action, target_type, target_id, details, created_at from admin_actions" + where + " order by id desc limit ? offset ?"
+	rows, err := m.db.QueryContext(ctx, query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	actions := []*AdminAction{}
+	for rows.Next() {
+		var action AdminAction
+		var details string
+		if err := rows.Scan(&action.Id, &action.AdminId, &action.Action, &action.TargetType, &action.TargetId, &details, &action.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal([]byte(details), &action.Details); err != nil {
+			return nil, 0, err
+		}
+		actions = append(actions, &action)
+	}
+	return actions, total, rows.Err()
+}
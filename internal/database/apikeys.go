@@ -0,0 +1,198 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes of API keys. Each route that accepts API keys requires one of
+// them; the others, like managing keys or the account, need a login.
+const (
+	ScopeEventsRead     = "events:read"
+	ScopeEventsWrite    = "events:write"
+	ScopeAttendeesRead  = "attendees:read"
+	ScopeAttendeesWrite = "attendees:write"
+	ScopeOrdersRead     = "orders:read"
+	ScopeOrdersWrite    = "orders:write"
+	ScopeInvitesRead    = "invites:read"
+	ScopeInvitesWrite   = "invites:write"
+	ScopeWebhooksRead   = "webhooks:read"
+	ScopeWebhooksWrite  = "webhooks:write"
+	ScopeStatsRead      = "stats:read"
+)
+
+var Scopes = []string{
+	ScopeEventsRead, ScopeEventsWrite,
+	ScopeAttendeesRead, ScopeAttendeesWrite,
+	ScopeOrdersRead, ScopeOrdersWrite,
+	ScopeInvitesRead, ScopeInvitesWrite,
+	ScopeWebhooksRead, ScopeWebhooksWrite,
+	ScopeStatsRead,
+}
+
+// apiKeyTouchInterval is how out of date the last use of a key may get, so
+// busy keys don't write to the database on every request.
+const apiKeyTouchInterval = time.Minute
+
+type APIKeyModel struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+// APIKey lets a program act as its user within its scopes. Only a hash of
+// the key is stored; Prefix, its start, tells keys apart in listings.
+type APIKey struct {
+	Id         int        `json:"id"`
+	UserId     int        `json:"userId"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+}
+
+// Usable reports whether the key can still be used at the given time.
+func (k *APIKey) Usable(at time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || at.Before(*k.ExpiresAt)
+}
+
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+const apiKeyColumns = "id,user_id,name,prefix,scopes,created_at,expires_at,last_used_at,revoked_at"
+
+func scanAPIKey(row interface{ Scan(...any) error }) (*APIKey, error) {
+	var key APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&key.Id, &key.UserId, &key.Name, &key.Prefix, &scopes, &key.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	key.Scopes = []string{}
+	if scopes != "" {
+		key.Scopes = strings.Split(scopes, ",")
+	}
+	if expiresAt.Valid {
+		key.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		key.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		key.RevokedAt = &revokedAt.Time
+	}
+	return &key, nil
+}
+
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Insert stores a key that is used with the given secret.
+func (m *APIKeyModel) Insert(key *APIKey, secret string) error {
+	ctx, cancel := m.traced("Insert")
+	defer cancel()
+
+	key.CreatedAt = time.Now().UTC()
+	//stored times are compared as text, so they must all be in UTC
+	if key.ExpiresAt != nil {
+		expiresAt := key.ExpiresAt.UTC()
+		key.ExpiresAt = &expiresAt
+	}
+	query := "insert into api_keys (user_id, name, prefix, key_hash, scopes, created_at, expires_at) values (?,?,?,?,?,?,?)"
+	result, err := m.db.ExecContext(ctx, query, key.UserId, key.Name, key.Prefix, hashAPIKey(secret), strings.Join(key.Scopes, ","), key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	key.Id = int(id)
+	return nil
+}
+
+func (m *APIKeyModel) Get(id int) (*APIKey, error) {
+	ctx, cancel := m.traced("Get")
+	defer cancel()
+
+	key, err := scanAPIKey(m.db.QueryRowContext(ctx, "select "+apiKeyColumns+" from api_keys where id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetBySecret returns the key of a secret, or nil if there is none.
+func (m *APIKeyModel) GetBySecret(secret string) (*APIKey, error) {
+	ctx, cancel := m.traced("GetBySecret")
+	defer cancel()
+
+	key, err := scanAPIKey(m.db.QueryRowContext(ctx, "select "+apiKeyColumns+" from api_keys where key_hash = ?", hashAPIKey(secret)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return key, err
+}
+
+// GetByUser returns the keys of a user, revoked ones included, newest first.
+func (m *APIKeyModel) GetByUser(userId int) ([]*APIKey, error) {
+	ctx, cancel := m.traced("GetByUser")
+	defer cancel()
+
+	rows, err := m.db.QueryContext(ctx, "select "+apiKeyColumns+" from api_keys where user_id = ? order by id desc", userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []*APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Revoke stops a key from working. Revoking a revoked key keeps the
+// original time.
+func (m *APIKeyModel) Revoke(id int) error {
+	ctx, cancel := m.traced("Revoke")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update api_keys set revoked_at = coalesce(revoked_at, ?) where id = ?", time.Now().UTC(), id)
+	return err
+}
+
+// Touch records that a key was used at the given time, unless its last use
+// was recorded less than a minute before.
+func (m *APIKeyModel) Touch(key *APIKey, at time.Time) error {
+	at = at.UTC()
+	if key.LastUsedAt != nil && at.Sub(*key.LastUsedAt) < apiKeyTouchInterval {
+		return nil
+	}
+	ctx, cancel := m.traced("Touch")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update api_keys set last_used_at = ? where id = ?", at, key.Id)
+	if err == nil {
+		key.LastUsedAt = &at
+	}
+	return err
+}
@@ -3,59 +3,148 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	ErrEventFull              = errors.New("event is full")
+	ErrAlreadyAttending       = errors.New("user already attends the event")
+	ErrCapacityBelowAttendees = errors.New("capacity is lower than the number of attendees")
 )
 
 type AttendeeModel struct {
-	db *sql.DB
+	db  *sql.DB
+	ctx context.Context
 }
 
 type Attendee struct {
-	Id      int `json:"id"`
-	UserId  int `json:"userId"`
-	EventId int `json:"eventId"`
+	Id           int        `json:"id"`
+	UserId       int        `json:"userId"`
+	EventId      int        `json:"eventId"`
+	RegisteredAt *time.Time `json:"registeredAt,omitempty"`
+	CheckedInAt  *time.Time `json:"checkedInAt,omitempty"`
+}
+
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
 }
 
 func (m *AttendeeModel) Insert(attendee *Attendee) (*Attendee, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.traced("Insert")
 	defer cancel()
 
-	query := "insert into attendees (event_id,user_id) values (?,?)"
-	result, err := m.db.ExecContext(ctx, query, attendee.EventId, attendee.UserId)
+	if err := insertAttendeeWithinCapacity(ctx, m.db, attendee); err != nil {
+		return nil, err
+	}
+	return attendee, nil
+}
 
+// insertAttendee adds an attendee row, on its own or as part of a
+// transaction such as a ticket order. It returns ErrAlreadyAttending if the
+// user attends the event already.
+func insertAttendee(ctx context.Context, db execer, attendee *Attendee) error {
+	registeredAt := time.Now().UTC()
+	query := "insert into attendees (event_id,user_id,registered_at) values (?,?,?)"
+	result, err := db.ExecContext(ctx, query, attendee.EventId, attendee.UserId, registeredAt)
+
+	if isUniqueViolation(err) {
+		return ErrAlreadyAttending
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	attendee.Id = int(id)
-	return attendee, nil
+	attendee.RegisteredAt = &registeredAt
+	return nil
+}
+
+// insertAttendeeWithinCapacity adds an attendee row unless the event is
+// full, in which case it returns ErrEventFull, or the user attends it
+// already, in which case it returns ErrAlreadyAttending. The check and the
+// insert are one statement, so concurrent sign-ups can't overfill the event.
+func insertAttendeeWithinCapacity(ctx context.Context, db execer, attendee *Attendee) error {
+	registeredAt := time.Now().UTC()
+	query := `insert into attendees (event_id,user_id,registered_at) select ?,?,? from events e
+		where e.id = ? and (e.capacity is null or (select count(*) from attendees where event_id = e.id) < e.capacity)`
+	result, err := db.ExecContext(ctx, query, attendee.EventId, attendee.UserId, registeredAt, attendee.EventId)
+	if isUniqueViolation(err) {
+		return ErrAlreadyAttending
+	}
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrEventFull
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	attendee.Id = int(id)
+	attendee.RegisteredAt = &registeredAt
+	return nil
+}
+
+// isUniqueViolation reports whether err comes from a row breaking a unique
+// constraint, such as a user attending an event twice.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
 }
 
 func (m *AttendeeModel) GetByEventAndAttendee(eventid, userid int) (*Attendee, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.traced("GetByEventAndAttendee")
 	defer cancel()
 
-	query := "select * from attendees where event_id= ?  and user_id = ?"
+	query := "select id,user_id,event_id,registered_at,checked_in_at from attendees where event_id= ?  and user_id = ?"
 
 	var attendee Attendee
-	err := m.db.QueryRowContext(ctx, query, eventid, userid).Scan(&attendee.Id, &attendee.UserId, &attendee.EventId)
+	var registeredAt, checkedInAt sql.NullTime
+	err := m.db.QueryRowContext(ctx, query, eventid, userid).Scan(&attendee.Id, &attendee.UserId, &attendee.EventId, &registeredAt, &checkedInAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
 		return nil, err
 	}
+	if registeredAt.Valid {
+		attendee.RegisteredAt = &registeredAt.Time
+	}
+	if checkedInAt.Valid {
+		attendee.CheckedInAt = &checkedInAt.Time
+	}
 	return &attendee, nil
 }
 
+// CheckIn records the time an attendee arrived at the event. Checking in
+// twice keeps the first time.
+func (m *AttendeeModel) CheckIn(eventid, userid int) (*Attendee, error) {
+	ctx, cancel := m.traced("CheckIn")
+	defer cancel()
+
+	query := "update attendees set checked_in_at = ? where event_id = ? and user_id = ? and checked_in_at is null"
+	_, err := m.db.ExecContext(ctx, query, time.Now().UTC(), eventid, userid)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetByEventAndAttendee(eventid, userid)
+}
+
 func (m *AttendeeModel) GetAttendeesByEvent(eventid int) ([]*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.traced("GetAttendeesByEvent")
 	defer cancel()
-	query := "select u.id,u.name,u.email from users u JOIN attendees a ON u.id=a.user_id where a.event_id=? "
+	query := "select u.id,u.name,u.email,u.hide_from_attendee_lists from users u JOIN attendees a ON u.id=a.user_id where a.event_id=? "
 
 	rows, err := m.db.QueryContext(ctx, query, eventid)
 	if err != nil {
@@ -66,7 +155,7 @@ func (m *AttendeeModel) GetAttendeesByEvent(eventid int) ([]*User, error) {
 
 	for rows.Next() {
 		var user User
-		err := rows.Scan(&user.Id, &user.Name, &user.Email)
+		err := rows.Scan(&user.Id, &user.Name, &user.Email, &user.HideFromAttendeeLists)
 		if err != nil {
 			return nil, err
 		}
@@ -78,38 +167,132 @@ func (m *AttendeeModel) GetAttendeesByEvent(eventid int) ([]*User, error) {
 	return users, nil
 }
 
+// Attendee statuses shown in exports. Everyone on the list has RSVPed; the
+// status tells whether they have arrived yet.
+const (
+	AttendeeRegistered = "registered"
+	AttendeeCheckedIn  = "checked_in"
+)
+
+// AttendeeRecord is one row of an attendee export.
+type AttendeeRecord struct {
+	UserId       int
+	Name         string
+	Email        string
+	Status       string
+	RegisteredAt *time.Time
+	CheckedInAt  *time.Time
+}
+
+// exportTimeout bounds attendee exports, which stream rows to the client
+// while the query is open and so take longer than other queries.
+const exportTimeout = 5 * time.Minute
+
+// ExportByEvent calls fn with each attendee of an event, in the order they
+// registered, without loading the whole list into memory. It stops at the
+// first error fn returns.
+func (m *AttendeeModel) ExportByEvent(eventid int, fn func(*AttendeeRecord) error) error {
+	ctx, cancel := startQuery(m.ctx, "AttendeeModel.ExportByEvent", exportTimeout)
+	defer cancel()
+
+	query := `select u.id,u.name,u.email,a.registered_at,a.checked_in_at from attendees a JOIN users u ON u.id=a.user_id
+		where a.event_id=? order by a.id`
+	rows, err := m.db.QueryContext(ctx, query, eventid)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var record AttendeeRecord
+		var registeredAt, checkedInAt sql.NullTime
+		if err := rows.Scan(&record.UserId, &record.Name, &record.Email, &registeredAt, &checkedInAt); err != nil {
+			return err
+		}
+		record.Status = AttendeeRegistered
+		if registeredAt.Valid {
+			record.RegisteredAt = &registeredAt.Time
+		}
+		if checkedInAt.Valid {
+			record.CheckedInAt = &checkedInAt.Time
+			record.Status = AttendeeCheckedIn
+		}
+		if err := fn(&record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Delete removes an attendee from an event and cancels their ticket orders,
+// releasing the tickets. The cancellation is recorded for event statistics.
 func (m *AttendeeModel) Delete(userId, eventId int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.traced("Delete")
 	defer cancel()
 
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := "delete from attendees where user_id = ? and event_id = ?"
-	_, err := m.db.ExecContext(ctx, query, userId, eventId)
+	result, err := tx.ExecContext(ctx, query, userId, eventId)
 	if err != nil {
 		return err
 	}
-	return nil
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n > 0 {
+		query = "insert into attendee_cancellations (event_id, user_id, cancelled_at) values (?,?,?)"
+		if _, err := tx.ExecContext(ctx, query, eventId, userId, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+
+	query = "update orders set status = ? where user_id = ? and event_id = ? and status in (?, ?)"
+	_, err = tx.ExecContext(ctx, query, OrderCancelled, userId, eventId, OrderPending, OrderConfirmed)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (m *AttendeeModel) GetByAttendee(attendeeid int) ([]*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// GetByAttendee returns the events a user attends that viewerId, 0 for
+// anonymous callers, may know about. Users see all of their own events and
+// organizers their events. Everyone else only sees public events whose
+// attendee list would name the user to them, and none of a user hidden
+// from attendee lists.
+func (m *AttendeeModel) GetByAttendee(attendeeid, viewerId int) ([]*Event, error) {
+	ctx, cancel := m.traced("GetByAttendee")
 	defer cancel()
 
-	query := "Select e.id,e.owner_id,e.name,e.description,e.date,e.location from events e JOIN attendees a on e.id=a.event_id where a.user_id=?"
+	query := "Select " + eventColumns + ` from events e JOIN attendees a on e.id=a.event_id JOIN users u on u.id=a.user_id
+		where a.user_id=? and (a.user_id = ? or e.owner_id = ? or (? != 0 and e.visibility = 'public' and u.hide_from_attendee_lists = 0 and (
+			e.attendee_visibility = 'public' or
+			e.attendee_visibility = 'attendees' and exists (select 1 from attendees v where v.event_id = e.id and v.user_id = ?))))
+		order by e.id`
 
-	rows, err := m.db.QueryContext(ctx, query, attendeeid)
+	rows, err := m.db.QueryContext(ctx, query, attendeeid, viewerId, viewerId, viewerId, viewerId)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var events []*Event
+	events := []*Event{}
 	for rows.Next() {
 		var event Event
-		err := rows.Scan(&event.Id, &event.OwnerId, &event.Name, &event.Description, &event.Date, &event.Location)
+		err := scanEvent(rows, &event)
 		if err != nil {
 			return nil, err
 		}
 		events = append(events, &event)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if err = loadTags(ctx, m.db, events...); err != nil {
+		return nil, err
+	}
 	return events, nil
 }
@@ -4,29 +4,87 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
+	"unicode"
+)
+
+const (
+	VisibilityPublic   = "public"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPrivate  = "private"
+)
+
+// Who can see the names on an event's attendee list. Everyone else only gets
+// the number of attendees.
+const (
+	AttendeesPublic     = "public"
+	AttendeesAttendees  = "attendees"
+	AttendeesOrganizers = "organizers"
 )
 
 type EventModel struct {
-	db *sql.DB
+	db  *sql.DB
+	ctx context.Context
 }
 
 type Event struct {
-	Id          int    `json:"id"`
-	OwnerId     int    `json:"owner_id"`
-	Name        string `json:"name" binding:"required,min=3"`
-	Description string `json:"description" binding:"required,min=10"`
-	Date        string `json:"date" binding:"required"`
-	Location    string `json:"location" binding:"required,min=3"`
+	Id                 int      `json:"id"`
+	OwnerId            int      `json:"owner_id"`
+	Name               string   `json:"name" binding:"required,min=3"`
+	Description        string   `json:"description" binding:"required,min=10"`
+	Date               string   `json:"date" binding:"required"`
+	Location           string   `json:"location" binding:"required,min=3"`
+	Latitude           *float64 `json:"latitude,omitempty" binding:"required_with=Longitude,omitempty,min=-90,max=90"`
+	Longitude          *float64 `json:"longitude,omitempty" binding:"required_with=Latitude,omitempty,min=-180,max=180"`
+	Tags               []string `json:"tags" binding:"max=20,dive,required,max=50"`
+	Visibility         string   `json:"visibility" binding:"omitempty,oneof=public unlisted private"`
+	AttendeeVisibility string   `json:"attendeeVisibility" binding:"omitempty,oneof=public attendees organizers"`
+	// Capacity limits the number of attendees; nil means no limit. It is
+	// checked when users are added, accept invites or claim free tickets.
+	// Paid tickets are limited by their own quotas instead, but their
+	// attendees count towards the capacity.
+	Capacity *int `json:"capacity,omitempty" binding:"omitempty,min=1"`
+}
+
+// eventColumns lists the columns scanned by scanEvent, for queries that
+// alias the events table as e.
+const eventColumns = "e.id, e.owner_id, e.name, e.description, e.date, e.location, e.latitude, e.longitude, e.visibility, e.attendee_visibility, e.capacity"
+
+// scanEvent scans a row selected with eventColumns, followed by any extra
+// columns into extra.
+func scanEvent(row interface{ Scan(...any) error }, event *Event, extra ...any) error {
+	dest := []any{&event.Id, &event.OwnerId, &event.Name, &event.Description, &event.Date, &event.Location, &event.Latitude, &event.Longitude, &event.Visibility, &event.AttendeeVisibility, &event.Capacity}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// setDefaults fills in the settings left out of a create request. Updates
+// keep the current settings instead, see updateEvent.
+func (event *Event) setDefaults() {
+	if event.Visibility == "" {
+		event.Visibility = VisibilityPublic
+	}
+	if event.AttendeeVisibility == "" {
+		event.AttendeeVisibility = AttendeesPublic
+	}
 }
 
 func (e *EventModel) Insert(event *Event) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := e.traced("Insert")
 	defer cancel()
 
-	query := "INSERT INTO events (owner_id, name, description, date, location) VALUES (?,?,?,?,?)"
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
-	result, err := e.db.ExecContext(ctx, query, event.OwnerId, event.Name, event.Description, event.Date, event.Location)
+	event.setDefaults()
+	query := "INSERT INTO events (owner_id, name, description, date, location, latitude, longitude, visibility, attendee_visibility, capacity) VALUES (?,?,?,?,?,?,?,?,?,?)"
+
+	result, err := tx.ExecContext(ctx, query, event.OwnerId, event.Name, event.Description, event.Date, event.Location, event.Latitude, event.Longitude, event.Visibility, event.AttendeeVisibility, event.Capacity)
 	if err != nil {
 		return err
 	}
@@ -36,75 +94,408 @@ func (e *EventModel) Insert(event *Event) error {
 		return err
 	}
 
+	event.Tags = NormalizeTags(event.Tags)
+	if err := setEventTags(ctx, tx, int(id), event.Tags); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
 	event.Id = int(id)
 	return nil
 }
 
-func (m *EventModel) GetAll() ([]Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// GetAll returns all public events. Unlisted and private events are only
+// reachable by id.
+func (m *EventModel) GetAll() ([]*Event, error) {
+	ctx, cancel := m.traced("GetAll")
 
 	defer cancel()
 
-	query := "SELECT * from events"
+	query := "SELECT " + eventColumns + " from events e where e.visibility = 'public'"
+	return m.query(ctx, query)
+}
 
-	rows, err := m.db.QueryContext(ctx, query)
+// GetByTags returns the public events carrying any of the given tags, or all
+// of them when matchAll is set.
+func (m *EventModel) GetByTags(tags []string, matchAll bool) ([]*Event, error) {
+	ctx, cancel := m.traced("GetByTags")
+	defer cancel()
+
+	tags = NormalizeTags(tags)
+	if len(tags) == 0 {
+		return []*Event{}, nil
+	}
+	args := make([]any, len(tags))
+	for i, tag := range tags {
+		args[i] = tag
+	}
+
+	query := "SELECT " + eventColumns + ` from events e where e.visibility = 'public' and e.id in (
+		select et.event_id from event_tags et join tags t on t.id = et.tag_id
+		where t.slug in (?` + strings.Repeat(",?", len(tags)-1) + `)
+		group by et.event_id`
+	if matchAll {
+		query += " having count(*) = ?"
+		args = append(args, len(tags))
+	}
+	query += ")"
+	return m.query(ctx, query, args...)
+}
+
+// GetByOwner returns all events of a user, whatever their visibility, in
+// the order they were created.
+func (m *EventModel) GetByOwner(ownerId int) ([]*Event, error) {
+	ctx, cancel := m.traced("GetByOwner")
+	defer cancel()
+
+	query := "select " + eventColumns + " from events e where e.owner_id = ? order by e.id"
+	return m.query(ctx, query, ownerId)
+}
+
+func (m *EventModel) query(ctx context.Context, query string, args ...any) ([]*Event, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var events []Event
+	events := []*Event{}
 
 	for rows.Next() {
 		var event Event
-		err := rows.Scan(&event.Id, &event.OwnerId, &event.Name, &event.Description, &event.Date, &event.Location)
+		err := scanEvent(rows, &event)
 		if err != nil {
 			return nil, err
 		}
-		events = append(events, event)
+		events = append(events, &event)
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	if err = loadTags(ctx, m.db, events...); err != nil {
+		return nil, err
+	}
 	return events, nil
 }
 
 func (m *EventModel) Get(id int) (*Event, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := m.traced("Get")
 	defer cancel()
 
-	query := "Select * from events where id=?"
+	query := "Select " + eventColumns + " from events e where e.id=?"
 	var event Event
 
-	err := m.db.QueryRowContext(ctx, query, id).Scan(&event.Id, &event.OwnerId, &event.Name, &event.Description, &event.Date, &event.Location)
+	err := scanEvent(m.db.QueryRowContext(ctx, query, id), &event)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("no event found with id %s", fmt.Sprint(id))
 		}
 		return nil, err
 	}
+	if err := loadTags(ctx, m.db, &event); err != nil {
+		return nil, err
+	}
 	return &event, nil
 
 }
 
+// eventTimeLayout is the format of SQLite's datetime(), which normalizes the
+// dates events are stored with. Dates without a time zone are taken as UTC.
+const eventTimeLayout = "2006-01-02 15:04:05"
+
+// UpcomingEvent is an event with its parsed start time.
+type UpcomingEvent struct {
+	*Event
+	StartsAt time.Time `json:"startsAt"`
+}
+
+// GetStartingBetween returns the events starting after from and no later
+// than to, soonest first. Events whose date isn't a valid time are skipped.
+func (m *EventModel) GetStartingBetween(from, to time.Time) ([]*UpcomingEvent, error) {
+	ctx, cancel := m.traced("GetStartingBetween")
+	defer cancel()
+
+	query := "select " + eventColumns + ", datetime(e.date) as starts_at from events e where datetime(e.date) > ? and datetime(e.date) <= ? order by starts_at"
+	rows, err := m.db.QueryContext(ctx, query, from.UTC().Format(eventTimeLayout), to.UTC().Format(eventTimeLayout))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	upcoming := []*UpcomingEvent{}
+	for rows.Next() {
+		var event Event
+		var startsAt string
+		if err := scanEvent(rows, &event, &startsAt); err != nil {
+			return nil, err
+		}
+		t, err := time.Parse(eventTimeLayout, startsAt)
+		if err != nil {
+			return nil, err
+		}
+		upcoming = append(upcoming, &UpcomingEvent{Event: &event, StartsAt: t})
+	}
+	return upcoming, rows.Err()
+}
+
+// StartsAt returns the start time of an event, or nil if the event doesn't
+// exist or its date isn't a valid time.
+func (m *EventModel) StartsAt(id int) (*time.Time, error) {
+	ctx, cancel := m.traced("StartsAt")
+	defer cancel()
+
+	var startsAt sql.NullString
+	err := m.db.QueryRowContext(ctx, "select datetime(date) from events where id = ?", id).Scan(&startsAt)
+	if err == sql.ErrNoRows || (err == nil && !startsAt.Valid) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	t, err := time.Parse(eventTimeLayout, startsAt.String)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Update stores an event. The capacity can't be lowered below the number of
+// attendees, which is checked by the update itself like attendees are
+// checked against the capacity.
 func (e *EventModel) Update(event *Event) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := e.traced("Update")
 	defer cancel()
 
-	query := "update events set name=$1,description=$2,date=$3, location=$4 where id=$5"
-	_, err := e.db.ExecContext(ctx, query, event.Name, event.Description, event.Date, event.Location, event.Id)
+	tx, err := e.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
+	defer tx.Rollback()
+
+	query := `update events set name=$1,description=$2,date=$3, location=$4, latitude=$5, longitude=$6, visibility=$7, attendee_visibility=$8, capacity=$9
+		where id=$10 and ($9 is null or (select count(*) from attendees where event_id = $10) <= $9)`
+	result, err := tx.ExecContext(ctx, query, event.Name, event.Description, event.Date, event.Location, event.Latitude, event.Longitude, event.Visibility, event.AttendeeVisibility, event.Capacity, event.Id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrCapacityBelowAttendees
+	}
+
+	event.Tags = NormalizeTags(event.Tags)
+	if err := setEventTags(ctx, tx, event.Id, event.Tags); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetOwner hands an event over to another user. Webhooks the previous owner
+// registered for the event alone are deleted, so they stop hearing about it.
+// audit, if given, is recorded in the same transaction.
+func (e *EventModel) SetOwner(id, ownerId int, audit *AdminAction) error {
+	ctx, cancel := e.traced("SetOwner")
+	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var previousOwnerId int
+	if err := tx.QueryRowContext(ctx, "select owner_id from events where id = ?", id).Scan(&previousOwnerId); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "update events set owner_id = ? where id = ?", ownerId, id); err != nil {
+		return err
+	}
+	if previousOwnerId != ownerId {
+		if _, err := tx.ExecContext(ctx, "delete from webhooks where event_id = ? and owner_id = ?", id, previousOwnerId); err != nil {
+			return err
+		}
+	}
+	if err := insertAdminAction(ctx, tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
 }
 
-func (e *EventModel) Delete(id int) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+// Delete deletes an event, recording audit, if given, in the same
+// transaction.
+func (e *EventModel) Delete(id int, audit *AdminAction) error {
+	ctx, cancel := e.traced("Delete")
 	defer cancel()
+
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	query := "Delete from events where id=$1"
-	_, err := e.db.ExecContext(ctx, query, id)
+	_, err = tx.ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
-	return nil
+	//the cascades only run on connections with foreign keys enabled, so the
+	//event's webhooks and their deliveries are deleted explicitly
+	_, err = tx.ExecContext(ctx, "delete from webhook_deliveries where webhook_id in (select id from webhooks where event_id=$1)", id)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "delete from webhooks where event_id=$1", id)
+	if err != nil {
+		return err
+	}
+	if err := insertAdminAction(ctx, tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+type EventSearchResult struct {
+	Event
+	Score     float64 `json:"score"`
+	Highlight struct {
+		Name     string `json:"name"`
+		Location string `json:"location"`
+	} `json:"highlight"`
+	Snippet string `json:"snippet"`
+}
+
+// searchWords splits free text into the words a search matches.
+func searchWords(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchQuery turns free text into an FTS5 query matching events that
+// contain every word, each as a prefix. It returns an empty string when the
+// text contains no searchable words.
+func SearchQuery(text string) string {
+	words := searchWords(text)
+	for i, word := range words {
+		words[i] = `"` + word + `"*`
+	}
+	return strings.Join(words, " ")
+}
+
+// scanSearchResults scans the rows of a search, selected with eventColumns
+// followed by the score, the highlights and the snippet, and loads the tags
+// of the events found.
+func scanSearchResults(ctx context.Context, db *sql.DB, rows *sql.Rows) ([]*EventSearchResult, error) {
+	defer rows.Close()
+
+	results := []*EventSearchResult{}
+	for rows.Next() {
+		var result EventSearchResult
+		err := scanEvent(rows, &result.Event, &result.Score, &result.Highlight.Name, &result.Highlight.Location, &result.Snippet)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	events := make([]*Event, len(results))
+	for i, result := range results {
+		events[i] = &result.Event
+	}
+	if err := loadTags(ctx, db, events...); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+const earthRadiusKm = 6371.0
+
+type EventDistance struct {
+	Event
+	DistanceKm float64 `json:"distanceKm"`
+}
+
+// Distance returns the great-circle distance in kilometres between two
+// coordinates using the haversine formula.
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// Nearby returns the public events within radiusKm of a point, closest first. A
+// bounding box around the point narrows the candidates in SQL before the
+// exact distances are computed.
+func (m *EventModel) Nearby(lat, lng, radiusKm float64) ([]*EventDistance, error) {
+	ctx, cancel := m.traced("Nearby")
+	defer cancel()
+
+	latDelta := radiusKm / (math.Pi * earthRadiusKm / 180)
+	minLat, maxLat := lat-latDelta, lat+latDelta
+
+	query := "select " + eventColumns + " from events e where e.visibility = 'public' and e.latitude between ? and ? and e.longitude is not null"
+	args := []any{minLat, maxLat}
+
+	//the longitude range is unbounded near the poles and split at the antimeridian
+	if maxLat < 90 && minLat > -90 {
+		lngDelta := latDelta / math.Cos(lat*math.Pi/180)
+		minLng, maxLng := lng-lngDelta, lng+lngDelta
+		switch {
+		case lngDelta >= 180:
+		case minLng < -180:
+			query += " and (e.longitude >= ? or e.longitude <= ?)"
+			args = append(args, minLng+360, maxLng)
+		case maxLng > 180:
+			query += " and (e.longitude >= ? or e.longitude <= ?)"
+			args = append(args, minLng, maxLng-360)
+		default:
+			query += " and e.longitude between ? and ?"
+			args = append(args, minLng, maxLng)
+		}
+	}
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*EventDistance{}
+	for rows.Next() {
+		var event EventDistance
+		if err := scanEvent(rows, &event.Event); err != nil {
+			return nil, err
+		}
+		//the query only matches located events, but a half-set pair mustn't panic
+		if event.Latitude == nil || event.Longitude == nil {
+			continue
+		}
+		event.DistanceKm = Distance(lat, lng, *event.Latitude, *event.Longitude)
+		if event.DistanceKm <= radiusKm {
+			events = append(events, &event)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].DistanceKm < events[j].DistanceKm
+	})
+
+	tagged := make([]*Event, len(events))
+	for i, event := range events {
+		tagged[i] = &event.Event
+	}
+	if err = loadTags(ctx, m.db, tagged...); err != nil {
+		return nil, err
+	}
+	return events, nil
 }
@@ -0,0 +1,87 @@
+package database
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lng1, lat2, lng2 float64
+		wantKm                 float64
+	}{
+		{"same point", 52.52, 13.405, 52.52, 13.405, 0},
+		{"Berlin to Munich", 52.52, 13.405, 48.1351, 11.582, 504.415},
+		{"Paris to London", 48.8566, 2.3522, 51.5074, -0.1278, 343.556},
+		{"across the antimeridian", 0, 179.5, 0, -179.5, 111.195},
+		{"across the pole", 89.5, 0, 89.5, 180, 111.195},
+		{"antipodes", 0, 0, 0, 180, 20015.087},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Distance(tt.lat1, tt.lng1, tt.lat2, tt.lng2)
+			if math.Abs(got-tt.wantKm) > 0.001 {
+				t.Errorf("Distance() = %.3f km, want %.3f km", got, tt.wantKm)
+			}
+			if back := Distance(tt.lat2, tt.lng2, tt.lat1, tt.lng1); math.Abs(back-got) > 1e-9 {
+				t.Errorf("Distance() is %.3f km one way and %.3f km back", got, back)
+			}
+		})
+	}
+}
+
+func TestNearby(t *testing.T) {
+	_, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	located := func(name string, lat, lng float64) *Event {
+		return &Event{Name: name, Description: "An event somewhere", Location: name, Latitude: &lat, Longitude: &lng}
+	}
+	private := located("Private Potsdam", 52.3906, 13.0645)
+	private.Visibility = VisibilityPrivate
+	insertTestEvents(t, models, owner,
+		located("Berlin", 52.52, 13.405),
+		located("Potsdam", 52.3906, 13.0645),
+		located("Munich", 48.1351, 11.582),
+		located("Fiji east", -17.7, 179.9),
+		located("Fiji west", -17.7, -179.9),
+		located("North pole", 89.9, 0),
+		located("Near the pole", 89.8, 170),
+		private,
+		&Event{Name: "Nowhere", Description: "An event without coordinates", Location: "Unknown"},
+	)
+
+	tests := []struct {
+		name     string
+		lat, lng float64
+		radiusKm float64
+		want     []string
+	}{
+		{"closest first", 52.52, 13.405, 50, []string{"Berlin", "Potsdam"}},
+		{"wider radius", 52.52, 13.405, 600, []string{"Berlin", "Potsdam", "Munich"}},
+		{"box corner outside the circle", 52.0, 12.6, 70, []string{"Potsdam"}},
+		{"across the antimeridian from the east", -17.7, 179.95, 30, []string{"Fiji east", "Fiji west"}},
+		{"across the antimeridian from the west", -17.7, -179.95, 30, []string{"Fiji west", "Fiji east"}},
+		{"near a pole every longitude is a candidate", 89.85, -90, 50, []string{"North pole", "Near the pole"}},
+		{"nothing around", 0, 0, 100, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := models.Events.Nearby(tt.lat, tt.lng, tt.radiusKm)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, event := range events {
+				got = append(got, event.Name)
+				if event.DistanceKm > tt.radiusKm {
+					t.Errorf("%s is %.1f km away, outside the radius", event.Name, event.DistanceKm)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Nearby() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// OIDCLoginTTL is how long a user has to sign in at the identity provider
+// after starting a login.
+const OIDCLoginTTL = 10 * time.Minute
+
+// ErrIdentityLinked is returned when an identity at a provider already
+// belongs to a user.
+var ErrIdentityLinked = errors.New("identity is already linked to a user")
+
+type IdentityModel struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+// Identity links a user to an account at an OpenID Connect provider, which
+// is named by its issuer and identifies the account by subject.
+type Identity struct {
+	Id          int        `json:"id"`
+	UserId      int        `json:"userId"`
+	Provider    string     `json:"provider"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	LastLoginAt *time.Time `json:"lastLoginAt,omitempty"`
+}
+
+// Get returns the identity of a subject at a provider, or nil if it isn't
+// linked to any user.
+func (m *IdentityModel) Get(provider, subject string) (*Identity, error) {
+	ctx, cancel := m.traced("Get")
+	defer cancel()
+
+	var identity Identity
+	var email sql.NullString
+	var lastLoginAt sql.NullTime
+	query := "select id, user_id, provider, subject, email, created_at, last_login_at from user_identities where provider = ? and subject = ?"
+	err := m.db.QueryRowContext(ctx, query, provider, subject).Scan(&identity.Id, &identity.UserId, &identity.Provider, &identity.Subject, &email, &identity.CreatedAt, &lastLoginAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	identity.Email = email.String
+	if lastLoginAt.Valid {
+		identity.LastLoginAt = &lastLoginAt.Time
+	}
+	return &identity, nil
+}
+
+// Insert links an identity to an existing user.
+func (m *IdentityModel) Insert(identity *Identity) error {
+	ctx, cancel := m.traced("Insert")
+	defer cancel()
+
+	return insertIdentity(ctx, m.db, identity)
+}
+
+// InsertWithUser creates a user for an identity, which is linked to them.
+// The user has no password of their own until they set one.
+func (m *IdentityModel) InsertWithUser(user *User, identity *Identity) error {
+	ctx, cancel := m.traced("InsertWithUser")
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := "insert into users (email, password, name, hide_from_attendee_lists, email_reminders, has_password) values (?,?,?,?,?,0)"
+	result, err := tx.ExecContext(ctx, query, user.Email, user.Password, user.Name, user.HideFromAttendeeLists, user.EmailReminders)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	user.Id = int(id)
+	user.HasPassword = false
+	identity.UserId = user.Id
+	if err := insertIdentity(ctx, tx, identity); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func insertIdentity(ctx context.Context, db execer, identity *Identity) error {
+	identity.CreatedAt = time.Now().UTC()
+	identity.LastLoginAt = &identity.CreatedAt
+	query := "insert into user_identities (user_id, provider, subject, email, created_at, last_login_at) values (?,?,?,?,?,?)"
+	result, err := db.ExecContext(ctx, query, identity.UserId, identity.Provider, identity.Subject, identity.Email, identity.CreatedAt, identity.LastLoginAt)
+	if isUniqueViolation(err) {
+		return ErrIdentityLinked
+	}
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	identity.Id = int(id)
+	return nil
+}
+
+// RecordLogin notes that the identity was used to log in, with the email
+// address the provider has for it now.
+func (m *IdentityModel) RecordLogin(id int, email string) error {
+	ctx, cancel := m.traced("RecordLogin")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update user_identities set email = ?, last_login_at = ? where id = ?", email, time.Now().UTC(), id)
+	return err
+}
+
+type OIDCLoginModel struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+// OIDCLogin is a login started at an identity provider, to be finished
+// when the provider sends the user back with the state.
+type OIDCLogin struct {
+	State     string
+	Nonce     string
+	Verifier  string
+	CreatedAt time.Time
+}
+
+// Insert stores a login, clearing out the logins that were abandoned.
+func (m *OIDCLoginModel) Insert(login *OIDCLogin) error {
+	ctx, cancel := m.traced("Insert")
+	defer cancel()
+
+	login.CreatedAt = time.Now().UTC()
+	if _, err := m.db.ExecContext(ctx, "delete from oidc_logins where created_at < ?", login.CreatedAt.Add(-OIDCLoginTTL)); err != nil {
+		return err
+	}
+	query := "insert into oidc_logins (state, nonce, verifier, created_at) values (?,?,?,?)"
+	_, err := m.db.ExecContext(ctx, query, login.State, login.Nonce, login.Verifier, login.CreatedAt)
+	return err
+}
+
+// Take removes the login of a state and returns it, or nil if there is
+// none or it expired. Each state is only good for one login.
+func (m *OIDCLoginModel) Take(state string) (*OIDCLogin, error) {
+	ctx, cancel := m.traced("Take")
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var login OIDCLogin
+	query := "select state, nonce, verifier, created_at from oidc_logins where state = ?"
+	err = tx.QueryRowContext(ctx, query, state).Scan(&login.State, &login.Nonce, &login.Verifier, &login.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	result, err := tx.ExecContext(ctx, "delete from oidc_logins where state = ?", state)
+	if err != nil {
+		return nil, err
+	}
+	//a concurrent callback with the same state may have taken it first
+	if n, err := result.RowsAffected(); err != nil || n != 1 {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if time.Since(login.CreatedAt) > OIDCLoginTTL {
+		return nil, nil
+	}
+	return &login, nil
+}
@@ -0,0 +1,176 @@
+package database
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
+)
+
+// Outcomes of the rows of an attendee import.
+const (
+	ImportAdded            = "added"
+	ImportInvited          = "invited"
+	ImportAlreadyAttending = "already_attending"
+	ImportAlreadyInvited   = "already_invited"
+	ImportDuplicate        = "duplicate"
+	ImportOverCapacity     = "over_capacity"
+)
+
+// importTimeout bounds an import, which runs one transaction over the whole
+// file.
+const importTimeout = 30 * time.Second
+
+// ImportRow is a person to add to an event, from line Line of an import file.
+type ImportRow struct {
+	Line  int
+	Email string
+	Name  string
+}
+
+// ImportResult is the outcome of one import row. Dry runs don't store
+// anything, so their new attendees and invites have no ids.
+type ImportResult struct {
+	Line     int       `json:"line"`
+	Email    string    `json:"email"`
+	Name     string    `json:"name,omitempty"`
+	Status   string    `json:"status"`
+	UserId   int       `json:"userId,omitempty"`
+	Attendee *Attendee `json:"attendee,omitempty"`
+	InviteId int       `json:"inviteId,omitempty"`
+}
+
+// ImportAttendees adds the users with the emails of the rows as attendees of
+// an event and invites the emails that don't belong to a user yet. Emails
+// are matched case insensitively; emails already attending or invited, and
+// repeated rows, are skipped. The rows are imported in one transaction: if
+// they don't all fit in the event, nothing is imported and ErrEventFull is
+// returned along with the results. A dry run reports the results without
+// storing anything.
+//
+// New invites are single use and bound to their email. Their tokens are
+// never shown; send them with a fresh token from SetToken.
+func (m *AttendeeModel) ImportAttendees(eventId, createdBy int, rows []ImportRow, dryRun bool) ([]*ImportResult, error) {
+	ctx, cancel := startQuery(m.ctx, "AttendeeModel.ImportAttendees", importTimeout)
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var capacity sql.NullInt64
+	var count int64
+	query := "select capacity, (select count(*) from attendees where event_id = e.id) from events e where e.id = ?"
+	if err := tx.QueryRowContext(ctx, query, eventId).Scan(&capacity, &count); err != nil {
+		return nil, err
+	}
+
+	full := false
+	seen := map[string]bool{}
+	results := make([]*ImportResult, 0, len(rows))
+	for _, row := range rows {
+		email := strings.ToLower(row.Email)
+		result := &ImportResult{Line: row.Line, Email: row.Email, Name: row.Name}
+		results = append(results, result)
+		if seen[email] {
+			result.Status = ImportDuplicate
+			continue
+		}
+		seen[email] = true
+
+		var userId int
+		err := tx.QueryRowContext(ctx, "select id from users where lower(email) = ? order by id limit 1", email).Scan(&userId)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+		if err == sql.ErrNoRows {
+			if err := importInvite(ctx, tx, eventId, createdBy, row.Email, result, dryRun); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		result.UserId = userId
+		var attending bool
+		err = tx.QueryRowContext(ctx, "select exists(select 1 from attendees where event_id = ? and user_id = ?)", eventId, userId).Scan(&attending)
+		if err != nil {
+			return nil, err
+		}
+		if attending {
+			result.Status = ImportAlreadyAttending
+			continue
+		}
+		if capacity.Valid && count >= capacity.Int64 {
+			result.Status = ImportOverCapacity
+			full = true
+			continue
+		}
+		count++
+		result.Status = ImportAdded
+		if dryRun {
+			continue
+		}
+		attendee := &Attendee{EventId: eventId, UserId: userId}
+		if err := insertAttendeeWithinCapacity(ctx, tx, attendee); err != nil {
+			if errors.Is(err, ErrEventFull) {
+				result.Status = ImportOverCapacity
+				full = true
+				continue
+			}
+			return nil, err
+		}
+		result.Attendee = attendee
+	}
+
+	if full {
+		//the rows weren't stored after all
+		for _, result := range results {
+			result.Attendee = nil
+			if result.Status == ImportInvited {
+				result.InviteId = 0
+			}
+		}
+		return results, ErrEventFull
+	}
+	if dryRun {
+		return results, nil
+	}
+	return results, tx.Commit()
+}
+
+// importInvite invites an email that doesn't belong to a user, unless it
+// already has a usable invite to the event.
+func importInvite(ctx context.Context, tx *sql.Tx, eventId, createdBy int, email string, result *ImportResult, dryRun bool) error {
+	query := `select id from invites where event_id = ? and lower(email) = ? and revoked = 0
+		and (expires_at is null or expires_at > ?) and (max_uses is null or uses < max_uses) order by id limit 1`
+	err := tx.QueryRowContext(ctx, query, eventId, strings.ToLower(email), time.Now().UTC()).Scan(&result.InviteId)
+	if err == nil {
+		result.Status = ImportAlreadyInvited
+		return nil
+	}
+	if err != sql.ErrNoRows {
+		return err
+	}
+
+	result.Status = ImportInvited
+	if dryRun {
+		return nil
+	}
+	//the token is thrown away; the invite email is sent with a new one
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return err
+	}
+	maxUses := 1
+	invite := &Invite{EventId: eventId, Email: &email, MaxUses: &maxUses, CreatedBy: createdBy}
+	if err := insertInvite(ctx, tx, invite, base64.RawURLEncoding.EncodeToString(buf)); err != nil {
+		return err
+	}
+	result.InviteId = invite.Id
+	return nil
+}
@@ -0,0 +1,226 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInviteInvalid  = errors.New("invite is invalid, expired or used up")
+	ErrTicketRequired = errors.New("event sells paid tickets")
+)
+
+type InviteModel struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+// Invite lets its holder RSVP to an event, including private ones. Only a
+// hash of the token is stored, so the link can't be recovered later.
+type Invite struct {
+	Id        int        `json:"id"`
+	EventId   int        `json:"eventId"`
+	Email     *string    `json:"email,omitempty"`
+	MaxUses   *int       `json:"maxUses,omitempty"`
+	Uses      int        `json:"uses"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	Revoked   bool       `json:"revoked"`
+	CreatedBy int        `json:"createdBy"`
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// For reports whether the invite may be used by user: anyone holding the
+// token, unless it is bound to an email address, which the user must have.
+func (i *Invite) For(user *User) bool {
+	return i.Email == nil || user.Id != 0 && strings.EqualFold(*i.Email, user.Email)
+}
+
+// Usable reports whether the invite can still be redeemed at the given time.
+func (i *Invite) Usable(at time.Time) bool {
+	if i.Revoked {
+		return false
+	}
+	if i.ExpiresAt != nil && !at.Before(*i.ExpiresAt) {
+		return false
+	}
+	return i.MaxUses == nil || i.Uses < *i.MaxUses
+}
+
+const inviteColumns = "id,event_id,email,max_uses,uses,expires_at,revoked,created_by,created_at"
+
+func scanInvite(row interface{ Scan(...any) error }) (*Invite, error) {
+	var invite Invite
+	var email sql.NullString
+	var maxUses sql.NullInt64
+	var expiresAt sql.NullTime
+	err := row.Scan(&invite.Id, &invite.EventId, &email, &maxUses, &invite.Uses, &expiresAt, &invite.Revoked, &invite.CreatedBy, &invite.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if email.Valid {
+		invite.Email = &email.String
+	}
+	if maxUses.Valid {
+		n := int(maxUses.Int64)
+		invite.MaxUses = &n
+	}
+	if expiresAt.Valid {
+		invite.ExpiresAt = &expiresAt.Time
+	}
+	return &invite, nil
+}
+
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Insert stores an invite that is redeemed with the given token.
+func (m *InviteModel) Insert(invite *Invite, token string) error {
+	ctx, cancel := m.traced("Insert")
+	defer cancel()
+
+	return insertInvite(ctx, m.db, invite, token)
+}
+
+// insertInvite adds an invite row, on its own or as part of a transaction
+// such as an attendee import.
+func insertInvite(ctx context.Context, db execer, invite *Invite, token string) error {
+	invite.CreatedAt = time.Now().UTC()
+	//stored times are compared as text, so they must all be in UTC
+	if invite.ExpiresAt != nil {
+		expiresAt := invite.ExpiresAt.UTC()
+		invite.ExpiresAt = &expiresAt
+	}
+	query := "insert into invites (event_id, token_hash, email, max_uses, expires_at, created_by, created_at) values (?,?,?,?,?,?,?)"
+	result, err := db.ExecContext(ctx, query, invite.EventId, hashInviteToken(token), invite.Email, invite.MaxUses, invite.ExpiresAt, invite.CreatedBy, invite.CreatedAt)
+	if err != nil {
+		return err
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	invite.Id = int(id)
+	return nil
+}
+
+func (m *InviteModel) Get(id int) (*Invite, error) {
+	ctx, cancel := m.traced("Get")
+	defer cancel()
+
+	invite, err := scanInvite(m.db.QueryRowContext(ctx, "select "+inviteColumns+" from invites where id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return invite, err
+}
+
+// GetByToken returns the invite of a token, or nil if there is none.
+func (m *InviteModel) GetByToken(token string) (*Invite, error) {
+	ctx, cancel := m.traced("GetByToken")
+	defer cancel()
+
+	invite, err := scanInvite(m.db.QueryRowContext(ctx, "select "+inviteColumns+" from invites where token_hash = ?", hashInviteToken(token)))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return invite, err
+}
+
+func (m *InviteModel) GetByEvent(eventId int) ([]*Invite, error) {
+	ctx, cancel := m.traced("GetByEvent")
+	defer cancel()
+
+	rows, err := m.db.QueryContext(ctx, "select "+inviteColumns+" from invites where event_id = ? order by id desc", eventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	invites := []*Invite{}
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+		invites = append(invites, invite)
+	}
+	return invites, rows.Err()
+}
+
+// SetToken replaces the token of an invite, invalidating links sent before.
+func (m *InviteModel) SetToken(id int, token string) error {
+	ctx, cancel := m.traced("SetToken")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update invites set token_hash = ? where id = ?", hashInviteToken(token), id)
+	return err
+}
+
+func (m *InviteModel) Revoke(id int) error {
+	ctx, cancel := m.traced("Revoke")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update invites set revoked = 1 where id = ?", id)
+	return err
+}
+
+func (m *InviteModel) Delete(id int) error {
+	ctx, cancel := m.traced("Delete")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "delete from invites where id = ?", id)
+	return err
+}
+
+// Redeem uses up one use of the invite and registers the user as an
+// attendee of its event. Users who already attend, or find the event full,
+// keep the use. Events selling paid tickets return ErrTicketRequired, since
+// their attendees register by paying for a ticket.
+func (m *InviteModel) Redeem(token string, userId int) (*Attendee, error) {
+	ctx, cancel := m.traced("Redeem")
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//the conditional update claims a use atomically, like ticket orders do
+	query := `update invites set uses = uses + 1 where token_hash = ? and revoked = 0
+		and (expires_at is null or expires_at > ?) and (max_uses is null or uses < max_uses)`
+	result, err := tx.ExecContext(ctx, query, hashInviteToken(token), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrInviteInvalid
+	}
+
+	var eventId int
+	if err := tx.QueryRowContext(ctx, "select event_id from invites where token_hash = ?", hashInviteToken(token)).Scan(&eventId); err != nil {
+		return nil, err
+	}
+	var paid bool
+	query = "select exists (select 1 from ticket_types where event_id = ? and price_cents > 0)"
+	if err := tx.QueryRowContext(ctx, query, eventId).Scan(&paid); err != nil {
+		return nil, err
+	}
+	if paid {
+		return nil, ErrTicketRequired
+	}
+	attendee := &Attendee{EventId: eventId, UserId: userId}
+	if err := insertAttendeeWithinCapacity(ctx, tx, attendee); err != nil {
+		return nil, err
+	}
+	return attendee, tx.Commit()
+}
@@ -0,0 +1,280 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+const (
+	JobPending   = "pending"
+	JobRunning   = "running"
+	JobDone      = "done"
+	JobDead      = "dead"
+	JobCancelled = "cancelled"
+)
+
+const DefaultJobMaxAttempts = 5
+
+type JobModel struct {
+	db *sql.DB
+}
+
+// Job is a unit of background work stored in the jobs table, so it survives
+// restarts. Jobs with a dedupe key are only enqueued once. A running job is
+// leased to one worker until LockedUntil; if the worker doesn't finish it by
+// then, the job is handed to another worker.
+type Job struct {
+	Id          int        `json:"id"`
+	Type        string     `json:"type"`
+	Payload     string     `json:"payload"`
+	DedupeKey   *string    `json:"dedupeKey,omitempty"`
+	Status      string     `json:"status"`
+	Priority    int        `json:"priority"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"maxAttempts"`
+	RunAt       time.Time  `json:"runAt"`
+	LockedBy    *string    `json:"lockedBy,omitempty"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+	LastError   string     `json:"lastError"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+const jobColumns = "id,type,payload,dedupe_key,status,priority,attempts,max_attempts,run_at,locked_by,locked_until,last_error,created_at,updated_at"
+
+func scanJob(row interface{ Scan(...any) error }) (*Job, error) {
+	var job Job
+	var dedupeKey, lockedBy sql.NullString
+	var lockedUntil sql.NullTime
+	err := row.Scan(&job.Id, &job.Type, &job.Payload, &dedupeKey, &job.Status, &job.Priority, &job.Attempts, &job.MaxAttempts,
+		&job.RunAt, &lockedBy, &lockedUntil, &job.LastError, &job.CreatedAt, &job.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if dedupeKey.Valid {
+		job.DedupeKey = &dedupeKey.String
+	}
+	if lockedBy.Valid {
+		job.LockedBy = &lockedBy.String
+	}
+	if lockedUntil.Valid {
+		job.LockedUntil = &lockedUntil.Time
+	}
+	return &job, nil
+}
+
+// Enqueue stores a pending job. It reports false, leaving the job unsaved,
+// if a job with the same dedupe key already exists, unless that job was
+// cancelled, in which case it is revived with the new payload and run time.
+func (m *JobModel) Enqueue(job *Job) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	job.Status = JobPending
+	if job.RunAt.IsZero() {
+		job.RunAt = now
+	}
+	job.RunAt = job.RunAt.UTC()
+	if job.MaxAttempts <= 0 {
+		job.MaxAttempts = DefaultJobMaxAttempts
+	}
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	query := `insert into jobs (type, payload, dedupe_key, status, priority, max_attempts, run_at, created_at, updated_at) values (?,?,?,?,?,?,?,?,?)
+		on conflict (dedupe_key) do update set payload = excluded.payload, status = excluded.status, priority = excluded.priority,
+			attempts = 0, max_attempts = excluded.max_attempts, run_at = excluded.run_at, last_error = '', updated_at = excluded.updated_at
+		where jobs.status = 'cancelled'
+		returning id`
+	err := m.db.QueryRowContext(ctx, query, job.Type, job.Payload, job.DedupeKey, job.Status, job.Priority, job.MaxAttempts,
+		job.RunAt, job.CreatedAt, job.UpdatedAt).Scan(&job.Id)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Lease hands the next due job of one of the given types to a worker until
+// now+timeout, counting it as an attempt. Jobs whose lease ran out are due
+// again, unless they used up their attempts, which sends them to the dead
+// letter state. Higher priorities go first. Lease returns nil if no job is
+// due.
+func (m *JobModel) Lease(types []string, worker string, now time.Time, timeout time.Duration) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	if len(types) == 0 {
+		return nil, nil
+	}
+	now = now.UTC()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//a worker that lost its lease on a final attempt gets no further tries
+	query := `update jobs set status = ?, locked_by = null, locked_until = null, last_error = 'lease expired', updated_at = ?
+		where status = ? and locked_until <= ? and attempts >= max_attempts`
+	if _, err := tx.ExecContext(ctx, query, JobDead, now, JobRunning, now); err != nil {
+		return nil, err
+	}
+
+	args := []any{JobRunning, worker, now.Add(timeout), now}
+	for _, t := range types {
+		args = append(args, t)
+	}
+	args = append(args, JobPending, now, JobRunning, now)
+	query = `update jobs set status = ?, locked_by = ?, locked_until = ?, attempts = attempts + 1, updated_at = ?
+		where id = (select id from jobs where type in (?` + strings.Repeat(",?", len(types)-1) + `)
+			and ((status = ? and run_at <= ?) or (status = ? and locked_until <= ?))
+			order by priority desc, run_at limit 1)
+		returning ` + jobColumns
+	job, err := scanJob(tx.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, tx.Commit()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return job, tx.Commit()
+}
+
+// Complete marks a leased job as done. It reports false if the worker no
+// longer holds the lease.
+func (m *JobModel) Complete(job *Job, worker string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	job.Status = JobDone
+	job.LastError = ""
+	job.UpdatedAt = time.Now().UTC()
+	query := `update jobs set status = ?, locked_by = null, locked_until = null, last_error = '', updated_at = ?
+		where id = ? and status = ? and locked_by = ?`
+	return m.affected(m.db.ExecContext(ctx, query, JobDone, job.UpdatedAt, job.Id, JobRunning, worker))
+}
+
+// Fail records a failed attempt of a leased job. The job runs again at
+// retryAt, or goes to the dead letter state if retryAt is nil. It reports
+// false if the worker no longer holds the lease.
+func (m *JobModel) Fail(job *Job, worker string, lastError string, retryAt *time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	job.Status = JobDead
+	if retryAt != nil {
+		job.Status = JobPending
+		job.RunAt = retryAt.UTC()
+	}
+	job.LastError = lastError
+	job.UpdatedAt = time.Now().UTC()
+	query := `update jobs set status = ?, run_at = ?, locked_by = null, locked_until = null, last_error = ?, updated_at = ?
+		where id = ? and status = ? and locked_by = ?`
+	return m.affected(m.db.ExecContext(ctx, query, job.Status, job.RunAt, job.LastError, job.UpdatedAt, job.Id, JobRunning, worker))
+}
+
+// Release returns a leased job to the queue without counting the attempt,
+// for jobs interrupted by a shutdown.
+func (m *JobModel) Release(job *Job, worker string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `update jobs set status = ?, attempts = max(attempts - 1, 0), locked_by = null, locked_until = null, updated_at = ?
+		where id = ? and status = ? and locked_by = ?`
+	_, err := m.db.ExecContext(ctx, query, JobPending, time.Now().UTC(), job.Id, JobRunning, worker)
+	return err
+}
+
+// Retry puts a dead or cancelled job back in the queue with fresh attempts.
+// It reports false if the job isn't dead or cancelled. audit, if given, is
+// recorded in the same transaction.
+func (m *JobModel) Retry(id int, audit *AdminAction) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+	query := `update jobs set status = ?, attempts = 0, run_at = ?, last_error = '', updated_at = ?
+		where id = ? and status in (?, ?)`
+	retried, err := m.affected(tx.ExecContext(ctx, query, JobPending, now, now, id, JobDead, JobCancelled))
+	if err != nil || !retried {
+		return false, err
+	}
+	if err := insertAdminAction(ctx, tx, audit); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+func (m *JobModel) affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+func (m *JobModel) Get(id int) (*Job, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	job, err := scanJob(m.db.QueryRowContext(ctx, "select "+jobColumns+" from jobs where id = ?", id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return job, err
+}
+
+// List returns one page of the jobs with the given status and type, newest
+// first, and the total number of such jobs. Empty filters match every job.
+func (m *JobModel) List(status, jobType string, limit, offset int) ([]*Job, int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	where := " where (? = '' or status = ?) and (? = '' or type = ?)"
+	args := []any{status, status, jobType, jobType}
+
+	var total int
+	if err := m.db.QueryRowContext(ctx, "select count(*) from jobs"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "select "+jobColumns+" from jobs"+where+" order by id desc limit ? offset ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	jobs := []*Job{}
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, total, rows.Err()
+}
+
+// CancelByKeyPrefix cancels the pending jobs whose dedupe key starts with
+// prefix.
+func (m *JobModel) CancelByKeyPrefix(prefix string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "update jobs set status = ?, updated_at = ? where status = ? and substr(dedupe_key, 1, length(?)) = ?"
+	_, err := m.db.ExecContext(ctx, query, JobCancelled, time.Now().UTC(), JobPending, prefix, prefix)
+	return err
+}
@@ -0,0 +1,197 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJobEnqueueDedupes(t *testing.T) {
+	_, models := newTestModels(t)
+	key := "report:1"
+
+	tests := []struct {
+		name        string
+		before      func()
+		payload     string
+		wantCreated bool
+		wantPayload string
+	}{
+		{"first", nil, "first", true, "first"},
+		{"duplicate dropped", nil, "second", false, "first"},
+		{"cancelled revived", func() {
+			if err := models.Jobs.CancelByKeyPrefix("report:"); err != nil {
+				t.Fatal(err)
+			}
+		}, "third", true, "third"},
+	}
+	var id int
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				tt.before()
+			}
+			job := &Job{Type: "report", Payload: tt.payload, DedupeKey: &key}
+			created, err := models.Jobs.Enqueue(job)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created != tt.wantCreated {
+				t.Errorf("created = %v, want %v", created, tt.wantCreated)
+			}
+			if created {
+				id = job.Id
+			}
+			stored, err := models.Jobs.Get(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Payload != tt.wantPayload || stored.Status != JobPending {
+				t.Errorf("job is %s with %q, want pending with %q", stored.Status, stored.Payload, tt.wantPayload)
+			}
+		})
+	}
+}
+
+func TestJobLeaseOrder(t *testing.T) {
+	_, models := newTestModels(t)
+	now := time.Now()
+	enqueue := func(jobType string, priority int, runAt time.Time) *Job {
+		job := &Job{Type: jobType, Payload: "{}", Priority: priority, RunAt: runAt}
+		if _, err := models.Jobs.Enqueue(job); err != nil {
+			t.Fatal(err)
+		}
+		return job
+	}
+	older := enqueue("mail", 0, now.Add(-time.Minute))
+	urgent := enqueue("mail", 5, now)
+	newer := enqueue("mail", 0, now)
+	enqueue("mail", 9, now.Add(time.Hour))
+	enqueue("report", 9, now)
+
+	for _, want := range []*Job{urgent, older, newer, nil} {
+		job, err := models.Jobs.Lease([]string{"mail"}, "worker", now, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case want == nil && job != nil:
+			t.Errorf("leased job %d, want none due", job.Id)
+		case want != nil && (job == nil || job.Id != want.Id):
+			t.Fatalf("leased %+v, want job %d", job, want.Id)
+		case job != nil && (job.Status != JobRunning || job.Attempts != 1 || *job.LockedBy != "worker"):
+			t.Errorf("leased job is %s after %d attempts, locked by %v", job.Status, job.Attempts, job.LockedBy)
+		}
+	}
+}
+
+func TestJobLeaseExpiry(t *testing.T) {
+	_, models := newTestModels(t)
+	now := time.Now()
+	job := &Job{Type: "mail", Payload: "{}", MaxAttempts: 2, RunAt: now}
+	if _, err := models.Jobs.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		worker string
+		at     time.Time
+		// wantAttempts is the attempts of the job leased, or 0 if none is.
+		wantAttempts int
+	}{
+		{"first worker", "a", now, 1},
+		{"still leased", "b", now.Add(30 * time.Second), 0},
+		{"lease expired", "b", now.Add(2 * time.Minute), 2},
+		{"last attempt expired too", "c", now.Add(4 * time.Minute), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leased, err := models.Jobs.Lease([]string{"mail"}, tt.worker, tt.at, time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantAttempts == 0 {
+				if leased != nil {
+					t.Fatalf("leased job %d after %d attempts", leased.Id, leased.Attempts)
+				}
+				return
+			}
+			if leased == nil || leased.Attempts != tt.wantAttempts {
+				t.Fatalf("leased %+v, want attempt %d", leased, tt.wantAttempts)
+			}
+		})
+	}
+
+	//the first worker lost its lease, so its result is ignored
+	if held, err := models.Jobs.Complete(job, "a"); err != nil || held {
+		t.Errorf("Complete() by a worker that lost its lease = %v, %v", held, err)
+	}
+	stored, err := models.Jobs.Get(job.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != JobDead || stored.LastError != "lease expired" {
+		t.Errorf("job is %s with %q, want dead after its lease expired", stored.Status, stored.LastError)
+	}
+}
+
+func TestJobFailAndRetry(t *testing.T) {
+	_, models := newTestModels(t)
+	now := time.Now()
+	job := &Job{Type: "mail", Payload: "{}", RunAt: now}
+	if _, err := models.Jobs.Enqueue(job); err != nil {
+		t.Fatal(err)
+	}
+	lease := func(at time.Time) *Job {
+		t.Helper()
+		leased, err := models.Jobs.Lease([]string{"mail"}, "worker", at, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return leased
+	}
+
+	//a failure with a retry time runs the job again at that time
+	leased := lease(now)
+	retryAt := now.Add(time.Minute)
+	if held, err := models.Jobs.Fail(leased, "worker", "smtp down", &retryAt); err != nil || !held {
+		t.Fatalf("Fail() = %v, %v", held, err)
+	}
+	if early := lease(now.Add(30 * time.Second)); early != nil {
+		t.Fatal("the job ran before its retry time")
+	}
+	leased = lease(retryAt)
+	if leased == nil || leased.Attempts != 2 || leased.LastError != "smtp down" {
+		t.Fatalf("retry leased %+v, want the second attempt", leased)
+	}
+
+	//a failure without one is a dead letter, until an administrator retries it
+	if held, err := models.Jobs.Fail(leased, "worker", "mailbox gone", nil); err != nil || !held {
+		t.Fatalf("Fail() = %v, %v", held, err)
+	}
+	if dead := lease(now.Add(time.Hour)); dead != nil {
+		t.Fatal("a dead job was leased")
+	}
+	if retried, err := models.Jobs.Retry(job.Id, nil); err != nil || !retried {
+		t.Fatalf("Retry() = %v, %v", retried, err)
+	}
+	if retried, err := models.Jobs.Retry(job.Id, nil); err != nil || retried {
+		t.Errorf("Retry() of a pending job = %v, %v, want false", retried, err)
+	}
+	leased = lease(time.Now())
+	if leased == nil || leased.Attempts != 1 || leased.LastError != "" {
+		t.Fatalf("retried job leased as %+v, want fresh attempts", leased)
+	}
+
+	//a released job doesn't count the interrupted attempt
+	if err := models.Jobs.Release(leased, "worker"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := models.Jobs.Get(job.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != JobPending || stored.Attempts != 0 {
+		t.Errorf("released job is %s after %d attempts, want pending after 0", stored.Status, stored.Attempts)
+	}
+}
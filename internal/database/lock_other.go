@@ -0,0 +1,14 @@
+//go:build !unix && !windows
+
+package database
+
+import "sync"
+
+var migrateMu sync.Mutex
+
+// lockFile only locks within the process where file locks aren't
+// supported, so servers sharing a database must not migrate concurrently.
+func lockFile(path string) (func(), error) {
+	migrateMu.Lock()
+	return migrateMu.Unlock, nil
+}
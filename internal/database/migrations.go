@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/golang-migrate/migrate"
+	"github.com/golang-migrate/migrate/database/sqlite3"
+	"github.com/golang-migrate/migrate/source"
+	bindata "github.com/golang-migrate/migrate/source/go_bindata"
+)
+
+var migrationFile = regexp.MustCompile(`^(\d+)_.*\.up\.sql$`)
+
+// SchemaVersion returns the version of the last migration applied to the
+// database and whether it failed halfway, as recorded by the migrate tool.
+// The version is 0 if no migration ran.
+func SchemaVersion(db *sql.DB) (int, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var version int
+	var dirty bool
+	err := db.QueryRowContext(ctx, "select version, dirty from schema_migrations limit 1").Scan(&version, &dirty)
+	if err == sql.ErrNoRows {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// LatestMigration returns the highest version among the migrations in
+// migrations.
+func LatestMigration(migrations fs.FS) (int, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return 0, err
+	}
+	latest := 0
+	for _, entry := range entries {
+		match := migrationFile.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return 0, err
+		}
+		latest = max(latest, version)
+	}
+	return latest, nil
+}
+
+// MigrationSource returns a golang-migrate source reading the migrations
+// in migrations.
+func MigrationSource(migrations fs.FS) (source.Driver, error) {
+	entries, err := fs.ReadDir(migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return bindata.WithInstance(bindata.Resource(names, func(name string) ([]byte, error) {
+		return fs.ReadFile(migrations, name)
+	}))
+}
+
+// Migrate applies the pending migrations in migrations to the SQLite
+// database at path and returns the versions it migrated from and to.
+// Servers starting together take turns through a lock file next to the
+// database, so only the first runs the migrations. It refuses to touch a
+// database left dirty by a failed migration, or one at a version newer than
+// the migrations, which belongs to a newer release.
+func Migrate(path string, migrations fs.FS) (from, to int, err error) {
+	unlock, err := lockFile(path + ".migrate.lock")
+	if err != nil {
+		return 0, 0, fmt.Errorf("locking migrations: %w", err)
+	}
+	defer unlock()
+
+	//migrations get their own connection, without the pragmas of the server's
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer db.Close()
+	instance, err := sqlite3.WithInstance(db, &sqlite3.Config{})
+	if err != nil {
+		return 0, 0, err
+	}
+	src, err := MigrationSource(migrations)
+	if err != nil {
+		return 0, 0, err
+	}
+	m, err := migrate.NewWithInstance("go-bindata", src, "sqlite3", instance)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	latest, err := LatestMigration(migrations)
+	if err != nil {
+		return 0, 0, err
+	}
+	version, dirty, err := m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return 0, 0, err
+	}
+	from = int(version)
+	if dirty {
+		return from, from, fmt.Errorf("migration %d failed halfway, fix it with the migrate command", from)
+	}
+	if from > latest {
+		return from, from, fmt.Errorf("the database is at version %d, newer than the latest migration %d", from, latest)
+	}
+
+	if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return from, from, err
+	}
+	version, _, err = m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return from, from, err
+	}
+	return from, int(version), nil
+}
@@ -0,0 +1,167 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+)
+
+// testMigrations returns migrations creating tables a, b, ... up to the
+// given version.
+func testMigrations(version int) fstest.MapFS {
+	fsys := fstest.MapFS{"README.md": {Data: []byte("not a migration")}}
+	for v := 1; v <= version; v++ {
+		table := string(rune('a' + v - 1))
+		fsys[fmt.Sprintf("%06d_create_%s.up.sql", v, table)] = &fstest.MapFile{Data: []byte("create table " + table + " (id integer);")}
+		fsys[fmt.Sprintf("%06d_create_%s.down.sql", v, table)] = &fstest.MapFile{Data: []byte("drop table " + table + ";")}
+	}
+	return fsys
+}
+
+func TestLatestMigration(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+		want int
+	}{
+		{"none", testMigrations(0), 0},
+		{"numbered", testMigrations(3), 3},
+		{"timestamped after numbered", fstest.MapFS{
+			"000017_old.up.sql":            {},
+			"20261019141612_new.up.sql":    {},
+			"20261019141612_new.down.sql":  {},
+			"99999999999999_only.down.sql": {},
+		}, 20261019141612},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LatestMigration(tt.fsys)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("LatestMigration() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name string
+		// setup prepares the database, which starts empty.
+		setup    func(t *testing.T, path string, db *sql.DB)
+		fsys     fstest.MapFS
+		wantFrom int
+		wantTo   int
+		wantErr  string
+	}{
+		{"fresh", nil, testMigrations(2), 0, 2, ""},
+		{"up to date", func(t *testing.T, path string, db *sql.DB) {
+			mustMigrate(t, path, testMigrations(2))
+		}, testMigrations(2), 2, 2, ""},
+		{"pending", func(t *testing.T, path string, db *sql.DB) {
+			mustMigrate(t, path, testMigrations(1))
+		}, testMigrations(3), 1, 3, ""},
+		{"dirty", func(t *testing.T, path string, db *sql.DB) {
+			mustMigrate(t, path, testMigrations(1))
+			mustExec(t, db, "update schema_migrations set dirty = 1")
+		}, testMigrations(2), 1, 1, "migration 1 failed halfway"},
+		{"newer than the migrations", func(t *testing.T, path string, db *sql.DB) {
+			mustMigrate(t, path, testMigrations(3))
+		}, testMigrations(2), 3, 3, "the database is at version 3, newer than the latest migration 2"},
+		{"failing migration", nil, fstest.MapFS{
+			"000001_create_a.up.sql": {Data: []byte("create table a (id integer);")},
+			"000002_broken.up.sql":   {Data: []byte("create table (;")},
+		}, 0, 0, "syntax error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "test.db")
+			db, err := sql.Open("sqlite3", path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			if tt.setup != nil {
+				tt.setup(t, path, db)
+			}
+
+			from, to, err := Migrate(path, tt.fsys)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("Migrate() error = %v, want %q", err, tt.wantErr)
+			}
+			if from != tt.wantFrom || to != tt.wantTo {
+				t.Errorf("Migrate() migrated from %d to %d, want from %d to %d", from, to, tt.wantFrom, tt.wantTo)
+			}
+			if tt.wantErr == "" {
+				if version, dirty, err := SchemaVersion(db); err != nil || version != tt.wantTo || dirty {
+					t.Errorf("SchemaVersion() = %d, %v, %v, want %d, clean", version, dirty, err, tt.wantTo)
+				}
+			}
+		})
+	}
+}
+
+// TestMigrateTogether checks that servers starting at once on the same
+// database migrate it once.
+func TestMigrateTogether(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	latest, err := LatestMigration(migrations.FS)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const servers = 4
+	var wg sync.WaitGroup
+	froms := make([]int, servers)
+	errs := make([]error, servers)
+	for i := range servers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var to int
+			froms[i], to, errs[i] = Migrate(path, migrations.FS)
+			if errs[i] == nil && to != latest {
+				t.Errorf("server %d migrated to %d, want %d", i, to, latest)
+			}
+		}()
+	}
+	wg.Wait()
+
+	fresh := 0
+	for i := range servers {
+		if errs[i] != nil {
+			t.Fatalf("server %d: %v", i, errs[i])
+		}
+		if froms[i] == 0 {
+			fresh++
+		}
+	}
+	if fresh != 1 {
+		t.Errorf("%d servers migrated a fresh database, want 1", fresh)
+	}
+}
+
+func mustMigrate(t *testing.T, path string, fsys fstest.MapFS) {
+	t.Helper()
+	if _, _, err := Migrate(path, fsys); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string) {
+	t.Helper()
+	if _, err := db.Exec(query); err != nil {
+		t.Fatal(err)
+	}
+}
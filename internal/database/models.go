@@ -3,15 +3,37 @@ package database
 import "database/sql"
 
 type Models struct {
-	Users     UserModel
-	Events    EventModel
-	Attendees AttendeeModel
+	Users       UserModel
+	Events      EventModel
+	Attendees   AttendeeModel
+	Webhooks    WebhookModel
+	Tags        TagModel
+	TicketTypes TicketTypeModel
+	Orders      OrderModel
+	Invites     InviteModel
+	Jobs        JobModel
+	Stats       StatsModel
+	Admin       AdminActionModel
+	APIKeys     APIKeyModel
+	Identities  IdentityModel
+	OIDCLogins  OIDCLoginModel
 }
 
 func NewModels(db *sql.DB) Models {
 	return Models{
-		Users:     UserModel{db: db},
-		Events:    EventModel{db: db},
-		Attendees: AttendeeModel{db: db},
+		Users:       UserModel{db: db},
+		Events:      EventModel{db: db},
+		Attendees:   AttendeeModel{db: db},
+		Webhooks:    WebhookModel{db: db},
+		Tags:        TagModel{db: db},
+		TicketTypes: TicketTypeModel{db: db},
+		Orders:      OrderModel{db: db},
+		Invites:     InviteModel{db: db},
+		Jobs:        JobModel{db: db},
+		Stats:       StatsModel{db: db},
+		Admin:       AdminActionModel{db: db},
+		APIKeys:     APIKeyModel{db: db},
+		Identities:  IdentityModel{db: db},
+		OIDCLogins:  OIDCLoginModel{db: db},
 	}
 }
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+)
+
+// newTestModels returns the models of a freshly migrated database, with
+// foreign keys enforced like in the API.
+func newTestModels(t *testing.T) (*sql.DB, Models) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if _, _, err := Migrate(path, migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec("PRAGMA foreign_keys = ON"); err != nil {
+		t.Fatal(err)
+	}
+	return db, NewModels(db)
+}
+
+// insertTestUser adds a user named name.
+func insertTestUser(t *testing.T, models Models, name string) *User {
+	t.Helper()
+	user := &User{Name: name, Email: name + "@example.com", Password: "x"}
+	if err := models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// insertTestEvents adds events owned by owner.
+func insertTestEvents(t *testing.T, models Models, owner *User, events ...*Event) {
+	t.Helper()
+	for _, event := range events {
+		event.OwnerId = owner.Id
+		if event.Date == "" {
+			event.Date = "2030-01-01T18:00:00Z"
+		}
+		if err := models.Events.Insert(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+//go:build sqlite_fts5
+
+package database
+
+// Search runs a full-text search over the names, descriptions and locations
+// of public events, best matches first, and returns one page of results
+// together with the total number of matches.
+func (m *EventModel) Search(text string, limit, offset int) ([]*EventSearchResult, int, error) {
+	ctx, cancel := m.traced("Search")
+	defer cancel()
+
+	match := SearchQuery(text)
+	if match == "" {
+		return []*EventSearchResult{}, 0, nil
+	}
+
+	var total int
+	query := "select count(*) from events_fts join events e on e.id = events_fts.rowid where events_fts match ? and e.visibility = 'public'"
+	err := m.db.QueryRowContext(ctx, query, match).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	//name matches weigh more than location matches, which weigh more than description matches
+	query = `select ` + eventColumns + `,
+		-bm25(events_fts, 10.0, 1.0, 5.0) as score,
+		highlight(events_fts, 0, '<mark>', '</mark>'),
+		highlight(events_fts, 2, '<mark>', '</mark>'),
+		snippet(events_fts, 1, '<mark>', '</mark>', '…', 16)
+		from events_fts join events e on e.id = events_fts.rowid
+		where events_fts match ? and e.visibility = 'public' order by score desc limit ? offset ?`
+
+	rows, err := m.db.QueryContext(ctx, query, match, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	results, err := scanSearchResults(ctx, m.db, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+	return results, total, nil
+}
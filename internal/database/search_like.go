@@ -0,0 +1,107 @@
+//go:build !sqlite_fts5
+
+package database
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// snippetWords is the number of words of a description a snippet shows.
+const snippetWords = 16
+
+// Search searches the names, descriptions and locations of public events
+// with LIKE, for builds without SQLite's FTS5 extension. Like the full-text
+// search, every word must match, but anywhere in a word rather than as a
+// prefix, and only ASCII letters match regardless of case. Events score 10
+// for each word in the name, 5 in the location and 1 in the description;
+// highlights and snippets are marked up in Go.
+func (m *EventModel) Search(text string, limit, offset int) ([]*EventSearchResult, int, error) {
+	ctx, cancel := m.traced("Search")
+	defer cancel()
+
+	words := searchWords(text)
+	if len(words) == 0 {
+		return []*EventSearchResult{}, 0, nil
+	}
+
+	var where, score []string
+	var whereArgs, scoreArgs []any
+	for _, word := range words {
+		pattern := "%" + likeEscaper.Replace(word) + "%"
+		where = append(where, `(e.name like ? escape '\' or e.description like ? escape '\' or e.location like ? escape '\')`)
+		whereArgs = append(whereArgs, pattern, pattern, pattern)
+		score = append(score, `10 * (e.name like ? escape '\') + 5 * (e.location like ? escape '\') + (e.description like ? escape '\')`)
+		scoreArgs = append(scoreArgs, pattern, pattern, pattern)
+	}
+	filter := "e.visibility = 'public' and " + strings.Join(where, " and ")
+
+	var total int
+	err := m.db.QueryRowContext(ctx, "select count(*) from events e where "+filter, whereArgs...).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `select ` + eventColumns + `, ` + strings.Join(score, " + ") + ` as score, '', '', ''
+		from events e where ` + filter + ` order by score desc, e.id limit ? offset ?`
+	args := append(append(scoreArgs, whereArgs...), limit, offset)
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	results, err := scanSearchResults(ctx, m.db, rows)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	matches := matchWords(words)
+	for _, result := range results {
+		result.Highlight.Name = mark(matches, result.Name)
+		result.Highlight.Location = mark(matches, result.Location)
+		result.Snippet = snippet(matches, result.Description)
+	}
+	return results, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// matchWords returns a case-insensitive expression matching any of words,
+// longest first so that a word containing another is marked whole.
+func matchWords(words []string) *regexp.Regexp {
+	quoted := make([]string, len(words))
+	for i, word := range words {
+		quoted[i] = regexp.QuoteMeta(word)
+	}
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// mark wraps the matches in text in <mark> tags.
+func mark(matches *regexp.Regexp, text string) string {
+	return matches.ReplaceAllString(text, "<mark>$0</mark>")
+}
+
+// snippet returns up to snippetWords words of text around its first match,
+// marked up, with an ellipsis where text was cut.
+func snippet(matches *regexp.Regexp, text string) string {
+	words := strings.Fields(text)
+	first := 0
+	for i, word := range words {
+		if matches.MatchString(word) {
+			first = i
+			break
+		}
+	}
+	start := max(0, min(first-snippetWords/4, len(words)-snippetWords))
+	end := min(len(words), start+snippetWords)
+
+	result := mark(matches, strings.Join(words[start:end], " "))
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(words) {
+		result += "…"
+	}
+	return result
+}
@@ -0,0 +1,136 @@
+package database
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// searchNames returns the names of the events a search finds, best first.
+func searchNames(t *testing.T, models Models, text string) []string {
+	t.Helper()
+	results, total, err := models.Events.Search(text, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != len(results) {
+		t.Errorf("Search(%q) counted %d results but returned %d", text, total, len(results))
+	}
+	names := []string{}
+	for _, result := range results {
+		names = append(names, result.Name)
+	}
+	return names
+}
+
+func TestSearch(t *testing.T) {
+	_, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	insertTestEvents(t, models, owner,
+		&Event{Name: "Go meetup", Description: "Monthly meetup for Go developers", Location: "Berlin"},
+		&Event{Name: "Rust conference", Description: "Two days of talks, with a Go track", Location: "Munich"},
+		&Event{Name: "Berlin book club", Description: "Reading group for crime novels", Location: "Library"},
+		&Event{Name: "Go board game night", Description: "Secret tournament of the club", Location: "Berlin", Visibility: VisibilityPrivate},
+	)
+
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"name before description", "go", []string{"Go meetup", "Rust conference"}},
+		{"name before location", "berlin", []string{"Berlin book club", "Go meetup"}},
+		{"every word must match", "go berlin", []string{"Go meetup"}},
+		{"case and punctuation are ignored", "  GO, Berlin!", []string{"Go meetup"}},
+		{"prefix", "confer", []string{"Rust conference"}},
+		{"private events are left out", "tournament", []string{}},
+		{"no match", "python", []string{}},
+		{"no words", "!?", []string{}},
+		{"empty", "", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := searchNames(t, models, tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchPages(t *testing.T) {
+	_, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	for _, name := range []string{"Jazz night", "Jazz brunch", "Jazz festival"} {
+		insertTestEvents(t, models, owner, &Event{Name: name, Description: "Live music all evening", Location: "Club"})
+	}
+
+	results, total, err := models.Events.Search("jazz", 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 3 || len(results) != 1 {
+		t.Errorf("Search() returned %d of %d results, want 1 of 3", len(results), total)
+	}
+}
+
+func TestSearchHighlights(t *testing.T) {
+	_, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	insertTestEvents(t, models, owner, &Event{
+		Name:        "Go meetup",
+		Description: "An evening of lightning talks about tooling, testing and profiling, followed by pizza, drinks and a Go quiz for everyone",
+		Location:    "Go-Haus Berlin",
+	})
+
+	results, _, err := models.Events.Search("go", 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Search() returned %d results, want 1", len(results))
+	}
+	result := results[0]
+	if result.Highlight.Name != "<mark>Go</mark> meetup" {
+		t.Errorf("name highlight = %q", result.Highlight.Name)
+	}
+	if result.Highlight.Location != "<mark>Go</mark>-Haus Berlin" {
+		t.Errorf("location highlight = %q", result.Highlight.Location)
+	}
+	if !strings.Contains(result.Snippet, "<mark>Go</mark> quiz") || !strings.HasPrefix(result.Snippet, "…") {
+		t.Errorf("snippet = %q, want the cut text around the match", result.Snippet)
+	}
+	if result.Score <= 0 {
+		t.Errorf("score = %v, want a positive score", result.Score)
+	}
+}
+
+// TestSearchFollowsChanges checks that the triggers keep the search up to
+// date with events that are updated or deleted.
+func TestSearchFollowsChanges(t *testing.T) {
+	_, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	event := &Event{Name: "Salsa workshop", Description: "Beginner friendly dance class", Location: "Studio"}
+	insertTestEvents(t, models, owner, event)
+
+	tests := []struct {
+		name   string
+		change func() error
+		text   string
+		want   []string
+	}{
+		{"inserted", func() error { return nil }, "salsa", []string{"Salsa workshop"}},
+		{"renamed", func() error { event.Name = "Tango workshop"; return models.Events.Update(event) }, "tango", []string{"Tango workshop"}},
+		{"old name gone", func() error { return nil }, "salsa", []string{}},
+		{"deleted", func() error { return models.Events.Delete(event.Id, nil) }, "tango", []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+			if got := searchNames(t, models, tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Search(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
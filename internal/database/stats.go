@@ -0,0 +1,287 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Intervals of the buckets of a statistics series.
+const (
+	IntervalDay  = "day"
+	IntervalWeek = "week"
+)
+
+const statsDateLayout = "2006-01-02"
+
+type StatsModel struct {
+	db *sql.DB
+}
+
+// StatsBucket holds the counts of one day or week, starting on Start. Weeks
+// start on Monday.
+type StatsBucket struct {
+	Start         string `json:"start"`
+	Registrations int    `json:"registrations"`
+	CheckIns      int    `json:"checkIns"`
+	Cancellations int    `json:"cancellations"`
+}
+
+// RSVPBreakdown counts people by where they are in the RSVP process.
+// Invited counts email invites that are still usable and whose recipient
+// doesn't attend yet; cancelled counts people who left and didn't come back.
+type RSVPBreakdown struct {
+	Invited    int `json:"invited"`
+	Registered int `json:"registered"`
+	CheckedIn  int `json:"checkedIn"`
+	Cancelled  int `json:"cancelled"`
+}
+
+// Stats are the figures shared by event and organizer statistics. The
+// series covers a date range; the other figures are all time. Attendees who
+// registered before registration times were recorded are missing from the
+// series.
+type Stats struct {
+	Attendees     int           `json:"attendees"`
+	RSVP          RSVPBreakdown `json:"rsvp"`
+	CheckInRate   float64       `json:"checkInRate"`
+	Cancellations int           `json:"cancellations"`
+	Interval      string        `json:"interval"`
+	Series        []StatsBucket `json:"series"`
+}
+
+type EventStats struct {
+	EventId  int  `json:"eventId"`
+	Capacity *int `json:"capacity,omitempty"`
+	Stats
+}
+
+// EventAttendance is an event with its number of attendees.
+type EventAttendance struct {
+	EventId   int    `json:"eventId"`
+	Name      string `json:"name"`
+	Date      string `json:"date"`
+	Attendees int    `json:"attendees"`
+	CheckedIn int    `json:"checkedIn"`
+}
+
+// OwnerStats are the statistics of all events of an organizer, with their
+// best attended events.
+type OwnerStats struct {
+	Events int `json:"events"`
+	Stats
+	TopEvents []EventAttendance `json:"topEvents"`
+}
+
+const topEventsLimit = 5
+
+// ForEvent returns the statistics of an event, with a series of the days or
+// weeks from from to to, both dates included.
+func (m *StatsModel) ForEvent(eventId int, interval string, from, to time.Time) (*EventStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	stats := EventStats{EventId: eventId}
+	if err := m.db.QueryRowContext(ctx, "select capacity from events where id = ?", eventId).Scan(&stats.Capacity); err != nil {
+		return nil, err
+	}
+	if err := m.collect(ctx, &stats.Stats, "select id from events where id = ?", eventId, interval, from, to); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// ForOwner returns the statistics of all events owned by a user, like
+// ForEvent.
+func (m *StatsModel) ForOwner(ownerId int, interval string, from, to time.Time) (*OwnerStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var stats OwnerStats
+	if err := m.db.QueryRowContext(ctx, "select count(*) from events where owner_id = ?", ownerId).Scan(&stats.Events); err != nil {
+		return nil, err
+	}
+	if err := m.collect(ctx, &stats.Stats, "select id from events where owner_id = ?", ownerId, interval, from, to); err != nil {
+		return nil, err
+	}
+
+	query := `select e.id, e.name, e.date, count(a.id), count(a.checked_in_at) from events e
+		left join attendees a on a.event_id = e.id where e.owner_id = ?
+		group by e.id order by count(a.id) desc, e.id limit ?`
+	rows, err := m.db.QueryContext(ctx, query, ownerId, topEventsLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats.TopEvents = []EventAttendance{}
+	for rows.Next() {
+		var event EventAttendance
+		if err := rows.Scan(&event.EventId, &event.Name, &event.Date, &event.Attendees, &event.CheckedIn); err != nil {
+			return nil, err
+		}
+		stats.TopEvents = append(stats.TopEvents, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return &stats, nil
+}
+
+// collect fills in the statistics of the events selected by scope, a query
+// of event ids with one parameter.
+func (m *StatsModel) collect(ctx context.Context, stats *Stats, scope string, arg int, interval string, from, to time.Time) error {
+	var checkedIn int
+	query := "select count(*), count(checked_in_at) from attendees where event_id in (" + scope + ")"
+	if err := m.db.QueryRowContext(ctx, query, arg).Scan(&stats.Attendees, &checkedIn); err != nil {
+		return err
+	}
+	stats.RSVP.CheckedIn = checkedIn
+	stats.RSVP.Registered = stats.Attendees - checkedIn
+	if stats.Attendees > 0 {
+		stats.CheckInRate = float64(checkedIn) / float64(stats.Attendees)
+	}
+
+	query = `select count(*) from invites i where i.event_id in (` + scope + `) and i.email is not null and i.revoked = 0
+		and (i.expires_at is null or i.expires_at > ?) and (i.max_uses is null or i.uses < i.max_uses)
+		and not exists (select 1 from attendees a join users u on u.id = a.user_id where a.event_id = i.event_id and lower(u.email) = lower(i.email))`
+	if err := m.db.QueryRowContext(ctx, query, arg, time.Now().UTC()).Scan(&stats.RSVP.Invited); err != nil {
+		return err
+	}
+
+	query = `select count(*), (select count(*) from (select distinct c.event_id, c.user_id from attendee_cancellations c
+			where c.event_id in (` + scope + `) and not exists (select 1 from attendees a where a.event_id = c.event_id and a.user_id = c.user_id)))
+		from attendee_cancellations where event_id in (` + scope + `)`
+	if err := m.db.QueryRowContext(ctx, query, arg, arg).Scan(&stats.Cancellations, &stats.RSVP.Cancelled); err != nil {
+		return err
+	}
+
+	stats.Interval = interval
+	stats.Series = statsBuckets(interval, from, to)
+	index := map[string]*StatsBucket{}
+	for i := range stats.Series {
+		index[stats.Series[i].Start] = &stats.Series[i]
+	}
+	series := []struct {
+		table, column string
+		count         func(*StatsBucket) *int
+	}{
+		{"attendees", "registered_at", func(b *StatsBucket) *int { return &b.Registrations }},
+		{"attendees", "checked_in_at", func(b *StatsBucket) *int { return &b.CheckIns }},
+		{"attendee_cancellations", "cancelled_at", func(b *StatsBucket) *int { return &b.Cancellations }},
+	}
+	start, end := bucketStart(interval, from.UTC()), bucketStart(interval, to.UTC()).AddDate(0, 0, intervalDays(interval))
+	for _, s := range series {
+		bucket := "date(" + s.column + ")"
+		if interval == IntervalWeek {
+			bucket = "date(" + s.column + ", 'weekday 0', '-6 days')"
+		}
+		query := "select " + bucket + ", count(*) from " + s.table + " where event_id in (" + scope + ") and " +
+			s.column + " >= ? and " + s.column + " < ? group by 1"
+		rows, err := m.db.QueryContext(ctx, query, arg, start, end)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			var day string
+			var count int
+			if err := rows.Scan(&day, &count); err != nil {
+				rows.Close()
+				return err
+			}
+			if b, ok := index[day]; ok {
+				*s.count(b) = count
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsBuckets returns empty buckets covering from to to.
+func statsBuckets(interval string, from, to time.Time) []StatsBucket {
+	buckets := []StatsBucket{}
+	end := bucketStart(interval, to.UTC())
+	for day := bucketStart(interval, from.UTC()); !day.After(end); day = day.AddDate(0, 0, intervalDays(interval)) {
+		buckets = append(buckets, StatsBucket{Start: day.Format(statsDateLayout)})
+	}
+	return buckets
+}
+
+// bucketStart returns the start of the bucket holding t: its day, or the
+// Monday of its week.
+func bucketStart(interval string, t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if interval == IntervalWeek {
+		day = day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	}
+	return day
+}
+
+func intervalDays(interval string) int {
+	if interval == IntervalWeek {
+		return 7
+	}
+	return 1
+}
+
+// SystemStats are the figures of the whole system, for administrators.
+type SystemStats struct {
+	Users struct {
+		Total    int `json:"total"`
+		Disabled int `json:"disabled"`
+		Admins   int `json:"admins"`
+	} `json:"users"`
+	Events struct {
+		Total    int `json:"total"`
+		Upcoming int `json:"upcoming"`
+		Public   int `json:"public"`
+		Unlisted int `json:"unlisted"`
+		Private  int `json:"private"`
+	} `json:"events"`
+	Attendees int `json:"attendees"`
+	Orders    int `json:"orders"`
+	// Jobs counts background jobs by status.
+	Jobs map[string]int `json:"jobs"`
+}
+
+func (m *StatsModel) System() (*SystemStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var stats SystemStats
+	query := "select count(*), count(disabled_at), coalesce(sum(is_admin), 0) from users"
+	if err := m.db.QueryRowContext(ctx, query).Scan(&stats.Users.Total, &stats.Users.Disabled, &stats.Users.Admins); err != nil {
+		return nil, err
+	}
+	query = `select count(*), coalesce(sum(datetime(date) >= datetime('now')), 0),
+		coalesce(sum(visibility = 'public'), 0), coalesce(sum(visibility = 'unlisted'), 0), coalesce(sum(visibility = 'private'), 0) from events`
+	if err := m.db.QueryRowContext(ctx, query).Scan(&stats.Events.Total, &stats.Events.Upcoming, &stats.Events.Public, &stats.Events.Unlisted, &stats.Events.Private); err != nil {
+		return nil, err
+	}
+	if err := m.db.QueryRowContext(ctx, "select count(*) from attendees").Scan(&stats.Attendees); err != nil {
+		return nil, err
+	}
+	if err := m.db.QueryRowContext(ctx, "select count(*) from orders").Scan(&stats.Orders); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.QueryContext(ctx, "select status, count(*) from jobs group by status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats.Jobs = map[string]int{}
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, err
+		}
+		stats.Jobs[status] = count
+	}
+	return &stats, rows.Err()
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestStatsBuckets(t *testing.T) {
+	day := func(s string) time.Time {
+		d, err := time.Parse(statsDateLayout, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	tests := []struct {
+		name     string
+		interval string
+		from, to time.Time
+		want     []string
+	}{
+		{"days", IntervalDay, day("2026-02-27"), day("2026-03-02"), []string{"2026-02-27", "2026-02-28", "2026-03-01", "2026-03-02"}},
+		{"one day", IntervalDay, day("2026-03-01"), day("2026-03-01").Add(23 * time.Hour), []string{"2026-03-01"}},
+		{"days in UTC", IntervalDay, time.Date(2026, 3, 2, 0, 30, 0, 0, time.FixedZone("CET", 3600)), day("2026-03-02"), []string{"2026-03-01", "2026-03-02"}},
+		{"weeks start on monday", IntervalWeek, day("2026-03-01"), day("2026-03-09"), []string{"2026-02-23", "2026-03-02", "2026-03-09"}},
+		{"sunday ends its week", IntervalWeek, day("2026-03-08"), day("2026-03-08"), []string{"2026-03-02"}},
+		{"weeks across the year", IntervalWeek, day("2025-12-31"), day("2026-01-05"), []string{"2025-12-29", "2026-01-05"}},
+		{"to before from", IntervalDay, day("2026-03-02"), day("2026-03-01"), []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+			for _, bucket := range statsBuckets(tt.interval, tt.from, tt.to) {
+				got = append(got, bucket.Start)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("statsBuckets(%s, %s, %s) = %q, want %q", tt.interval, tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+// insertTestAttendee adds an attendee who registered and maybe checked in
+// at the given times.
+func insertTestAttendee(t *testing.T, db *sql.DB, eventId, userId int, registeredAt time.Time, checkedInAt *time.Time) {
+	t.Helper()
+	query := "insert into attendees (user_id, event_id, registered_at, checked_in_at) values (?,?,?,?)"
+	if _, err := db.Exec(query, userId, eventId, registeredAt, checkedInAt); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatsSeries(t *testing.T) {
+	db, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	event := &Event{Name: "Meetup", Description: "Monthly meetup", Location: "Town hall"}
+	insertTestEvents(t, models, owner, event)
+	at := func(s string) time.Time {
+		t.Helper()
+		d, err := time.Parse(time.RFC3339, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	checkedIn := func(s string) *time.Time {
+		d := at(s)
+		return &d
+	}
+	var users []*User
+	for _, name := range []string{"bob", "carol", "dave", "erin", "frank"} {
+		users = append(users, insertTestUser(t, models, name))
+	}
+	insertTestAttendee(t, db, event.Id, users[0].Id, at("2026-03-01T23:30:00Z"), checkedIn("2026-03-09T10:00:00Z"))
+	insertTestAttendee(t, db, event.Id, users[1].Id, at("2026-03-02T09:00:00Z"), nil)
+	insertTestAttendee(t, db, event.Id, users[2].Id, at("2026-03-02T18:00:00+02:00"), checkedIn("2026-03-09T11:00:00Z"))
+	insertTestAttendee(t, db, event.Id, users[3].Id, at("2026-02-20T12:00:00Z"), nil)
+	//carol came back after cancelling, frank didn't
+	for _, c := range []struct {
+		user *User
+		at   time.Time
+	}{{users[1], at("2026-03-01T08:00:00Z")}, {users[4], at("2026-03-03T08:00:00Z")}} {
+		if _, err := db.Exec("insert into attendee_cancellations (event_id, user_id, cancelled_at) values (?,?,?)", event.Id, c.user.Id, c.at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		interval string
+		want     []StatsBucket
+	}{
+		{"days", IntervalDay, []StatsBucket{
+			{Start: "2026-03-01", Registrations: 1, Cancellations: 1},
+			{Start: "2026-03-02", Registrations: 2},
+			{Start: "2026-03-03", Cancellations: 1},
+			{Start: "2026-03-04"}, {Start: "2026-03-05"}, {Start: "2026-03-06"}, {Start: "2026-03-07"}, {Start: "2026-03-08"},
+			{Start: "2026-03-09", CheckIns: 2},
+		}},
+		{"weeks", IntervalWeek, []StatsBucket{
+			{Start: "2026-02-23", Registrations: 1, Cancellations: 1},
+			{Start: "2026-03-02", Registrations: 2, Cancellations: 1},
+			{Start: "2026-03-09", CheckIns: 2},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats, err := models.Stats.ForEvent(event.Id, tt.interval, at("2026-03-01T00:00:00Z"), at("2026-03-09T00:00:00Z"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(stats.Series, tt.want) {
+				t.Errorf("series = %+v, want %+v", stats.Series, tt.want)
+			}
+			want := RSVPBreakdown{Registered: 2, CheckedIn: 2, Cancelled: 1}
+			if stats.Attendees != 4 || stats.RSVP != want || stats.CheckInRate != 0.5 || stats.Cancellations != 2 {
+				t.Errorf("stats = %d attendees, %+v, rate %v, %d cancellations", stats.Attendees, stats.RSVP, stats.CheckInRate, stats.Cancellations)
+			}
+		})
+	}
+}
+
+func TestStatsForOwner(t *testing.T) {
+	db, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	other := insertTestUser(t, models, "mallory")
+	quiet := &Event{Name: "Quiet meetup", Description: "Nobody came", Location: "Cellar"}
+	busy := &Event{Name: "Busy meetup", Description: "Everybody came", Location: "Town hall"}
+	insertTestEvents(t, models, owner, quiet, busy)
+	foreign := &Event{Name: "Other meetup", Description: "Not alice's", Location: "Elsewhere"}
+	insertTestEvents(t, models, other, foreign)
+
+	registeredAt := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for _, name := range []string{"bob", "carol"} {
+		user := insertTestUser(t, models, name)
+		insertTestAttendee(t, db, busy.Id, user.Id, registeredAt, &registeredAt)
+		insertTestAttendee(t, db, foreign.Id, user.Id, registeredAt, nil)
+	}
+
+	stats, err := models.Stats.ForOwner(owner.Id, IntervalWeek, registeredAt, registeredAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Events != 2 || stats.Attendees != 2 || stats.CheckInRate != 1 {
+		t.Errorf("stats = %d events, %d attendees, rate %v, want only alice's events", stats.Events, stats.Attendees, stats.CheckInRate)
+	}
+	if want := []StatsBucket{{Start: "2026-03-02", Registrations: 2, CheckIns: 2}}; !slices.Equal(stats.Series, want) {
+		t.Errorf("series = %+v, want %+v", stats.Series, want)
+	}
+	if len(stats.TopEvents) != 2 || stats.TopEvents[0].EventId != busy.Id || stats.TopEvents[0].Attendees != 2 || stats.TopEvents[1].Attendees != 0 {
+		t.Errorf("top events = %+v, want the busy event first", stats.TopEvents)
+	}
+}
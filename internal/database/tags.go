@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type TagModel struct {
+	db *sql.DB
+}
+
+type Tag struct {
+	Slug   string `json:"slug"`
+	Events int    `json:"events"`
+}
+
+// Slugify lowercases a tag and joins its words with dashes, so "Go Meetup"
+// and "go-meetup" name the same tag.
+func Slugify(tag string) string {
+	words := strings.FieldsFunc(strings.ToLower(tag), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// NormalizeTags slugifies tags and drops empty and duplicate ones, keeping
+// the first occurrence of each.
+func NormalizeTags(tags []string) []string {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		slug := Slugify(tag)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		normalized = append(normalized, slug)
+	}
+	return normalized
+}
+
+// GetAll returns every tag in use with the number of events carrying it,
+// most used first.
+func (m *TagModel) GetAll() ([]*Tag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := `select t.slug, count(et.event_id) as events from tags t
+		join event_tags et on et.tag_id = t.id
+		group by t.id order by events desc, t.slug`
+
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tags := []*Tag{}
+	for rows.Next() {
+		var tag Tag
+		if err := rows.Scan(&tag.Slug, &tag.Events); err != nil {
+			return nil, err
+		}
+		tags = append(tags, &tag)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// setEventTags replaces the tags of an event. Tags must already be normalized.
+func setEventTags(ctx context.Context, tx *sql.Tx, eventId int, tags []string) error {
+	if _, err := tx.ExecContext(ctx, "delete from event_tags where event_id = ?", eventId); err != nil {
+		return err
+	}
+	for _, tag := range tags {
+		if _, err := tx.ExecContext(ctx, "insert into tags (slug) values (?) on conflict (slug) do nothing", tag); err != nil {
+			return err
+		}
+		query := "insert into event_tags (event_id, tag_id) select ?, id from tags where slug = ?"
+		if _, err := tx.ExecContext(ctx, query, eventId, tag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTags fills in the tags of the given events.
+func loadTags(ctx context.Context, db *sql.DB, events ...*Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	byId := map[int]*Event{}
+	placeholders := make([]string, 0, len(events))
+	args := make([]any, 0, len(events))
+	for _, event := range events {
+		event.Tags = []string{}
+		byId[event.Id] = event
+		placeholders = append(placeholders, "?")
+		args = append(args, event.Id)
+	}
+
+	query := "select et.event_id, t.slug from event_tags et join tags t on t.id = et.tag_id where et.event_id in (" +
+		strings.Join(placeholders, ",") + ") order by t.slug"
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var eventId int
+		var slug string
+		if err := rows.Scan(&eventId, &slug); err != nil {
+			return err
+		}
+		if event, ok := byId[eventId]; ok {
+			event.Tags = append(event.Tags, slug)
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want string
+	}{
+		{"go", "go"},
+		{"Go Meetup", "go-meetup"},
+		{"go-meetup", "go-meetup"},
+		{"  Go   meetup!  ", "go-meetup"},
+		{"C++ & Rust", "c-rust"},
+		{"Café Musik", "café-musik"},
+		{"2026 edition", "2026-edition"},
+		{"---", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := Slugify(tt.tag); got != tt.want {
+			t.Errorf("Slugify(%q) = %q, want %q", tt.tag, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"none", nil, []string{}},
+		{"slugified", []string{"Go Meetup", "Music"}, []string{"go-meetup", "music"}},
+		{"duplicates keep the first", []string{"music", "Go", "MUSIC", "go"}, []string{"music", "go"}},
+		{"empty dropped", []string{"", "!!", "art"}, []string{"art"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeTags(tt.tags); !slices.Equal(got, tt.want) {
+				t.Errorf("NormalizeTags(%q) = %q, want %q", tt.tags, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetByTags(t *testing.T) {
+	_, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	insertTestEvents(t, models, owner,
+		&Event{Name: "Jazz night", Description: "Live jazz downtown", Location: "Club", Tags: []string{"Music", "Night Life"}},
+		&Event{Name: "Rock concert", Description: "Loud guitars all evening", Location: "Arena", Tags: []string{"music"}},
+		&Event{Name: "Pub quiz", Description: "Questions and answers", Location: "Pub", Tags: []string{"night-life", "games"}},
+		&Event{Name: "Private party", Description: "Friends and family only", Location: "Home", Tags: []string{"music", "night life"}, Visibility: VisibilityPrivate},
+	)
+
+	tests := []struct {
+		name     string
+		tags     []string
+		matchAll bool
+		want     []string
+	}{
+		{"one tag", []string{"music"}, false, []string{"Jazz night", "Rock concert"}},
+		{"tags are normalized", []string{"NIGHT LIFE"}, false, []string{"Jazz night", "Pub quiz"}},
+		{"any tag", []string{"music", "games"}, false, []string{"Jazz night", "Rock concert", "Pub quiz"}},
+		{"all tags", []string{"music", "night-life"}, true, []string{"Jazz night"}},
+		{"all tags with duplicates", []string{"music", "Music", "night-life"}, true, []string{"Jazz night"}},
+		{"all tags, none has them", []string{"music", "games"}, true, []string{}},
+		{"unknown tag", []string{"sports"}, false, []string{}},
+		{"no tags", []string{"", "!!"}, false, []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, err := models.Events.GetByTags(tt.tags, tt.matchAll)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, event := range events {
+				got = append(got, event.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("GetByTags(%q, %v) = %q, want %q", tt.tags, tt.matchAll, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTagCounts(t *testing.T) {
+	_, models := newTestModels(t)
+	owner := insertTestUser(t, models, "alice")
+	jazz := &Event{Name: "Jazz night", Description: "Live jazz downtown", Location: "Club", Tags: []string{"Music", "Night Life"}}
+	insertTestEvents(t, models, owner, jazz,
+		&Event{Name: "Rock concert", Description: "Loud guitars all evening", Location: "Arena", Tags: []string{"music"}},
+	)
+
+	jazz.Tags = []string{"music", "Jazz"}
+	if err := models.Events.Update(jazz); err != nil {
+		t.Fatal(err)
+	}
+	tags, err := models.Tags.GetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]int{}
+	for _, tag := range tags {
+		got[tag.Slug] = tag.Events
+	}
+	want := map[string]int{"music": 2, "jazz": 1}
+	if len(got) != len(want) || got["music"] != want["music"] || got["jazz"] != want["jazz"] {
+		t.Errorf("tag counts = %v, want %v, without tags no event carries", got, want)
+	}
+	if tags[0].Slug != "music" {
+		t.Errorf("first tag = %q, want the most used one", tags[0].Slug)
+	}
+}
@@ -0,0 +1,454 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+const (
+	OrderPending   = "pending"
+	OrderConfirmed = "confirmed"
+	OrderCancelled = "cancelled"
+	OrderRefunded  = "refunded"
+)
+
+var (
+	ErrSoldOut        = errors.New("not enough tickets left")
+	ErrSalesClosed    = errors.New("ticket sales are closed")
+	ErrQuotaBelowSold = errors.New("quota is lower than the number of tickets sold")
+	ErrPendingOrders  = errors.New("too many unpaid orders")
+	ErrUnknownIntent  = errors.New("no order is paid by the payment intent")
+	ErrOrderClosed    = errors.New("order is no longer pending")
+)
+
+// MaxPendingOrders is the number of unpaid orders a user can hold at once.
+const MaxPendingOrders = 3
+
+type TicketTypeModel struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+type TicketType struct {
+	Id          int        `json:"id"`
+	EventId     int        `json:"eventId"`
+	Name        string     `json:"name" binding:"required,min=2"`
+	Description string     `json:"description"`
+	PriceCents  int        `json:"priceCents" binding:"min=0"`
+	Currency    string     `json:"currency" binding:"omitempty,len=3,uppercase"`
+	Quota       int        `json:"quota" binding:"required,min=1"`
+	SalesStart  *time.Time `json:"salesStart,omitempty"`
+	SalesEnd    *time.Time `json:"salesEnd,omitempty"`
+	Sold        int        `json:"sold"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
+
+// OnSale reports whether the ticket type's sales window is open at t.
+func (t *TicketType) OnSale(at time.Time) bool {
+	if t.SalesStart != nil && at.Before(*t.SalesStart) {
+		return false
+	}
+	if t.SalesEnd != nil && !at.Before(*t.SalesEnd) {
+		return false
+	}
+	return true
+}
+
+type OrderModel struct {
+	db  *sql.DB
+	ctx context.Context
+}
+
+type Order struct {
+	Id              int       `json:"id"`
+	TicketTypeId    int       `json:"ticketTypeId"`
+	EventId         int       `json:"eventId"`
+	UserId          int       `json:"userId"`
+	Quantity        int       `json:"quantity"`
+	Status          string    `json:"status"`
+	AmountCents     int       `json:"amountCents"`
+	Currency        string    `json:"currency"`
+	PaymentIntentId *string   `json:"paymentIntentId,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+	// RefundRequestedAt is when the refund of a confirmed order was first
+	// asked of the payment provider. It is set until the order is marked
+	// refunded, so an interrupted refund can be told apart and retried.
+	RefundRequestedAt *time.Time `json:"refundRequestedAt,omitempty"`
+}
+
+const orderColumns = "id, ticket_type_id, event_id, user_id, quantity, status, amount_cents, currency, payment_intent_id, created_at, refund_requested_at"
+
+func scanOrder(row interface{ Scan(...any) error }) (*Order, error) {
+	var order Order
+	var refundRequestedAt sql.NullTime
+	err := row.Scan(&order.Id, &order.TicketTypeId, &order.EventId, &order.UserId, &order.Quantity, &order.Status,
+		&order.AmountCents, &order.Currency, &order.PaymentIntentId, &order.CreatedAt, &refundRequestedAt)
+	if err != nil {
+		return nil, err
+	}
+	if refundRequestedAt.Valid {
+		order.RefundRequestedAt = &refundRequestedAt.Time
+	}
+	return &order, nil
+}
+
+// ticketTypeColumns lists the columns scanned by scanTicketType, including
+// the number of tickets held by pending and confirmed orders.
+const ticketTypeColumns = `t.id, t.event_id, t.name, t.description, t.price_cents, t.currency, t.quota, t.sales_start, t.sales_end, t.created_at,
+	(select coalesce(sum(o.quantity), 0) from orders o where o.ticket_type_id = t.id and o.status in ('pending', 'confirmed'))`
+
+func scanTicketType(row interface{ Scan(...any) error }) (*TicketType, error) {
+	var ticketType TicketType
+	err := row.Scan(&ticketType.Id, &ticketType.EventId, &ticketType.Name, &ticketType.Description, &ticketType.PriceCents, &ticketType.Currency,
+		&ticketType.Quota, &ticketType.SalesStart, &ticketType.SalesEnd, &ticketType.CreatedAt, &ticketType.Sold)
+	if err != nil {
+		return nil, err
+	}
+	return &ticketType, nil
+}
+
+func (m *TicketTypeModel) Insert(ticketType *TicketType) error {
+	ctx, cancel := m.traced("Insert")
+	defer cancel()
+
+	if ticketType.Currency == "" {
+		ticketType.Currency = "EUR"
+	}
+	ticketType.CreatedAt = time.Now().UTC()
+
+	query := "insert into ticket_types (event_id,name,description,price_cents,currency,quota,sales_start,sales_end,created_at) values (?,?,?,?,?,?,?,?,?)"
+	result, err := m.db.ExecContext(ctx, query, ticketType.EventId, ticketType.Name, ticketType.Description, ticketType.PriceCents,
+		ticketType.Currency, ticketType.Quota, ticketType.SalesStart, ticketType.SalesEnd, ticketType.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	ticketType.Id = int(id)
+	return nil
+}
+
+func (m *TicketTypeModel) Get(id int) (*TicketType, error) {
+	ctx, cancel := m.traced("Get")
+	defer cancel()
+
+	query := "select " + ticketTypeColumns + " from ticket_types t where t.id = ?"
+	ticketType, err := scanTicketType(m.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return ticketType, nil
+}
+
+func (m *TicketTypeModel) GetByEvent(eventId int) ([]*TicketType, error) {
+	ctx, cancel := m.traced("GetByEvent")
+	defer cancel()
+
+	query := "select " + ticketTypeColumns + " from ticket_types t where t.event_id = ? order by t.price_cents, t.id"
+	rows, err := m.db.QueryContext(ctx, query, eventId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ticketTypes := []*TicketType{}
+	for rows.Next() {
+		ticketType, err := scanTicketType(rows)
+		if err != nil {
+			return nil, err
+		}
+		ticketTypes = append(ticketTypes, ticketType)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return ticketTypes, nil
+}
+
+// Update stores a ticket type. The quota can't be lowered below the number
+// of tickets already sold.
+func (m *TicketTypeModel) Update(ticketType *TicketType) error {
+	ctx, cancel := m.traced("Update")
+	defer cancel()
+
+	if ticketType.Currency == "" {
+		ticketType.Currency = "EUR"
+	}
+
+	query := `update ticket_types set name=?, description=?, price_cents=?, currency=?, quota=?, sales_start=?, sales_end=?
+		where id=? and ? >= (select coalesce(sum(quantity), 0) from orders where ticket_type_id = ? and status in ('pending', 'confirmed'))`
+	result, err := m.db.ExecContext(ctx, query, ticketType.Name, ticketType.Description, ticketType.PriceCents, ticketType.Currency,
+		ticketType.Quota, ticketType.SalesStart, ticketType.SalesEnd, ticketType.Id, ticketType.Quota, ticketType.Id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return ErrQuotaBelowSold
+	}
+	return nil
+}
+
+func (m *TicketTypeModel) Delete(id int) error {
+	ctx, cancel := m.traced("Delete")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "delete from ticket_types where id = ?", id)
+	return err
+}
+
+// Claim places an order for tickets and registers the user as an attendee
+// of the event, returning the new attendee. The quota is checked by the
+// insert itself, so concurrent claims can never oversell a ticket type. Users
+// attending the event already get ErrAlreadyAttending. Free tickets also
+// count against the event's capacity, returning ErrEventFull once it is
+// reached. Orders of paid tickets stay pending, holding their tickets, until
+// ConfirmPayment registers the attendee; they return a nil attendee. Users
+// can only hold MaxPendingOrders of them, after that Claim returns
+// ErrPendingOrders.
+func (m *OrderModel) Claim(order *Order) (*Attendee, error) {
+	ctx, cancel := m.traced("Claim")
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//writing first takes the database write lock up front, so concurrent
+	//claims queue up instead of failing to upgrade a read lock
+	order.CreatedAt = time.Now().UTC()
+
+	query := `insert into orders (ticket_type_id, event_id, user_id, quantity, status, amount_cents, currency, created_at)
+		select t.id, t.event_id, ?, ?, case when t.price_cents > 0 then ? else ? end, t.price_cents * ?, t.currency, ? from ticket_types t
+		where t.id = ? and (select coalesce(sum(o.quantity), 0) from orders o where o.ticket_type_id = t.id and o.status in ('pending', 'confirmed')) + ? <= t.quota`
+	result, err := tx.ExecContext(ctx, query, order.UserId, order.Quantity, OrderPending, OrderConfirmed, order.Quantity, order.CreatedAt,
+		order.TicketTypeId, order.Quantity)
+	if err != nil {
+		return nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, err
+	} else if n == 0 {
+		return nil, ErrSoldOut
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	order.Id = int(id)
+
+	ticketType, err := scanTicketType(tx.QueryRowContext(ctx, "select "+ticketTypeColumns+" from ticket_types t where t.id = ?", order.TicketTypeId))
+	if err != nil {
+		return nil, err
+	}
+	if !ticketType.OnSale(order.CreatedAt) {
+		return nil, ErrSalesClosed
+	}
+	order.EventId = ticketType.EventId
+	order.AmountCents = ticketType.PriceCents * order.Quantity
+	order.Currency = ticketType.Currency
+
+	var attending bool
+	query = "select exists (select 1 from attendees where event_id = ? and user_id = ?)"
+	if err := tx.QueryRowContext(ctx, query, order.EventId, order.UserId).Scan(&attending); err != nil {
+		return nil, err
+	}
+	if attending {
+		return nil, ErrAlreadyAttending
+	}
+
+	order.Status = OrderConfirmed
+	if order.AmountCents > 0 {
+		order.Status = OrderPending
+		var pending int
+		query = "select count(*) from orders where user_id = ? and status = ?"
+		if err := tx.QueryRowContext(ctx, query, order.UserId, OrderPending).Scan(&pending); err != nil {
+			return nil, err
+		}
+		if pending > MaxPendingOrders {
+			return nil, ErrPendingOrders
+		}
+		return nil, tx.Commit()
+	}
+
+	attendee := &Attendee{UserId: order.UserId, EventId: order.EventId}
+	if err := insertAttendeeWithinCapacity(ctx, tx, attendee); err != nil {
+		return nil, err
+	}
+	return attendee, tx.Commit()
+}
+
+// registerAttendee adds the user of an order as an attendee of the event,
+// returning nil if they are registered already.
+func registerAttendee(ctx context.Context, tx *sql.Tx, order *Order) (*Attendee, error) {
+	attendee := &Attendee{UserId: order.UserId, EventId: order.EventId}
+	if err := insertAttendee(ctx, tx, attendee); errors.Is(err, ErrAlreadyAttending) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return attendee, nil
+}
+
+// SetPaymentIntent links a pending order to the provider's payment intent.
+func (m *OrderModel) SetPaymentIntent(orderId int, intentId string) error {
+	ctx, cancel := m.traced("SetPaymentIntent")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update orders set payment_intent_id = ? where id = ?", intentId, orderId)
+	return err
+}
+
+// ConfirmPayment confirms the pending order paid by a payment intent and
+// registers its user as an attendee. Confirming an order twice is a no-op, so
+// repeated provider webhooks are harmless. The returned attendee is nil unless
+// the user was newly registered. It returns ErrUnknownIntent if no order has
+// the intent, and the order with ErrOrderClosed if it was cancelled or
+// refunded before the payment came through, so the payment can be refunded.
+func (m *OrderModel) ConfirmPayment(intentId string) (*Order, *Attendee, error) {
+	ctx, cancel := m.traced("ConfirmPayment")
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "update orders set status = ? where payment_intent_id = ? and status = ?", OrderConfirmed, intentId, OrderPending)
+	if err != nil {
+		return nil, nil, err
+	}
+	order, err := scanOrder(tx.QueryRowContext(ctx, "select "+orderColumns+" from orders where payment_intent_id = ?", intentId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrUnknownIntent
+		}
+		return nil, nil, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return nil, nil, err
+	} else if n == 0 {
+		if order.Status != OrderConfirmed {
+			return order, nil, ErrOrderClosed
+		}
+		return order, nil, nil
+	}
+
+	attendee, err := registerAttendee(ctx, tx, order)
+	if err != nil {
+		return nil, nil, err
+	}
+	return order, attendee, tx.Commit()
+}
+
+// CancelPayment cancels the order of a payment intent that failed or was
+// abandoned, releasing its tickets, if it is still pending.
+func (m *OrderModel) CancelPayment(intentId string) error {
+	ctx, cancel := m.traced("CancelPayment")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update orders set status = ? where payment_intent_id = ? and status = ?", OrderCancelled, intentId, OrderPending)
+	return err
+}
+
+// GetPendingBefore returns the orders still pending that were created
+// before t, oldest first.
+func (m *OrderModel) GetPendingBefore(t time.Time) ([]*Order, error) {
+	ctx, cancel := m.traced("GetPendingBefore")
+	defer cancel()
+
+	query := "select " + orderColumns + " from orders where status = ? and created_at < ? order by created_at"
+	return m.query(ctx, query, OrderPending, t.UTC())
+}
+
+// Cancel cancels a pending order, releasing its tickets.
+func (m *OrderModel) Cancel(orderId int) error {
+	ctx, cancel := m.traced("Cancel")
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "update orders set status = ? where id = ? and status = ?", OrderCancelled, orderId, OrderPending)
+	return err
+}
+
+// GetPaidByEvent returns the confirmed orders of an event that were paid
+// through the payment provider.
+func (m *OrderModel) GetPaidByEvent(eventId int) ([]*Order, error) {
+	ctx, cancel := m.traced("GetPaidByEvent")
+	defer cancel()
+
+	query := "select " + orderColumns + " from orders where event_id = ? and status = ? and payment_intent_id is not null"
+	return m.query(ctx, query, eventId, OrderConfirmed)
+}
+
+// GetPaidByAttendee is GetPaidByEvent for the orders of a single user.
+func (m *OrderModel) GetPaidByAttendee(eventId, userId int) ([]*Order, error) {
+	ctx, cancel := m.traced("GetPaidByAttendee")
+	defer cancel()
+
+	query := "select " + orderColumns + " from orders where event_id = ? and user_id = ? and status = ? and payment_intent_id is not null"
+	return m.query(ctx, query, eventId, userId, OrderConfirmed)
+}
+
+// MarkRefundRequested records that a confirmed order is about to be
+// refunded, keeping the time of the first request.
+func (m *OrderModel) MarkRefundRequested(orderId int) error {
+	ctx, cancel := m.traced("MarkRefundRequested")
+	defer cancel()
+
+	query := "update orders set refund_requested_at = coalesce(refund_requested_at, ?) where id = ? and status = ?"
+	_, err := m.db.ExecContext(ctx, query, time.Now().UTC(), orderId, OrderConfirmed)
+	return err
+}
+
+// MarkRefunded records that the payment provider refunded a confirmed
+// order, or a payment that arrived after its order was cancelled.
+func (m *OrderModel) MarkRefunded(orderId int) error {
+	ctx, cancel := m.traced("MarkRefunded")
+	defer cancel()
+
+	query := "update orders set status = ? where id = ? and status in (?, ?)"
+	_, err := m.db.ExecContext(ctx, query, OrderRefunded, orderId, OrderConfirmed, OrderCancelled)
+	return err
+}
+
+func (m *OrderModel) GetByUser(userId int) ([]*Order, error) {
+	ctx, cancel := m.traced("GetByUser")
+	defer cancel()
+
+	query := "select " + orderColumns + " from orders where user_id = ? order by id desc"
+	return m.query(ctx, query, userId)
+}
+
+func (m *OrderModel) query(ctx context.Context, query string, args ...any) ([]*Order, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orders := []*Order{}
+	for rows.Next() {
+		order, err := scanOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+		orders = append(orders, order)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const queryTimeout = 3 * time.Second
+
+var tracer = otel.Tracer("github.com/anshbadoni30/event-management-app/internal/database")
+
+// WithContext returns the models with their queries bound to ctx, so they
+// are traced as part of the request or job the context belongs to. Queries
+// of models without a context start their own traces.
+func (m Models) WithContext(ctx context.Context) *Models {
+	m.Users.ctx = ctx
+	m.Events.ctx = ctx
+	m.Attendees.ctx = ctx
+	m.APIKeys.ctx = ctx
+	m.TicketTypes.ctx = ctx
+	m.Orders.ctx = ctx
+	m.Invites.ctx = ctx
+	m.Identities.ctx = ctx
+	m.OIDCLogins.ctx = ctx
+	return &m
+}
+
+// startQuery starts the span of a model query named like UserModel.Get, as
+// a child of the span in parent, and bounds the query by timeout. The
+// returned function ends both.
+func startQuery(parent context.Context, name string, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, span := tracer.Start(parent, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", "sqlite"),
+			attribute.String("db.operation.name", name),
+		))
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	return ctx, func() {
+		cancel()
+		span.End()
+	}
+}
+
+func (m *UserModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "UserModel."+name, queryTimeout)
+}
+
+func (m *EventModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "EventModel."+name, queryTimeout)
+}
+
+func (m *AttendeeModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "AttendeeModel."+name, queryTimeout)
+}
+
+func (m *APIKeyModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "APIKeyModel."+name, queryTimeout)
+}
+
+func (m *TicketTypeModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "TicketTypeModel."+name, queryTimeout)
+}
+
+func (m *OrderModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "OrderModel."+name, queryTimeout)
+}
+
+func (m *InviteModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "InviteModel."+name, queryTimeout)
+}
+
+func (m *IdentityModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "IdentityModel."+name, queryTimeout)
+}
+
+func (m *OIDCLoginModel) traced(name string) (context.Context, context.CancelFunc) {
+	return startQuery(m.ctx, "OIDCLoginModel."+name, queryTimeout)
+}
@@ -3,12 +3,17 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 )
 
+// ErrUserNotFound is returned when looking up a user who doesn't exist.
+var ErrUserNotFound = errors.New("no user found")
+
 type UserModel struct {
-	db *sql.DB
+	db  *sql.DB
+	ctx context.Context
 }
 
 type User struct {
@@ -16,15 +21,43 @@ type User struct {
 	Name     string `json:"name"`
 	Email    string `json:"email"`
 	Password string `json:"-"`
+	// HideFromAttendeeLists keeps the user's name off attendee lists shown
+	// to anyone but the event organizer.
+	HideFromAttendeeLists bool `json:"hideFromAttendeeLists"`
+	// EmailReminders is whether the user is emailed before events they attend.
+	EmailReminders bool `json:"emailReminders"`
+	IsAdmin        bool `json:"isAdmin"`
+	// DisabledAt is when an administrator disabled the account, which
+	// can't log in or use its tokens until it is enabled again.
+	DisabledAt *time.Time `json:"disabledAt,omitempty"`
+	// PasswordResetRequired locks the account out of everything but
+	// changing its password, after an administrator forced a reset.
+	PasswordResetRequired bool `json:"passwordResetRequired"`
+	// HasPassword is false for users who signed up with an identity
+	// provider and never set a password, so they can set one without
+	// giving a current password.
+	HasPassword bool `json:"hasPassword"`
+}
+
+// userColumns lists the columns scanned by scanUser.
+const userColumns = "id, name, email, password, hide_from_attendee_lists, email_reminders, is_admin, disabled_at, password_reset_required, has_password"
+
+func scanUser(row interface{ Scan(...any) error }, user *User) error {
+	var disabledAt sql.NullTime
+	err := row.Scan(&user.Id, &user.Name, &user.Email, &user.Password, &user.HideFromAttendeeLists, &user.EmailReminders, &user.IsAdmin, &disabledAt, &user.PasswordResetRequired, &user.HasPassword)
+	if disabledAt.Valid {
+		user.DisabledAt = &disabledAt.Time
+	}
+	return err
 }
 
 func (e *UserModel) Insert(user *User) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := e.traced("Insert")
 	defer cancel()
 
-	query := "INSERT INTO users (email,password,name) VALUES (?,?,?)"
+	query := "INSERT INTO users (email,password,name,hide_from_attendee_lists,email_reminders) VALUES (?,?,?,?,?)"
 
-	result, err := e.db.ExecContext(ctx, query, user.Email, user.Password, user.Name)
+	result, err := e.db.ExecContext(ctx, query, user.Email, user.Password, user.Name, user.HideFromAttendeeLists, user.EmailReminders)
 	if err != nil {
 		return err
 	}
@@ -35,19 +68,20 @@ func (e *UserModel) Insert(user *User) error {
 	}
 
 	user.Id = int(id)
+	user.HasPassword = true
 	return nil
 }
 
 func (e *UserModel) Get(id int) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := e.traced("Get")
 	defer cancel()
 
-	query := "select id, name,email from users where id=?"
+	query := "select " + userColumns + " from users where id=?"
 	var user User
-	err := e.db.QueryRowContext(ctx, query, id).Scan(&user.Id, &user.Name, &user.Email)
+	err := scanUser(e.db.QueryRowContext(ctx, query, id), &user)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("no user found with id %s", fmt.Sprint(id))
+			return nil, fmt.Errorf("%w with id %d", ErrUserNotFound, id)
 		}
 		return nil, err
 	}
@@ -55,17 +89,145 @@ func (e *UserModel) Get(id int) (*User, error) {
 }
 
 func (e *UserModel) GetByEmail(email string) (*User, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	ctx, cancel := e.traced("GetByEmail")
 	defer cancel()
 
-	query := "select * from users where email=?"
+	query := "select " + userColumns + " from users where email=?"
 	var user User
-	err := e.db.QueryRowContext(ctx, query, email).Scan(&user.Id, &user.Name, &user.Email,&user.Password)
+	err := scanUser(e.db.QueryRowContext(ctx, query, email), &user)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("no user found with email %s", fmt.Sprint(email))
+			return nil, fmt.Errorf("%w with email %s", ErrUserNotFound, email)
 		}
 		return nil, err
 	}
 	return &user, nil
 }
+
+// GetAll returns every user, with their password hashes, in the order they
+// registered.
+func (e *UserModel) GetAll() ([]*User, error) {
+	ctx, cancel := e.traced("GetAll")
+	defer cancel()
+
+	query := "select " + userColumns + " from users order by id"
+	rows, err := e.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, err
+		}
+		users = append(users, &user)
+	}
+	return users, rows.Err()
+}
+
+func (e *UserModel) SetHideFromAttendeeLists(id int, hide bool) error {
+	ctx, cancel := e.traced("SetHideFromAttendeeLists")
+	defer cancel()
+
+	query := "update users set hide_from_attendee_lists=? where id=?"
+	_, err := e.db.ExecContext(ctx, query, hide, id)
+	return err
+}
+
+func (e *UserModel) SetEmailReminders(id int, enabled bool) error {
+	ctx, cancel := e.traced("SetEmailReminders")
+	defer cancel()
+
+	query := "update users set email_reminders=? where id=?"
+	_, err := e.db.ExecContext(ctx, query, enabled, id)
+	return err
+}
+
+// Search returns a page of users whose name or email contains query, or
+// all users if it's empty, with the total number of matches. status filters
+// them to "active" or "disabled" accounts unless it's empty.
+func (e *UserModel) Search(query, status string, limit, offset int) ([]*User, int, error) {
+	ctx, cancel := e.traced("Search")
+	defer cancel()
+
+	where := " where (? = '' or instr(lower(name), lower(?)) > 0 or instr(lower(email), lower(?)) > 0)" +
+		" and (? = '' or (? = 'active' and disabled_at is null) or (? = 'disabled' and disabled_at is not null))"
+	args := []any{query, query, query, status, status, status}
+
+	var total int
+	if err := e.db.QueryRowContext(ctx, "select count(*) from users"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := e.db.QueryContext(ctx, "select "+userColumns+" from users"+where+" order by id limit ? offset ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	users := []*User{}
+	for rows.Next() {
+		var user User
+		if err := scanUser(rows, &user); err != nil {
+			return nil, 0, err
+		}
+		users = append(users, &user)
+	}
+	return users, total, rows.Err()
+}
+
+// SetDisabled disables or enables an account, recording audit, if given, in
+// the same transaction. Disabling an account that is disabled already keeps
+// the original time.
+func (e *UserModel) SetDisabled(id int, disabled bool, audit *AdminAction) error {
+	ctx, cancel := e.traced("SetDisabled")
+	defer cancel()
+
+	query := "update users set disabled_at = null where id = ?"
+	args := []any{id}
+	if disabled {
+		query = "update users set disabled_at = coalesce(disabled_at, ?) where id = ?"
+		args = []any{time.Now().UTC(), id}
+	}
+	return e.execAudited(ctx, audit, query, args...)
+}
+
+// RequirePasswordReset locks the account out of everything but changing
+// its password, recording audit, if given, in the same transaction.
+func (e *UserModel) RequirePasswordReset(id int, audit *AdminAction) error {
+	ctx, cancel := e.traced("RequirePasswordReset")
+	defer cancel()
+
+	return e.execAudited(ctx, audit, "update users set password_reset_required = 1 where id = ?", id)
+}
+
+// execAudited runs an update together with recording the admin action that
+// made it.
+func (e *UserModel) execAudited(ctx context.Context, audit *AdminAction, query string, args ...any) error {
+	tx, err := e.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+	if err := insertAdminAction(ctx, tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// SetPassword replaces the password hash of a user, which satisfies a
+// required password reset.
+func (e *UserModel) SetPassword(id int, hash string) error {
+	ctx, cancel := e.traced("SetPassword")
+	defer cancel()
+
+	_, err := e.db.ExecContext(ctx, "update users set password = ?, password_reset_required = 0, has_password = 1 where id = ?", hash, id)
+	return err
+}
@@ -0,0 +1,264 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+type WebhookModel struct {
+	db *sql.DB
+}
+
+type Webhook struct {
+	Id         int       `json:"id"`
+	OwnerId    int       `json:"ownerId"`
+	EventId    *int      `json:"eventId,omitempty"`
+	Url        string    `json:"url"`
+	Secret     string    `json:"-"`
+	EventTypes []string  `json:"eventTypes"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// Accepts reports whether the webhook is subscribed to the given event type.
+// An empty filter means every event type is delivered.
+func (w *Webhook) Accepts(eventType string) bool {
+	if len(w.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range w.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDelivery struct {
+	Id             int        `json:"id"`
+	WebhookId      int        `json:"webhookId"`
+	EventType      string     `json:"eventType"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	LastError      string     `json:"lastError"`
+	ResponseStatus int        `json:"responseStatus"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+}
+
+const webhookColumns = "id,owner_id,event_id,url,secret,event_types,active,created_at"
+
+func scanWebhook(row interface{ Scan(...any) error }) (*Webhook, error) {
+	var webhook Webhook
+	var eventId sql.NullInt64
+	var eventTypes string
+	err := row.Scan(&webhook.Id, &webhook.OwnerId, &eventId, &webhook.Url, &webhook.Secret, &eventTypes, &webhook.Active, &webhook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if eventId.Valid {
+		id := int(eventId.Int64)
+		webhook.EventId = &id
+	}
+	webhook.EventTypes = []string{}
+	if eventTypes != "" {
+		webhook.EventTypes = strings.Split(eventTypes, ",")
+	}
+	return &webhook, nil
+}
+
+func (m *WebhookModel) Insert(webhook *Webhook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "insert into webhooks (owner_id,event_id,url,secret,event_types,active,created_at) values (?,?,?,?,?,?,?)"
+	webhook.CreatedAt = time.Now().UTC()
+	result, err := m.db.ExecContext(ctx, query, webhook.OwnerId, webhook.EventId, webhook.Url, webhook.Secret, strings.Join(webhook.EventTypes, ","), webhook.Active, webhook.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	webhook.Id = int(id)
+	return nil
+}
+
+func (m *WebhookModel) Get(id int) (*Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "select " + webhookColumns + " from webhooks where id=?"
+	webhook, err := scanWebhook(m.db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (m *WebhookModel) GetByOwner(ownerId int) ([]*Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "select " + webhookColumns + " from webhooks where owner_id=? order by id"
+	return m.query(ctx, query, ownerId)
+}
+
+// GetSubscribers returns the active webhooks that should receive a
+// notification about an event: the owner's account-wide webhooks plus the
+// ones registered on that particular event.
+func (m *WebhookModel) GetSubscribers(ownerId, eventId int, eventType string) ([]*Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "select " + webhookColumns + " from webhooks where active=1 and ((owner_id=? and event_id is null) or event_id=?)"
+	webhooks, err := m.query(ctx, query, ownerId, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	var subscribers []*Webhook
+	for _, webhook := range webhooks {
+		if webhook.Accepts(eventType) {
+			subscribers = append(subscribers, webhook)
+		}
+	}
+	return subscribers, nil
+}
+
+func (m *WebhookModel) Delete(id int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, "delete from webhooks where id=?", id)
+	return err
+}
+
+func (m *WebhookModel) query(ctx context.Context, query string, args ...any) ([]*Webhook, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []*Webhook{}
+	for rows.Next() {
+		webhook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+const deliveryColumns = "id,webhook_id,event_type,payload,status,attempts,next_attempt_at,last_error,response_status,created_at,delivered_at"
+
+func scanDelivery(row interface{ Scan(...any) error }) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	var deliveredAt sql.NullTime
+	err := row.Scan(&delivery.Id, &delivery.WebhookId, &delivery.EventType, &delivery.Payload, &delivery.Status, &delivery.Attempts,
+		&delivery.NextAttemptAt, &delivery.LastError, &delivery.ResponseStatus, &delivery.CreatedAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+	if deliveredAt.Valid {
+		delivery.DeliveredAt = &deliveredAt.Time
+	}
+	return &delivery, nil
+}
+
+func (m *WebhookModel) InsertDelivery(delivery *WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	now := time.Now().UTC()
+	delivery.Status = DeliveryPending
+	delivery.CreatedAt = now
+	delivery.NextAttemptAt = now
+
+	query := "insert into webhook_deliveries (webhook_id,event_type,payload,status,next_attempt_at,created_at) values (?,?,?,?,?,?)"
+	result, err := m.db.ExecContext(ctx, query, delivery.WebhookId, delivery.EventType, delivery.Payload, delivery.Status, delivery.NextAttemptAt, delivery.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return err
+	}
+	delivery.Id = int(id)
+	return nil
+}
+
+// GetDueDeliveries returns pending deliveries whose next attempt is due,
+// oldest first.
+func (m *WebhookModel) GetDueDeliveries(now time.Time, limit int) ([]*WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "select " + deliveryColumns + " from webhook_deliveries where status=? and next_attempt_at<=? order by next_attempt_at limit ?"
+	return m.queryDeliveries(ctx, query, DeliveryPending, now.UTC(), limit)
+}
+
+func (m *WebhookModel) GetDeliveries(webhookId int) ([]*WebhookDelivery, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "select " + deliveryColumns + " from webhook_deliveries where webhook_id=? order by id desc limit 100"
+	return m.queryDeliveries(ctx, query, webhookId)
+}
+
+// UpdateDelivery stores the outcome of a delivery attempt.
+func (m *WebhookModel) UpdateDelivery(delivery *WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	query := "update webhook_deliveries set status=?,attempts=?,next_attempt_at=?,last_error=?,response_status=?,delivered_at=? where id=?"
+	_, err := m.db.ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.NextAttemptAt.UTC(), delivery.LastError,
+		delivery.ResponseStatus, delivery.DeliveredAt, delivery.Id)
+	if err != nil {
+		return fmt.Errorf("updating delivery %d: %w", delivery.Id, err)
+	}
+	return nil
+}
+
+func (m *WebhookModel) queryDeliveries(ctx context.Context, query string, args ...any) ([]*WebhookDelivery, error) {
+	rows, err := m.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*WebhookDelivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
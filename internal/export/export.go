@@ -0,0 +1,65 @@
+// Package export writes tables as CSV or XLSX files, one row at a time, so
+// large tables can be streamed to a client without holding them in memory.
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// Writer writes the rows of a table. Cells are strings, ints, times or nil
+// for an empty cell. Close must be called after the last row; for XLSX the
+// file is unreadable without it.
+type Writer interface {
+	Write(row []any) error
+	Close() error
+}
+
+// NewWriter returns a writer of the given format.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSV(w), nil
+	case FormatXLSX:
+		return NewXLSX(w)
+	}
+	return nil, fmt.Errorf("%w %q", ErrUnknownFormat, format)
+}
+
+// ContentType returns the MIME type of a format.
+func ContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/octet-stream"
+}
+
+// formatCell formats a cell as text, for formats without typed cells.
+func formatCell(cell any) string {
+	switch v := cell.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.UTC().Format(time.RFC3339)
+	}
+	return fmt.Sprint(cell)
+}
@@ -0,0 +1,155 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+var registeredAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600))
+
+func TestCSV(t *testing.T) {
+	var nilTime *time.Time
+	tests := []struct {
+		name string
+		row  []any
+		want string
+	}{
+		{"text", []any{"Alice", "alice@example.com"}, "Alice,alice@example.com\n"},
+		{"quoted", []any{`Bob "the builder", Jr.`}, "\"Bob \"\"the builder\"\", Jr.\"\n"},
+		{"numbers", []any{42, -1}, "42,-1\n"},
+		{"times in UTC", []any{registeredAt, &registeredAt}, "2026-03-01T11:00:00Z,2026-03-01T11:00:00Z\n"},
+		{"empty cells", []any{nil, nilTime, ""}, ",,\n"},
+		{"formulas escaped", []any{"=SUM(A1:A9)", "+1", "-1", "@cmd", "a=b"}, "'=SUM(A1:A9),'+1,'-1,'@cmd,a=b\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewCSV(&buf)
+			if err := w.Write(tt.row); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("CSV row = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// xlsxCell is a cell of a sheet as read back from an XLSX file.
+type xlsxCell struct {
+	Type   string `xml:"t,attr"`
+	Style  string `xml:"s,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+// readXLSX returns the cells of the sheet of a workbook, checking that every
+// part the workbook refers to is there.
+func readXLSX(t *testing.T, data []byte) [][]xlsxCell {
+	t.Helper()
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]*zip.File{}
+	for _, f := range z.File {
+		files[f.Name] = f
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if files[name] == nil {
+			t.Fatalf("workbook has no %s", name)
+		}
+	}
+	f, err := files["xl/worksheets/sheet1.xml"].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var sheet struct {
+		Rows []struct {
+			Cells []xlsxCell `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.NewDecoder(f).Decode(&sheet); err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]xlsxCell{}
+	for _, row := range sheet.Rows {
+		rows = append(rows, row.Cells)
+	}
+	return rows
+}
+
+func TestXLSX(t *testing.T) {
+	var nilTime *time.Time
+	tests := []struct {
+		name string
+		cell any
+		want xlsxCell
+	}{
+		{"text", "Alice", xlsxCell{Type: "inlineStr", Inline: "Alice"}},
+		{"markup escaped", "<b>Tom & Jerry</b>", xlsxCell{Type: "inlineStr", Inline: "<b>Tom & Jerry</b>"}},
+		{"spaces kept", "  padded  ", xlsxCell{Type: "inlineStr", Inline: "  padded  "}},
+		{"formulas are text", "=SUM(A1:A9)", xlsxCell{Type: "inlineStr", Inline: "=SUM(A1:A9)"}},
+		{"number", 42, xlsxCell{Value: "42"}},
+		{"time as a date serial", registeredAt, xlsxCell{Style: "1", Value: "46082.458333333336"}},
+		{"time pointer", &registeredAt, xlsxCell{Style: "1", Value: "46082.458333333336"}},
+		{"excel epoch", time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC), xlsxCell{Style: "1", Value: "0"}},
+		{"nil", nil, xlsxCell{}},
+		{"nil time", nilTime, xlsxCell{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := NewXLSX(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write([]any{"header"}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Write([]any{tt.cell}); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			rows := readXLSX(t, buf.Bytes())
+			if len(rows) != 2 || len(rows[1]) != 1 {
+				t.Fatalf("sheet has rows %+v, want a header and one cell", rows)
+			}
+			if got := rows[1][0]; got != tt.want {
+				t.Errorf("cell = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewWriter(t *testing.T) {
+	tests := []struct {
+		format      string
+		wantErr     error
+		contentType string
+	}{
+		{FormatCSV, nil, "text/csv; charset=utf-8"},
+		{FormatXLSX, nil, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		{"pdf", ErrUnknownFormat, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		w, err := NewWriter(tt.format, io.Discard)
+		if !errors.Is(err, tt.wantErr) || (err == nil) != (w != nil) {
+			t.Errorf("NewWriter(%q) = %v, %v, want error %v", tt.format, w, err, tt.wantErr)
+		}
+		if got := ContentType(tt.format); got != tt.contentType {
+			t.Errorf("ContentType(%q) = %q, want %q", tt.format, got, tt.contentType)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package jobs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+const (
+	defaultWorkers      = 4
+	defaultLeaseTimeout = 5 * time.Minute
+	pollInterval        = 2 * time.Second
+	baseBackoff         = 30 * time.Second
+	maxBackoff          = time.Hour
+)
+
+// Handler runs one job. Returning an error retries the job with backoff
+// until it runs out of attempts. The context ends when the job's lease does,
+// or when the queue shuts down.
+type Handler func(ctx context.Context, job *database.Job) error
+
+// Options control how a job is enqueued. The zero value runs the job as soon
+// as possible with the default priority and attempts.
+type Options struct {
+	RunAt       time.Time
+	Priority    int
+	MaxAttempts int
+	// DedupeKey makes enqueueing idempotent: a second job with the same key
+	// is dropped.
+	DedupeKey string
+}
+
+// Queue runs jobs stored in the database on a pool of workers. Jobs are
+// leased, so a job whose worker dies is picked up again once its lease
+// times out, and a job is never run by two workers at once.
+type Queue struct {
+	jobs     *database.JobModel
+	handlers map[string]Handler
+	types    []string
+	id       string
+	wake     chan struct{}
+
+	Workers      int
+	LeaseTimeout time.Duration
+}
+
+func NewQueue(jobs *database.JobModel) *Queue {
+	host, _ := os.Hostname()
+	buf := make([]byte, 4)
+	rand.Read(buf)
+	return &Queue{
+		jobs:         jobs,
+		handlers:     map[string]Handler{},
+		id:           fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(buf)),
+		wake:         make(chan struct{}, 1),
+		Workers:      defaultWorkers,
+		LeaseTimeout: defaultLeaseTimeout,
+	}
+}
+
+// Register sets the handler of a job type. It must be called before Run.
+func (q *Queue) Register(jobType string, handler Handler) {
+	if _, ok := q.handlers[jobType]; !ok {
+		q.types = append(q.types, jobType)
+	}
+	q.handlers[jobType] = handler
+}
+
+// Enqueue stores a job with a JSON encoded payload. It reports false if the
+// job was dropped because of its dedupe key.
+func (q *Queue) Enqueue(jobType string, payload any, opts Options) (bool, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, err
+	}
+	job := database.Job{
+		Type:        jobType,
+		Payload:     string(body),
+		Priority:    opts.Priority,
+		MaxAttempts: opts.MaxAttempts,
+		RunAt:       opts.RunAt,
+	}
+	if opts.DedupeKey != "" {
+		job.DedupeKey = &opts.DedupeKey
+	}
+	created, err := q.jobs.Enqueue(&job)
+	if err != nil {
+		return false, err
+	}
+	if created && !job.RunAt.After(time.Now()) {
+		q.Wake()
+	}
+	return created, nil
+}
+
+// Wake makes an idle worker look for due jobs right away.
+func (q *Queue) Wake() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run works off jobs until ctx is cancelled. Jobs still running then are
+// interrupted and returned to the queue without counting the attempt, and
+// Run waits for their workers to finish.
+func (q *Queue) Run(ctx context.Context) {
+	var workers sync.WaitGroup
+	for i := 0; i < q.Workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			q.work(ctx, fmt.Sprintf("%s/%d", q.id, i))
+		}()
+	}
+	workers.Wait()
+}
+
+func (q *Queue) work(ctx context.Context, worker string) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		for ctx.Err() == nil {
+			job, err := q.jobs.Lease(q.types, worker, time.Now(), q.LeaseTimeout)
+			if err != nil {
+				log.Printf("jobs: leasing a job: %v", err)
+				break
+			}
+			if job == nil {
+				break
+			}
+			q.run(ctx, worker, job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, worker string, job *database.Job) {
+	jobCtx, cancel := context.WithTimeout(ctx, q.LeaseTimeout)
+	defer cancel()
+
+	err := q.call(jobCtx, job)
+	if err != nil && ctx.Err() != nil && errors.Is(err, context.Canceled) {
+		if err := q.jobs.Release(job, worker); err != nil {
+			log.Printf("jobs: releasing job %d: %v", job.Id, err)
+		}
+		return
+	}
+
+	var held bool
+	switch {
+	case err == nil:
+		held, err = q.jobs.Complete(job, worker)
+	case job.Attempts >= job.MaxAttempts:
+		log.Printf("jobs: job %d (%s) failed for good after %d attempts: %v", job.Id, job.Type, job.Attempts, err)
+		held, err = q.jobs.Fail(job, worker, err.Error(), nil)
+	default:
+		log.Printf("jobs: job %d (%s) attempt %d failed: %v", job.Id, job.Type, job.Attempts, err)
+		retryAt := time.Now().Add(Backoff(job.Attempts))
+		held, err = q.jobs.Fail(job, worker, err.Error(), &retryAt)
+	}
+	if err != nil {
+		log.Printf("jobs: saving job %d: %v", job.Id, err)
+	} else if !held {
+		log.Printf("jobs: lease of job %d expired before it finished", job.Id)
+	}
+}
+
+// call runs the job's handler, turning panics into errors so one bad job
+// doesn't take the server down.
+func (q *Queue) call(ctx context.Context, job *database.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	handler, ok := q.handlers[job.Type]
+	if !ok {
+		return fmt.Errorf("no handler for job type %q", job.Type)
+	}
+	return handler(ctx, job)
+}
+
+// Backoff returns the delay before retrying a job that failed attempts
+// times: 30s, 1m, 2m, ... up to an hour.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 20 {
+		return maxBackoff
+	}
+	return min(baseBackoff<<(attempts-1), maxBackoff)
+}
@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+// newTestQueue returns a queue over a freshly migrated database.
+func newTestQueue(t *testing.T) *Queue {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if _, _, err := database.Migrate(path, migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	models := database.NewModels(db)
+	return NewQueue(&models.Jobs)
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{-1, 30 * time.Second},
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{7, 32 * time.Minute},
+		{8, time.Hour},
+		{20, time.Hour},
+		{64, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestRun(t *testing.T) {
+	failing := errors.New("smtp down")
+	tests := []struct {
+		name        string
+		handler     Handler
+		maxAttempts int
+		// shutdown cancels the queue's context while the job runs.
+		shutdown     bool
+		wantStatus   string
+		wantAttempts int
+		wantError    string
+		wantRetry    time.Duration
+	}{
+		{"success", func(ctx context.Context, job *database.Job) error { return nil }, 3, false, database.JobDone, 1, "", 0},
+		{"failure is retried with backoff", func(ctx context.Context, job *database.Job) error { return failing }, 3, false, database.JobPending, 1, "smtp down", 30 * time.Second},
+		{"last failure is a dead letter", func(ctx context.Context, job *database.Job) error { return failing }, 1, false, database.JobDead, 1, "smtp down", 0},
+		{"panic is a failure", func(ctx context.Context, job *database.Job) error { panic("nil map") }, 3, false, database.JobPending, 1, "panic: nil map", 30 * time.Second},
+		{"shutdown releases the job", func(ctx context.Context, job *database.Job) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}, 1, true, database.JobPending, 0, "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queue := newTestQueue(t)
+			queue.Register("mail", tt.handler)
+			if _, err := queue.Enqueue("mail", map[string]string{"to": "alice@example.com"}, Options{MaxAttempts: tt.maxAttempts}); err != nil {
+				t.Fatal(err)
+			}
+			job, err := queue.jobs.Lease(queue.types, "worker", time.Now(), time.Minute)
+			if err != nil || job == nil {
+				t.Fatalf("Lease() = %v, %v", job, err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.shutdown {
+				cancel()
+			}
+			failedAt := time.Now()
+			queue.run(ctx, "worker", job)
+
+			stored, err := queue.jobs.Get(job.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Status != tt.wantStatus || stored.Attempts != tt.wantAttempts {
+				t.Errorf("job is %s after %d attempts, want %s after %d", stored.Status, stored.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if !strings.HasPrefix(stored.LastError, tt.wantError) || (tt.wantError == "") != (stored.LastError == "") {
+				t.Errorf("last error = %q, want %q", stored.LastError, tt.wantError)
+			}
+			if tt.wantRetry > 0 {
+				if wait := stored.RunAt.Sub(failedAt); wait < tt.wantRetry-time.Second || wait > tt.wantRetry+time.Second {
+					t.Errorf("retried in %v, want %v", wait, tt.wantRetry)
+				}
+			}
+		})
+	}
+}
+
+func TestRunUnknownType(t *testing.T) {
+	queue := newTestQueue(t)
+	queue.Register("mail", func(ctx context.Context, job *database.Job) error { return nil })
+	if _, err := queue.Enqueue("mail", nil, Options{MaxAttempts: 1}); err != nil {
+		t.Fatal(err)
+	}
+	job, err := queue.jobs.Lease(queue.types, "worker", time.Now(), time.Minute)
+	if err != nil || job == nil {
+		t.Fatalf("Lease() = %v, %v", job, err)
+	}
+
+	//a job left over from a removed handler fails rather than crashing
+	job.Type = "sms"
+	queue.run(context.Background(), "worker", job)
+	stored, err := queue.jobs.Get(job.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != database.JobDead || stored.LastError != `no handler for job type "sms"` {
+		t.Errorf("job is %s with %q, want dead for lack of a handler", stored.Status, stored.LastError)
+	}
+}
@@ -0,0 +1,220 @@
+// Package oidc signs users in with an OpenID Connect provider, using the
+// authorization code flow with PKCE. The provider's endpoints are found
+// through discovery, and ID tokens are verified against its published keys.
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// leeway allows for clocks differing between the provider and the server.
+const leeway = time.Minute
+
+var ErrInvalidToken = errors.New("invalid ID token")
+
+// Config identifies the server as a client of a provider.
+type Config struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// RedirectURL is where the provider sends users back to with a code.
+	RedirectURL string
+	Scopes      []string
+}
+
+// Identity is who the provider says signed in. Subject identifies them at
+// the provider and never changes; the email address may.
+type Identity struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider talks to an OpenID Connect provider. Its endpoints are
+// discovered on first use, so the server starts even while the provider is
+// down.
+type Provider struct {
+	config Config
+	client *http.Client
+
+	mu       sync.Mutex
+	metadata *metadata
+	keys     *keySet
+}
+
+type metadata struct {
+	Issuer                        string   `json:"issuer"`
+	AuthorizationEndpoint         string   `json:"authorization_endpoint"`
+	TokenEndpoint                 string   `json:"token_endpoint"`
+	JWKSURI                       string   `json:"jwks_uri"`
+	CodeChallengeMethodsSupported []string `json:"code_challenge_methods_supported"`
+}
+
+func New(config Config) *Provider {
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid"}
+	}
+	if !slices.Contains(config.Scopes, "openid") {
+		config.Scopes = append([]string{"openid"}, config.Scopes...)
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	return &Provider{
+		config: config,
+		client: client,
+		keys:   &keySet{client: client},
+	}
+}
+
+// Issuer is the identifier of the provider, which subjects are unique in.
+func (p *Provider) Issuer() string {
+	return p.config.Issuer
+}
+
+// AuthURL returns the URL of the provider's login page. state is sent back
+// with the code, nonce ends up in the ID token and verifier is kept secret
+// until the code is exchanged.
+func (p *Provider) AuthURL(ctx context.Context, state, nonce, verifier string) (string, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("response_type", "code")
+	query.Set("client_id", p.config.ClientID)
+	query.Set("redirect_uri", p.config.RedirectURL)
+	query.Set("scope", strings.Join(p.config.Scopes, " "))
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	query.Set("code_challenge", Challenge(verifier))
+	query.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(m.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return m.AuthorizationEndpoint + separator + query.Encode(), nil
+}
+
+// Exchange redeems an authorization code and returns the identity in the
+// verified ID token, which must carry nonce.
+func (p *Provider) Exchange(ctx context.Context, code, verifier, nonce string) (*Identity, error) {
+	m, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.config.RedirectURL)
+	form.Set("code_verifier", verifier)
+	form.Set("client_id", p.config.ClientID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	//public clients have no secret and only prove themselves with PKCE
+	if p.config.ClientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+	}
+
+	var token struct {
+		IdToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	status, err := getJSON(p.client, req, &token)
+	if err != nil {
+		return nil, fmt.Errorf("exchanging the code: %w", err)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("exchanging the code: %d %s: %s", status, token.Error, token.ErrorDescription)
+	}
+	if token.IdToken == "" {
+		return nil, errors.New("exchanging the code: the response has no ID token")
+	}
+	return p.verify(ctx, m, token.IdToken, nonce, time.Now())
+}
+
+// discover fetches the provider's configuration, once it succeeds.
+func (p *Provider) discover(ctx context.Context) (*metadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.metadata != nil {
+		return p.metadata, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(p.config.Issuer, "/")+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	var m metadata
+	status, err := getJSON(p.client, req, &m)
+	if err == nil && status != http.StatusOK {
+		err = fmt.Errorf("status %d", status)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("discovering %s: %w", p.config.Issuer, err)
+	}
+	switch {
+	case m.Issuer != p.config.Issuer:
+		return nil, fmt.Errorf("discovering %s: the provider calls itself %q", p.config.Issuer, m.Issuer)
+	case m.AuthorizationEndpoint == "" || m.TokenEndpoint == "" || m.JWKSURI == "":
+		return nil, fmt.Errorf("discovering %s: endpoints are missing", p.config.Issuer)
+	case len(m.CodeChallengeMethodsSupported) > 0 && !slices.Contains(m.CodeChallengeMethodsSupported, "S256"):
+		return nil, fmt.Errorf("discovering %s: the provider doesn't support PKCE with S256", p.config.Issuer)
+	}
+	p.metadata = &m
+	p.keys.url = m.JWKSURI
+	return p.metadata, nil
+}
+
+// getJSON sends a request and decodes its JSON response whatever the
+// status, as errors have a JSON body too.
+func getJSON(client *http.Client, req *http.Request, result any) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return 0, err
+	}
+	if err := json.Unmarshal(body, result); err != nil && resp.StatusCode == http.StatusOK {
+		return resp.StatusCode, err
+	}
+	return resp.StatusCode, nil
+}
+
+// RandomString returns a random URL-safe string, for states, nonces and
+// PKCE verifiers.
+func RandomString() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Challenge derives the S256 PKCE challenge of a verifier.
+func Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
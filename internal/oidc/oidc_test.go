@@ -0,0 +1,144 @@
+package oidc_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/internal/oidc"
+	"github.com/anshbadoni30/event-management-app/internal/oidc/oidctest"
+)
+
+const redirectURL = "http://localhost:8080/api/v1/auth/oidc/callback"
+
+// provider is a test provider whose login and token endpoints can be taken
+// over by an impostor with keys of its own, while its published keys stay.
+type provider struct {
+	*oidctest.Server
+	url   string
+	login atomic.Pointer[oidctest.Server]
+}
+
+func newProvider(t *testing.T) *provider {
+	t.Helper()
+	p := &provider{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/jwks" || r.URL.Path == "/.well-known/openid-configuration" {
+			p.Server.ServeHTTP(w, r)
+			return
+		}
+		p.login.Load().ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+	p.url = server.URL
+
+	var err error
+	p.Server, err = oidctest.NewServer(server.URL, "events", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.login.Store(p.Server)
+	return p
+}
+
+// signIn runs the login of the default user up to the redirect back and
+// returns the code in it.
+func signIn(t *testing.T, client *oidc.Provider, nonce, verifier string) string {
+	t.Helper()
+	authURL, err := client.AuthURL(context.Background(), "state", nonce, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noRedirects := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	resp, err := noRedirects.Get(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code := location.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in the redirect to %s", location)
+	}
+	return code
+}
+
+func TestExchange(t *testing.T) {
+	p := newProvider(t)
+	impostor, err := oidctest.NewServer(p.url, "events", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		// login issues the code and the ID token, the provider by default.
+		login          *oidctest.Server
+		nonce          string
+		verifier       string
+		wrongSecret    bool
+		invalidToken   bool
+		exchangeFailed bool
+	}{
+		{name: "valid", nonce: "nonce", verifier: "verifier"},
+		{name: "other nonce", nonce: "other", verifier: "verifier", invalidToken: true},
+		{name: "other verifier", nonce: "nonce", verifier: "other", exchangeFailed: true},
+		{name: "wrong client secret", nonce: "nonce", verifier: "verifier", wrongSecret: true, exchangeFailed: true},
+		{name: "signed with a foreign key", login: impostor, nonce: "nonce", verifier: "verifier", invalidToken: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			login := p.Server
+			if tt.login != nil {
+				login = tt.login
+			}
+			p.login.Store(login)
+			defer p.login.Store(p.Server)
+
+			secret := "secret"
+			if tt.wrongSecret {
+				secret = "wrong"
+			}
+			client := oidc.New(oidc.Config{Issuer: p.url, ClientID: "events", ClientSecret: secret, RedirectURL: redirectURL})
+
+			code := signIn(t, client, "nonce", "verifier")
+			identity, err := client.Exchange(context.Background(), code, tt.verifier, tt.nonce)
+			switch {
+			case tt.invalidToken:
+				if !errors.Is(err, oidc.ErrInvalidToken) {
+					t.Fatalf("Exchange() = %v, want %v", err, oidc.ErrInvalidToken)
+				}
+			case tt.exchangeFailed:
+				if err == nil || errors.Is(err, oidc.ErrInvalidToken) {
+					t.Fatalf("Exchange() = %v, want the exchange refused", err)
+				}
+			case err != nil:
+				t.Fatalf("Exchange() = %v", err)
+			case *identity != p.User:
+				t.Errorf("identity = %+v, want %+v", *identity, p.User)
+			}
+		})
+	}
+}
+
+func TestExchangeRedeemsCodesOnce(t *testing.T) {
+	p := newProvider(t)
+	client := oidc.New(oidc.Config{Issuer: p.url, ClientID: "events", ClientSecret: "secret", RedirectURL: redirectURL})
+
+	code := signIn(t, client, "nonce", "verifier")
+	if _, err := client.Exchange(context.Background(), code, "verifier", "nonce"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Exchange(context.Background(), code, "verifier", "nonce"); err == nil {
+		t.Error("a code was redeemed twice")
+	}
+}
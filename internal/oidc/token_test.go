@@ -0,0 +1,112 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+func TestVerify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &metadata{Issuer: "https://accounts.example.com"}
+	p := New(Config{Issuer: m.Issuer, ClientID: "events"})
+	//the keys are known, so nothing is fetched
+	p.keys.keys = map[string]crypto.PublicKey{"key": &key.PublicKey}
+	p.keys.fetchedAt = time.Now()
+
+	now := time.Now()
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            m.Issuer,
+			"sub":            "alice",
+			"aud":            "events",
+			"exp":            now.Add(5 * time.Minute).Unix(),
+			"iat":            now.Unix(),
+			"nonce":          "nonce",
+			"email":          "alice@example.com",
+			"email_verified": true,
+			"name":           "Alice",
+		}
+	}
+	sign := func(method jwt.SigningMethod, signingKey any, kid string, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(method, claims)
+		if kid != "" {
+			token.Header["kid"] = kid
+		}
+		raw, err := token.SignedString(signingKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	with := func(changes jwt.MapClaims) string {
+		claims := valid()
+		for name, value := range changes {
+			if value == nil {
+				delete(claims, name)
+			} else {
+				claims[name] = value
+			}
+		}
+		return sign(jwt.SigningMethodRS256, key, "key", claims)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		// valid is whether the token is accepted.
+		valid bool
+	}{
+		{name: "valid", token: with(nil), valid: true},
+		{name: "no key id with a single key", token: sign(jwt.SigningMethodRS256, key, "", valid()), valid: true},
+		{name: "audience list", token: with(jwt.MapClaims{"aud": []any{"events", "other"}, "azp": "events"}), valid: true},
+		{name: "expired within the leeway", token: with(jwt.MapClaims{"exp": now.Add(-30 * time.Second).Unix()}), valid: true},
+		{name: "verified email as a string", token: with(jwt.MapClaims{"email_verified": "true"}), valid: true},
+		{name: "foreign key", token: sign(jwt.SigningMethodRS256, foreign, "key", valid())},
+		{name: "unknown key id", token: sign(jwt.SigningMethodRS256, key, "other", valid())},
+		{name: "shared secret", token: sign(jwt.SigningMethodHS256, []byte("secret"), "key", valid())},
+		{name: "unsigned", token: sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "key", valid())},
+		{name: "other issuer", token: with(jwt.MapClaims{"iss": "https://evil.example.com"})},
+		{name: "other audience", token: with(jwt.MapClaims{"aud": "other"})},
+		{name: "audience list without azp", token: with(jwt.MapClaims{"aud": []any{"events", "other"}})},
+		{name: "authorized for another client", token: with(jwt.MapClaims{"azp": "other"})},
+		{name: "expired", token: with(jwt.MapClaims{"exp": now.Add(-2 * time.Minute).Unix()})},
+		{name: "no expiry", token: with(jwt.MapClaims{"exp": nil})},
+		{name: "issued in the future", token: with(jwt.MapClaims{"iat": now.Add(2 * time.Minute).Unix()})},
+		{name: "not valid yet", token: with(jwt.MapClaims{"nbf": now.Add(2 * time.Minute).Unix()})},
+		{name: "other nonce", token: with(jwt.MapClaims{"nonce": "other"})},
+		{name: "no nonce", token: with(jwt.MapClaims{"nonce": nil})},
+		{name: "no subject", token: with(jwt.MapClaims{"sub": nil})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			identity, err := p.verify(context.Background(), m, tt.token, "nonce", now)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidToken) {
+					t.Errorf("verify() = %v, want %v", err, ErrInvalidToken)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("verify() = %v", err)
+			}
+			want := Identity{Subject: "alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+			if *identity != want {
+				t.Errorf("identity = %+v, want %+v", *identity, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package payments
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Fake is an in-memory provider for development and tests. Intent ids are
+// numbered in creation order, confirmations succeed unless the intent was
+// cancelled, refunds always succeed, and webhooks are verified like
+// Stripe's with the configured secret, so they can be produced with
+// SignWebhook.
+type Fake struct {
+	mu            sync.Mutex
+	webhookSecret string
+	intents       map[string]*Intent
+	refunds       []string
+	next          int
+	now           func() time.Time
+}
+
+func NewFake(webhookSecret string) *Fake {
+	return &Fake{
+		webhookSecret: webhookSecret,
+		intents:       map[string]*Intent{},
+		now:           time.Now,
+	}
+}
+
+func (f *Fake) CreateIntent(ctx context.Context, amountCents int, currency string, metadata map[string]string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.next++
+	id := fmt.Sprintf("pi_fake_%06d", f.next)
+	intent := &Intent{
+		Id:           id,
+		AmountCents:  amountCents,
+		Currency:     currency,
+		Status:       StatusRequiresPayment,
+		ClientSecret: id + "_secret",
+	}
+	f.intents[id] = intent
+	result := *intent
+	return &result, nil
+}
+
+func (f *Fake) Confirm(ctx context.Context, intentId, paymentMethod string) (*Intent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentId]
+	if !ok {
+		return nil, fmt.Errorf("fake: no such payment intent %s", intentId)
+	}
+	if intent.Status == StatusCanceled {
+		return nil, fmt.Errorf("fake: payment intent %s was cancelled", intentId)
+	}
+	intent.Status = StatusSucceeded
+	result := *intent
+	return &result, nil
+}
+
+// Cancel cancels an intent unless it succeeded. Unknown intents are
+// cancelled without complaint, since intents don't survive a restart of
+// the API.
+func (f *Fake) Cancel(ctx context.Context, intentId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	intent, ok := f.intents[intentId]
+	if !ok {
+		return nil
+	}
+	if intent.Status == StatusSucceeded {
+		return fmt.Errorf("fake: payment intent %s succeeded already", intentId)
+	}
+	intent.Status = StatusCanceled
+	return nil
+}
+
+// Refund records the refund. Intents aren't checked, since they don't
+// survive a restart of the API. Like Stripe, an intent is only refunded
+// once however often it is asked for.
+func (f *Fake) Refund(ctx context.Context, intentId string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !slices.Contains(f.refunds, intentId) {
+		f.refunds = append(f.refunds, intentId)
+	}
+	return nil
+}
+
+func (f *Fake) VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	return verifyWebhook(f.webhookSecret, payload, header, f.now())
+}
+
+// Refunds returns the ids of the refunded intents in refund order.
+func (f *Fake) Refunds() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.refunds...)
+}
@@ -0,0 +1,130 @@
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Intent statuses, following Stripe's naming.
+const (
+	StatusRequiresPayment = "requires_payment_method"
+	StatusSucceeded       = "succeeded"
+	StatusCanceled        = "canceled"
+)
+
+// Webhook event types the API reacts to.
+const (
+	EventPaymentSucceeded = "payment_intent.succeeded"
+	EventPaymentFailed    = "payment_intent.payment_failed"
+	EventPaymentCanceled  = "payment_intent.canceled"
+)
+
+const signatureTolerance = 5 * time.Minute
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Intent is a payment the provider collects from a customer.
+type Intent struct {
+	Id           string `json:"id"`
+	AmountCents  int    `json:"amountCents"`
+	Currency     string `json:"currency"`
+	Status       string `json:"status"`
+	ClientSecret string `json:"clientSecret"`
+}
+
+// WebhookEvent is a verified notification from the provider about an intent.
+type WebhookEvent struct {
+	Id       string
+	Type     string
+	IntentId string
+}
+
+// Provider is a payment service provider.
+type Provider interface {
+	// CreateIntent starts collecting a payment. The metadata is stored with
+	// the intent at the provider.
+	CreateIntent(ctx context.Context, amountCents int, currency string, metadata map[string]string) (*Intent, error)
+	// Confirm attempts to complete an intent with the given payment method.
+	Confirm(ctx context.Context, intentId, paymentMethod string) (*Intent, error)
+	// Cancel cancels an intent so it can't be paid anymore. It fails for an
+	// intent that succeeded already; cancelling an intent again succeeds.
+	Cancel(ctx context.Context, intentId string) error
+	// Refund returns the full amount of a succeeded intent. Refunding an
+	// intent again succeeds without paying it out twice.
+	Refund(ctx context.Context, intentId string) error
+	// VerifyWebhook checks the signature of a webhook request and parses it.
+	VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+}
+
+// SignWebhook returns a Stripe-Signature header value for payload, signed
+// with secret at time t.
+func SignWebhook(secret string, payload []byte, t time.Time) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + timestamp + ",v1=" + sign(secret, timestamp, payload)
+}
+
+func sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyWebhook checks a Stripe-Signature header and parses a Stripe event
+// payload. Both providers use this format.
+func verifyWebhook(secret string, payload []byte, header http.Header, now time.Time) (*WebhookEvent, error) {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header.Get("Stripe-Signature"), ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	if age := now.Sub(time.Unix(unix, 0)); age > signatureTolerance || age < -signatureTolerance {
+		return nil, ErrInvalidSignature
+	}
+
+	expected := sign(secret, timestamp, payload)
+	valid := false
+	for _, signature := range signatures {
+		if hmac.Equal([]byte(signature), []byte(expected)) {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, ErrInvalidSignature
+	}
+
+	var event struct {
+		Id   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			Object struct {
+				Id string `json:"id"`
+			} `json:"object"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("parsing webhook payload: %w", err)
+	}
+	return &WebhookEvent{Id: event.Id, Type: event.Type, IntentId: event.Data.Object.Id}, nil
+}
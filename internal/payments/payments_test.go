@@ -0,0 +1,146 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1760000000, 0)
+	payload := []byte(`{"id":"evt_1","type":"payment_intent.succeeded","data":{"object":{"id":"pi_1"}}}`)
+	tests := []struct {
+		name   string
+		header string
+		valid  bool
+	}{
+		{name: "valid", header: SignWebhook("secret", payload, now), valid: true},
+		{name: "one of several signatures", header: SignWebhook("secret", payload, now) + ",v1=00", valid: true},
+		{name: "wrong secret", header: SignWebhook("other", payload, now)},
+		{name: "too old", header: SignWebhook("secret", payload, now.Add(-signatureTolerance-time.Second))},
+		{name: "from the future", header: SignWebhook("secret", payload, now.Add(signatureTolerance+time.Second))},
+		{name: "no timestamp", header: "v1=00"},
+		{name: "missing", header: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{"Stripe-Signature": {tt.header}}
+			event, err := verifyWebhook("secret", payload, header, now)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidSignature) {
+					t.Fatalf("err = %v, want ErrInvalidSignature", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if event.Id != "evt_1" || event.Type != EventPaymentSucceeded || event.IntentId != "pi_1" {
+				t.Fatalf("event = %+v", event)
+			}
+		})
+	}
+}
+
+func TestStripeRefundIsIdempotent(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) > 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":"charge_already_refunded","message":"Charge has already been refunded."}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"re_1"}`))
+	}))
+	defer server.Close()
+
+	stripe := NewStripe("sk_test", "whsec", server.URL)
+	for range 2 {
+		if err := stripe.Refund(context.Background(), "pi_1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !slices.Equal(keys, []string{"refund-pi_1", "refund-pi_1"}) {
+		t.Fatalf("idempotency keys = %q", keys)
+	}
+}
+
+func TestStripeErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusPaymentRequired)
+		w.Write([]byte(`{"error":{"code":"card_declined","message":"Your card was declined."}}`))
+	}))
+	defer server.Close()
+
+	err := NewStripe("sk_test", "whsec", server.URL).Refund(context.Background(), "pi_1")
+	if err == nil || err.Error() != "stripe: 402 Payment Required: Your card was declined." {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+func TestFakeRefundsOnce(t *testing.T) {
+	fake := NewFake("secret")
+	for _, id := range []string{"pi_1", "pi_2", "pi_1"} {
+		if err := fake.Refund(context.Background(), id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if refunds := fake.Refunds(); !slices.Equal(refunds, []string{"pi_1", "pi_2"}) {
+		t.Fatalf("refunds = %q", refunds)
+	}
+}
+
+func TestStripeCancelIsIdempotent(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path+" "+r.Header.Get("Idempotency-Key"))
+		if len(paths) > 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":{"code":"payment_intent_unexpected_state","message":"This PaymentIntent's status is canceled.","payment_intent":{"id":"pi_1","status":"canceled"}}}`))
+			return
+		}
+		w.Write([]byte(`{"id":"pi_1","status":"canceled"}`))
+	}))
+	defer server.Close()
+
+	stripe := NewStripe("sk_test", "whsec", server.URL)
+	for range 2 {
+		if err := stripe.Cancel(context.Background(), "pi_1"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []string{"/v1/payment_intents/pi_1/cancel cancel-pi_1", "/v1/payment_intents/pi_1/cancel cancel-pi_1"}
+	if !slices.Equal(paths, want) {
+		t.Fatalf("requests = %q", paths)
+	}
+}
+
+func TestFakeCancel(t *testing.T) {
+	fake := NewFake("secret")
+	ctx := context.Background()
+	paid, _ := fake.CreateIntent(ctx, 1000, "EUR", nil)
+	unpaid, _ := fake.CreateIntent(ctx, 1000, "EUR", nil)
+	if _, err := fake.Confirm(ctx, paid.Id, "pm_card_visa"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		intentId string
+		wantErr  bool
+	}{
+		{unpaid.Id, false},
+		{unpaid.Id, false},
+		{paid.Id, true},
+		{"pi_unknown", false},
+	}
+	for _, tt := range tests {
+		if err := fake.Cancel(ctx, tt.intentId); (err != nil) != tt.wantErr {
+			t.Errorf("Cancel(%s) = %v, want error %v", tt.intentId, err, tt.wantErr)
+		}
+	}
+}
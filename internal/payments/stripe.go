@@ -0,0 +1,160 @@
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultStripeURL = "https://api.stripe.com"
+
+// Stripe talks to the Stripe API, or any service implementing the same
+// payment intent and refund endpoints.
+type Stripe struct {
+	secretKey     string
+	webhookSecret string
+	baseURL       string
+	client        *http.Client
+}
+
+// NewStripe returns a Stripe provider. An empty baseURL uses the Stripe API.
+func NewStripe(secretKey, webhookSecret, baseURL string) *Stripe {
+	if baseURL == "" {
+		baseURL = defaultStripeURL
+	}
+	return &Stripe{
+		secretKey:     secretKey,
+		webhookSecret: webhookSecret,
+		baseURL:       strings.TrimSuffix(baseURL, "/"),
+		client:        &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type stripeIntent struct {
+	Id           string `json:"id"`
+	Amount       int    `json:"amount"`
+	Currency     string `json:"currency"`
+	Status       string `json:"status"`
+	ClientSecret string `json:"client_secret"`
+}
+
+func (s *Stripe) CreateIntent(ctx context.Context, amountCents int, currency string, metadata map[string]string) (*Intent, error) {
+	form := url.Values{}
+	form.Set("amount", strconv.Itoa(amountCents))
+	form.Set("currency", strings.ToLower(currency))
+	for key, value := range metadata {
+		form.Set("metadata["+key+"]", value)
+	}
+
+	var intent stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents", form, "", &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+func (s *Stripe) Confirm(ctx context.Context, intentId, paymentMethod string) (*Intent, error) {
+	form := url.Values{}
+	form.Set("payment_method", paymentMethod)
+
+	var intent stripeIntent
+	if err := s.post(ctx, "/v1/payment_intents/"+url.PathEscape(intentId)+"/confirm", form, "", &intent); err != nil {
+		return nil, err
+	}
+	return intent.toIntent(), nil
+}
+
+// Cancel cancels an intent. Retries share an idempotency key, and an
+// intent that was cancelled already is left as it is.
+func (s *Stripe) Cancel(ctx context.Context, intentId string) error {
+	err := s.post(ctx, "/v1/payment_intents/"+url.PathEscape(intentId)+"/cancel", url.Values{}, "cancel-"+intentId, nil)
+	var apiErr *stripeError
+	if errors.As(err, &apiErr) && apiErr.PaymentIntent != nil && apiErr.PaymentIntent.Status == StatusCanceled {
+		return nil
+	}
+	return err
+}
+
+// Refund refunds an intent once: retries share an idempotency key, and an
+// intent that was refunded already is left as it is.
+func (s *Stripe) Refund(ctx context.Context, intentId string) error {
+	form := url.Values{}
+	form.Set("payment_intent", intentId)
+	err := s.post(ctx, "/v1/refunds", form, "refund-"+intentId, nil)
+	var apiErr *stripeError
+	if errors.As(err, &apiErr) && apiErr.Code == "charge_already_refunded" {
+		return nil
+	}
+	return err
+}
+
+func (s *Stripe) VerifyWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	return verifyWebhook(s.webhookSecret, payload, header, time.Now())
+}
+
+// stripeError is an error response of the Stripe API.
+type stripeError struct {
+	Status  string
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// PaymentIntent is the intent an error about an intent's state refers to.
+	PaymentIntent *stripeIntent `json:"payment_intent"`
+}
+
+func (e *stripeError) Error() string {
+	return fmt.Sprintf("stripe: %s: %s", e.Status, e.Message)
+}
+
+// post sends a form to the API. Requests with the same non-empty
+// idempotency key are only carried out once.
+func (s *Stripe) post(ctx context.Context, path string, form url.Values, idempotencyKey string, result any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.baseURL+path, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(s.secretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Error stripeError `json:"error"`
+		}
+		json.Unmarshal(body, &apiErr)
+		apiErr.Error.Status = resp.Status
+		return &apiErr.Error
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(body, result)
+}
+
+func (i *stripeIntent) toIntent() *Intent {
+	return &Intent{
+		Id:           i.Id,
+		AmountCents:  i.Amount,
+		Currency:     strings.ToUpper(i.Currency),
+		Status:       i.Status,
+		ClientSecret: i.ClientSecret,
+	}
+}
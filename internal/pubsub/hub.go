@@ -0,0 +1,201 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	defaultBuffer     = 32
+	defaultHistory    = 100
+	defaultHistoryTTL = 10 * time.Minute
+	// sweepInterval is how often idle topics are looked for.
+	sweepInterval = time.Minute
+)
+
+// Message is a single update published to a topic. Ids increase
+// monotonically across all topics, also across restarts, so they can be used
+// as SSE event ids.
+type Message struct {
+	Id   uint64
+	Type string
+	Data any
+}
+
+// Hub is an in-process publish/subscribe hub keyed by event id. It keeps the
+// most recent messages of every topic so reconnecting clients can resume.
+// A topic is dropped once nobody subscribes to it and its history expired,
+// HistoryTTL after its last message.
+type Hub struct {
+	mu         sync.Mutex
+	seq        uint64
+	topics     map[int]*topic
+	closed     bool
+	sweptAt    time.Time
+	Buffer     int
+	History    int
+	HistoryTTL time.Duration
+}
+
+type topic struct {
+	subscribers map[*Subscription]struct{}
+	history     []Message
+	publishedAt time.Time
+}
+
+// Subscription delivers the messages of one topic on C. C is closed when the
+// subscription is closed, when the hub shuts down, or when the subscriber
+// falls behind by more than its buffer.
+type Subscription struct {
+	C     <-chan Message
+	ch    chan Message
+	hub   *Hub
+	topic int
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		seq:        uint64(time.Now().UnixMilli()) * 1000,
+		topics:     map[int]*topic{},
+		sweptAt:    time.Now(),
+		Buffer:     defaultBuffer,
+		History:    defaultHistory,
+		HistoryTTL: defaultHistoryTTL,
+	}
+}
+
+func (h *Hub) topic(id int) *topic {
+	t, ok := h.topics[id]
+	if !ok {
+		t = &topic{subscribers: map[*Subscription]struct{}{}}
+		h.topics[id] = t
+	}
+	return t
+}
+
+// idle is whether a topic can be dropped: nobody listens and there is
+// nothing left to resume from.
+func (h *Hub) idle(t *topic, now time.Time) bool {
+	return len(t.subscribers) == 0 && now.Sub(t.publishedAt) >= h.HistoryTTL
+}
+
+// sweep drops idle topics, at most once per sweepInterval.
+func (h *Hub) sweep(now time.Time) {
+	if now.Sub(h.sweptAt) < sweepInterval {
+		return
+	}
+	h.sweptAt = now
+	for id, t := range h.topics {
+		if h.idle(t, now) {
+			delete(h.topics, id)
+		}
+	}
+}
+
+// Publish sends a message to every subscriber of the topic.
+func (h *Hub) Publish(topicId int, messageType string, data any) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+
+	now := time.Now()
+	h.sweep(now)
+	h.seq++
+	message := Message{Id: h.seq, Type: messageType, Data: data}
+	t := h.topic(topicId)
+	t.publishedAt = now
+	t.history = append(t.history, message)
+	if len(t.history) > h.History {
+		t.history = t.history[len(t.history)-h.History:]
+	}
+
+	for sub := range t.subscribers {
+		select {
+		case sub.ch <- message:
+		default:
+			//slow subscriber, drop it so it reconnects and resumes from history
+			delete(t.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe registers a subscriber on the topic. Messages published after
+// lastId that are still in the topic history are returned so the caller can
+// replay them before reading from the subscription.
+func (h *Hub) Subscribe(topicId int, lastId uint64) (*Subscription, []Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Message, h.Buffer)
+	sub := &Subscription{C: ch, ch: ch, hub: h, topic: topicId}
+	if h.closed {
+		close(ch)
+		return sub, nil
+	}
+
+	t := h.topic(topicId)
+	t.subscribers[sub] = struct{}{}
+
+	var missed []Message
+	if lastId > 0 {
+		for _, message := range t.history {
+			if message.Id > lastId {
+				missed = append(missed, message)
+			}
+		}
+	}
+	return sub, missed
+}
+
+// Close removes the subscription from the hub.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	t, ok := s.hub.topics[s.topic]
+	if !ok {
+		return
+	}
+	if _, ok := t.subscribers[s]; ok {
+		delete(t.subscribers, s)
+		close(s.ch)
+	}
+	now := time.Now()
+	if s.hub.idle(t, now) {
+		delete(s.hub.topics, s.topic)
+	}
+	s.hub.sweep(now)
+}
+
+// Remove disconnects the subscribers of a topic and drops its history, for
+// topics that won't get any more messages.
+func (h *Hub) Remove(topicId int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	t, ok := h.topics[topicId]
+	if !ok {
+		return
+	}
+	for sub := range t.subscribers {
+		close(sub.ch)
+	}
+	delete(h.topics, topicId)
+}
+
+// Close disconnects every subscriber and stops accepting new messages.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, t := range h.topics {
+		for sub := range t.subscribers {
+			delete(t.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
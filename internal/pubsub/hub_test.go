@@ -0,0 +1,93 @@
+package pubsub
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscribeReplaysMissedMessages(t *testing.T) {
+	h := NewHub()
+	h.Publish(1, "a", 1)
+	h.Publish(1, "b", 2)
+	h.Publish(2, "c", 3)
+
+	first, _ := h.Subscribe(1, 0)
+	defer first.Close()
+	h.Publish(1, "d", 4)
+	last := <-first.C
+
+	//ids are shared by all topics, so c of topic 2 sits between b and d
+	sub, missed := h.Subscribe(1, last.Id-3)
+	defer sub.Close()
+	if len(missed) != 2 || missed[0].Type != "b" || missed[1].Type != "d" {
+		t.Fatalf("missed = %+v, want b and d", missed)
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	h := NewHub()
+	h.Buffer = 1
+	sub, _ := h.Subscribe(1, 0)
+	defer sub.Close()
+
+	h.Publish(1, "a", nil)
+	h.Publish(1, "b", nil)
+	if message := <-sub.C; message.Type != "a" {
+		t.Fatalf("got %q, want a", message.Type)
+	}
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscription still open after falling behind")
+	}
+}
+
+func TestIdleTopicsAreDropped(t *testing.T) {
+	tests := []struct {
+		name      string
+		publish   bool
+		age       time.Duration
+		subscribe bool
+		kept      bool
+	}{
+		{name: "no history", kept: false},
+		{name: "recent history", publish: true, age: time.Minute, kept: true},
+		{name: "expired history", publish: true, age: time.Hour, kept: false},
+		{name: "subscribed", publish: true, age: time.Hour, subscribe: true, kept: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub()
+			if tt.publish {
+				h.Publish(1, "a", nil)
+				h.topics[1].publishedAt = time.Now().Add(-tt.age)
+			}
+			if tt.subscribe {
+				sub, _ := h.Subscribe(1, 0)
+				defer sub.Close()
+			}
+			sub, _ := h.Subscribe(1, 0)
+			sub.Close()
+
+			h.sweptAt = time.Time{}
+			h.Publish(2, "b", nil)
+			if _, ok := h.topics[1]; ok != tt.kept {
+				t.Fatalf("topic kept = %v, want %v", ok, tt.kept)
+			}
+		})
+	}
+}
+
+func TestRemoveDisconnectsSubscribers(t *testing.T) {
+	h := NewHub()
+	sub, _ := h.Subscribe(1, 0)
+	h.Publish(1, "a", nil)
+	h.Remove(1)
+
+	<-sub.C
+	if _, ok := <-sub.C; ok {
+		t.Fatal("subscription still open after Remove")
+	}
+	sub.Close()
+	if _, missed := h.Subscribe(1, 1); len(missed) != 0 {
+		t.Fatalf("history survived Remove: %+v", missed)
+	}
+}
@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrPrivateTarget is returned for webhook URLs, and refused connections,
+// pointing at loopback, private, link-local or other internal addresses.
+var ErrPrivateTarget = errors.New("webhook target is not a public address")
+
+// blockedPrefixes are the ranges not covered by the netip.Addr predicates
+// that webhooks may not reach.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),     // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),  // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"), // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),   // reserved, and broadcast
+	netip.MustParsePrefix("64:ff9b::/96"),  // NAT64, which can reach any IPv4 address
+	netip.MustParsePrefix("fd00:ec2::/32"), // cloud metadata over IPv6
+}
+
+// Blocked reports whether addr is an address webhooks may not be sent to.
+// Cloud metadata services live in the link-local ranges.
+func Blocked(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return true
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckURL checks that rawURL is an http or https URL whose host only
+// resolves to public addresses, unless the dispatcher allows private ones.
+// Deliveries check the address again when they connect, since the host may
+// resolve differently by then.
+func (d *Dispatcher) CheckURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("webhook URL %q must be an absolute http or https URL", rawURL)
+	}
+	if d.AllowPrivate {
+		return nil
+	}
+	if addr, err := netip.ParseAddr(u.Hostname()); err == nil {
+		if Blocked(addr) {
+			return ErrPrivateTarget
+		}
+		return nil
+	}
+	addrs, err := net.DefaultResolver.LookupNetIP(ctx, "ip", u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook host %q can't be resolved", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if Blocked(addr) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// control is the net.Dialer Control function of deliveries, refusing
+// connections to blocked addresses, including those reached through
+// redirects.
+func (d *Dispatcher) control(network, address string, _ syscall.RawConn) error {
+	if d.AllowPrivate {
+		return nil
+	}
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if Blocked(addrPort.Addr()) {
+		return ErrPrivateTarget
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+func TestBlocked(t *testing.T) {
+	tests := []struct {
+		addr    string
+		blocked bool
+	}{
+		{"93.184.216.34", false},
+		{"2606:2800:220:1:248:1893:25c8:1946", false},
+		{"127.0.0.1", true},
+		{"::1", true},
+		{"10.1.2.3", true},
+		{"172.16.0.1", true},
+		{"192.168.1.1", true},
+		{"169.254.169.254", true},
+		{"0.0.0.0", true},
+		{"100.64.0.1", true},
+		{"fe80::1", true},
+		{"fd00:ec2::254", true},
+		{"::ffff:127.0.0.1", true},
+		{"64:ff9b::7f00:1", true},
+		{"224.0.0.1", true},
+	}
+	for _, tt := range tests {
+		if got := Blocked(netip.MustParseAddr(tt.addr)); got != tt.blocked {
+			t.Errorf("Blocked(%s) = %v, want %v", tt.addr, got, tt.blocked)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		allowPrivate bool
+		// wantErr is part of the expected error, or empty to accept the URL.
+		wantErr string
+	}{
+		{name: "public address", url: "https://93.184.216.34/hook"},
+		{name: "loopback", url: "http://127.0.0.1:8080/hook", wantErr: ErrPrivateTarget.Error()},
+		{name: "localhost", url: "http://localhost/hook", wantErr: ErrPrivateTarget.Error()},
+		{name: "cloud metadata", url: "http://169.254.169.254/latest/meta-data", wantErr: ErrPrivateTarget.Error()},
+		{name: "IPv6 loopback", url: "http://[::1]/hook", wantErr: ErrPrivateTarget.Error()},
+		{name: "private allowed", url: "http://127.0.0.1:8080/hook", allowPrivate: true},
+		{name: "other scheme", url: "ftp://93.184.216.34/hook", wantErr: "absolute http or https URL"},
+		{name: "relative", url: "/hook", wantErr: "absolute http or https URL"},
+		{name: "other scheme with private allowed", url: "file:///etc/passwd", allowPrivate: true, wantErr: "absolute http or https URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dispatcher{AllowPrivate: tt.allowPrivate}
+			err := d.CheckURL(context.Background(), tt.url)
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("CheckURL(%q) = %v", tt.url, err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("CheckURL(%q) = %v, want an error containing %q", tt.url, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDeliveryRefusesPrivateAddress(t *testing.T) {
+	var called atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called.Store(true)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(nil)
+	_, err := d.send(context.Background(), &database.Webhook{Url: server.URL, Secret: "whsec"}, &database.WebhookDelivery{Payload: "{}"})
+	if !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("send() = %v, want %v", err, ErrPrivateTarget)
+	}
+	if called.Load() {
+		t.Error("the delivery reached the endpoint")
+	}
+}
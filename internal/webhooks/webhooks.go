@@ -0,0 +1,227 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+// Event types that can be subscribed to.
+const (
+	EventCreated    = "event.created"
+	EventUpdated    = "event.updated"
+	EventDeleted    = "event.deleted"
+	AttendeeAdded   = "attendee.added"
+	AttendeeRemoved = "attendee.removed"
+	EventReminder   = "event.reminder"
+)
+
+var EventTypes = []string{EventCreated, EventUpdated, EventDeleted, AttendeeAdded, AttendeeRemoved, EventReminder}
+
+const (
+	defaultMaxAttempts = 8
+	baseBackoff        = 30 * time.Second
+	maxBackoff         = 6 * time.Hour
+	pollInterval       = 5 * time.Second
+	batchSize          = 20
+)
+
+// Payload is the JSON body posted to webhook endpoints.
+type Payload struct {
+	Type      string    `json:"type"`
+	Timestamp time.Time `json:"timestamp"`
+	EventId   int       `json:"eventId"`
+	Data      any       `json:"data"`
+}
+
+// Dispatcher records webhook deliveries and sends them from a background
+// worker, retrying failed attempts with exponential backoff. Deliveries to
+// loopback, private and link-local addresses are refused unless
+// AllowPrivate is set.
+type Dispatcher struct {
+	webhooks     *database.WebhookModel
+	client       *http.Client
+	wake         chan struct{}
+	MaxAttempts  int
+	AllowPrivate bool
+}
+
+func NewDispatcher(webhooks *database.WebhookModel) *Dispatcher {
+	d := &Dispatcher{
+		webhooks:    webhooks,
+		wake:        make(chan struct{}, 1),
+		MaxAttempts: defaultMaxAttempts,
+	}
+	//deliveries connect directly, without a proxy, so that the dialer sees
+	//the address of the endpoint
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{Timeout: 5 * time.Second, Control: d.control}).DialContext
+	d.client = &http.Client{Timeout: 10 * time.Second, Transport: transport}
+	return d
+}
+
+// Emit queues a delivery of eventType to every webhook subscribed to the event.
+func (d *Dispatcher) Emit(eventType string, event *database.Event, data any) error {
+	subscribers, err := d.webhooks.GetSubscribers(event.OwnerId, event.Id, eventType)
+	if err != nil {
+		return err
+	}
+	if len(subscribers) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(Payload{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		EventId:   event.Id,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, webhook := range subscribers {
+		delivery := database.WebhookDelivery{
+			WebhookId: webhook.Id,
+			EventType: eventType,
+			Payload:   string(body),
+		}
+		if err := d.webhooks.InsertDelivery(&delivery); err != nil {
+			return err
+		}
+	}
+
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Run delivers pending webhooks until ctx is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.deliverDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.webhooks.GetDueDeliveries(time.Now(), batchSize)
+		if err != nil {
+			log.Printf("webhooks: loading due deliveries: %v", err)
+			return
+		}
+		for _, delivery := range deliveries {
+			d.attempt(ctx, delivery)
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) attempt(ctx context.Context, delivery *database.WebhookDelivery) {
+	webhook, err := d.webhooks.Get(delivery.WebhookId)
+	if err != nil {
+		log.Printf("webhooks: loading webhook %d: %v", delivery.WebhookId, err)
+		return
+	}
+
+	delivery.Attempts++
+	if webhook == nil || !webhook.Active {
+		delivery.Status = database.DeliveryFailed
+		delivery.LastError = "webhook is no longer active"
+	} else {
+		status, err := d.send(ctx, webhook, delivery)
+		delivery.ResponseStatus = status
+		switch {
+		case err == nil:
+			now := time.Now().UTC()
+			delivery.Status = database.DeliveryDelivered
+			delivery.DeliveredAt = &now
+			delivery.LastError = ""
+		case delivery.Attempts >= d.MaxAttempts:
+			delivery.Status = database.DeliveryFailed
+			delivery.LastError = err.Error()
+		default:
+			delivery.NextAttemptAt = time.Now().Add(Backoff(delivery.Attempts))
+			delivery.LastError = err.Error()
+		}
+	}
+
+	if err := d.webhooks.UpdateDelivery(delivery); err != nil {
+		log.Printf("webhooks: %v", err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, webhook *database.Webhook, delivery *database.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", delivery.EventType)
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(delivery.Id))
+	req.Header.Set("X-Webhook-Timestamp", timestamp)
+	req.Header.Set("X-Webhook-Signature", "sha256="+Sign(webhook.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of "timestamp.body" keyed with the
+// webhook secret. Receivers recompute it to verify a delivery.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Backoff returns the wait before retrying a delivery that failed attempts times.
+func Backoff(attempts int) time.Duration {
+	wait := baseBackoff
+	for i := 1; i < attempts; i++ {
+		wait *= 2
+		if wait >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return wait
+}
@@ -0,0 +1,198 @@
+package webhooks
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anshbadoni30/event-management-app/cmd/migrate/migrations"
+	"github.com/anshbadoni30/event-management-app/internal/database"
+)
+
+// newTestModels returns the models of a freshly migrated database.
+func newTestModels(t *testing.T) *database.Models {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "test.db")
+	if _, _, err := database.Migrate(path, migrations.FS); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	models := database.NewModels(db)
+	return &models
+}
+
+// newTestEvent adds an event and its owner.
+func newTestEvent(t *testing.T, models *database.Models) *database.Event {
+	t.Helper()
+	user := &database.User{Name: "alice", Email: "alice@example.com", Password: "x"}
+	if err := models.Users.Insert(user); err != nil {
+		t.Fatal(err)
+	}
+	event := &database.Event{OwnerId: user.Id, Name: "Meetup", Description: "Monthly meetup", Date: "2030-01-01T18:00:00Z", Location: "Town hall"}
+	if err := models.Events.Insert(event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"type":"event.created"}`)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      []byte
+		want      string
+	}{
+		{"known signature", "whsec", "1760000000", body, "eee3f503c9c46d570701f57f4509a4cd8589f80407bb10ef54e0cf64ac8316af"},
+		{"other secret", "other", "1760000000", body, ""},
+		{"other timestamp", "whsec", "1760000001", body, ""},
+		{"other body", "whsec", "1760000000", []byte(`{"type":"event.deleted"}`), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sign(tt.secret, tt.timestamp, tt.body)
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+			if tt.want == "" && got == tests[0].want {
+				t.Error("Sign() doesn't depend on its inputs")
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{10, 256 * time.Minute},
+		{11, maxBackoff},
+		{100, maxBackoff},
+	}
+	for _, tt := range tests {
+		if got := Backoff(tt.attempts); got != tt.want {
+			t.Errorf("Backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	models := newTestModels(t)
+	event := newTestEvent(t, models)
+
+	//the endpoint fails until it is told to succeed, and checks signatures
+	var succeed, badSignatures atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+		}
+		want := "sha256=" + Sign("whsec", r.Header.Get("X-Webhook-Timestamp"), body)
+		if r.Header.Get("X-Webhook-Signature") != want {
+			badSignatures.Store(true)
+		}
+		if !succeed.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+
+	webhook := &database.Webhook{OwnerId: event.OwnerId, Url: server.URL, Secret: "whsec", Active: true}
+	if err := models.Webhooks.Insert(webhook); err != nil {
+		t.Fatal(err)
+	}
+	d := NewDispatcher(&models.Webhooks)
+	d.AllowPrivate = true
+	d.MaxAttempts = 3
+
+	tests := []struct {
+		name         string
+		succeed      bool
+		wantStatus   string
+		wantAttempts int
+		// wantRetryIn is the expected wait before the next attempt.
+		wantRetryIn time.Duration
+	}{
+		{name: "first failure", wantStatus: database.DeliveryPending, wantAttempts: 1, wantRetryIn: Backoff(1)},
+		{name: "second failure", wantStatus: database.DeliveryPending, wantAttempts: 2, wantRetryIn: Backoff(2)},
+		{name: "success", succeed: true, wantStatus: database.DeliveryDelivered, wantAttempts: 3},
+	}
+	if err := d.Emit(EventCreated, event, event); err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			succeed.Store(tt.succeed)
+			d.deliverDue(context.Background())
+			deliveries, err := models.Webhooks.GetDeliveries(webhook.Id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(deliveries) != 1 {
+				t.Fatalf("%d deliveries, want 1", len(deliveries))
+			}
+			delivery := deliveries[0]
+			if delivery.Status != tt.wantStatus || delivery.Attempts != tt.wantAttempts {
+				t.Fatalf("delivery is %s after %d attempts, want %s after %d", delivery.Status, delivery.Attempts, tt.wantStatus, tt.wantAttempts)
+			}
+			if tt.wantRetryIn > 0 {
+				if retryIn := time.Until(delivery.NextAttemptAt); retryIn < tt.wantRetryIn-time.Minute || retryIn > tt.wantRetryIn {
+					t.Errorf("next attempt in %v, want %v", retryIn, tt.wantRetryIn)
+				}
+				//make the next attempt due, as if the backoff had passed
+				delivery.NextAttemptAt = time.Now().Add(-time.Second)
+				if err := models.Webhooks.UpdateDelivery(delivery); err != nil {
+					t.Fatal(err)
+				}
+			}
+		})
+	}
+	if badSignatures.Load() {
+		t.Error("a delivery was sent with a bad signature")
+	}
+}
+
+func TestDeliveryGivesUp(t *testing.T) {
+	models := newTestModels(t)
+	event := newTestEvent(t, models)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	webhook := &database.Webhook{OwnerId: event.OwnerId, Url: server.URL, Secret: "whsec", Active: true}
+	if err := models.Webhooks.Insert(webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	d := NewDispatcher(&models.Webhooks)
+	d.AllowPrivate = true
+	d.MaxAttempts = 1
+	if err := d.Emit(EventCreated, event, event); err != nil {
+		t.Fatal(err)
+	}
+	d.deliverDue(context.Background())
+
+	deliveries, err := models.Webhooks.GetDeliveries(webhook.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != database.DeliveryFailed || deliveries[0].ResponseStatus != http.StatusServiceUnavailable {
+		t.Fatalf("deliveries = %+v, want one failed with 503", deliveries)
+	}
+}